@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 	"path"
 
 	"github.com/JoachimTislov/lite-jnc/compiler"
@@ -15,12 +16,18 @@ import (
 func main() {
 	language := flag.String("l", "ELF", "Select language to either compile or transpile")
 	source := path.Join(env.Home(), "projects/lite-jnc/src/Main.javaa")
-	path := flag.String("p", source, "Path to the source file")
+	path := flag.String("p", source, "Path to the source file, or a source root directory compiled as a whole")
 	out := flag.String("o", "out", "name of output file")
 	// compile := flag.Bool("c", false, "Compile the transpiled language. Nothing happens when compiling directly to machine code")
 	flag.Parse()
 
-	p, err := parser.New(*path, *language)
+	var p *parser.Parser
+	var err error
+	if info, statErr := os.Stat(*path); statErr == nil && info.IsDir() {
+		p, err = parser.NewFromDir(*path, *language)
+	} else {
+		p, err = parser.New(*path, *language)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
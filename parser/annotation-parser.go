@@ -0,0 +1,509 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// isAnnotation reports whether an annotation starts at peekToken, rather than the declaration of an annotation interface
+func (p *Parser) isAnnotation() bool {
+	return p.peekToken.kind == AT && p.peekAt(2).kind != INTERFACE
+}
+
+// parseAnnotation parses an annotation following @, the current token, JLS 9.7.
+// The name may be qualified, like @java.lang.Override
+func (p *Parser) parseAnnotation() *annotation {
+	at := p.token
+	if !p.expectNext(IDENTIFIER) {
+		return nil
+	}
+	kind := p.parseTypeName()
+	a := &annotation{expression: expression{node{kind.name, at.pos}}, kind: kind}
+	if p.peekToken.kind != OPAREN {
+		return a
+	}
+	p.nextToken()
+	for p.peekToken.kind != CPAREN {
+		name := node{"value", p.peekToken.pos}
+		if p.peekToken.kind == IDENTIFIER && p.peekAt(2).kind == ASSIGN {
+			p.nextToken()
+			name = p.token.node()
+			p.nextToken()
+		} else if len(a.elements) > 0 {
+			p.errorAt(p.peekToken.pos, "expected identifier, got %s", p.peekToken.kind)
+		}
+		value := p.parseElementValue()
+		if value == nil {
+			return nil
+		}
+		a.elements = append(a.elements, &elementValue{name: name, value: value})
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectNext(CPAREN) {
+		return nil
+	}
+	return a
+}
+
+// parseElementValue parses the value of an annotation element, which is an annotation, an array initializer
+// of element values or a conditional expression, JLS 9.7.1
+func (p *Parser) parseElementValue() Expression {
+	switch p.peekToken.kind {
+	case AT:
+		p.nextToken()
+		if a := p.parseAnnotation(); a != nil {
+			return a
+		}
+		return nil
+	case OBRACE:
+		p.nextToken()
+		init := &arrayInit{expression: p.token.expression()}
+		for p.peekToken.kind != CBRACE {
+			e := p.parseElementValue()
+			if e == nil {
+				return nil
+			}
+			init.elements = append(init.elements, e)
+			if p.peekToken.kind != COMMA {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectNext(CBRACE) {
+			return nil
+		}
+		return init
+	}
+	return p.parseExpression(assign)
+}
+
+// scanAnnotations returns the offset of the token after the annotations starting at peekAt(n)
+func (p *Parser) scanAnnotations(n int) int {
+	for p.peekAt(n).kind == AT && p.peekAt(n+1).kind == IDENTIFIER {
+		n += 2
+		for p.peekAt(n).kind == DOT && p.peekAt(n+1).kind == IDENTIFIER {
+			n += 2
+		}
+		if p.peekAt(n).kind != OPAREN {
+			continue
+		}
+		for depth := 0; ; n++ {
+			switch p.peekAt(n).kind {
+			case OPAREN:
+				depth++
+			case CPAREN:
+				depth--
+			case EOF:
+				return n
+			}
+			if depth == 0 {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// parseElementDefault parses the default value of an element of an annotation interface, following its parameters
+// and throws clause, or returns nil when it has none, JLS 9.6.2. Elements take no parameters and throw nothing
+func (p *Parser) parseElementDefault(params []*parameter) Expression {
+	switch {
+	case len(params) > 0:
+		p.errorAt(params[0].name.pos, "@interface members may not have parameters")
+	case len(p.decl.throws) > 0:
+		p.errorAt(p.decl.throws[0].pos, "throws clause not allowed in @interface members")
+	}
+	if p.peekToken.kind != DEFAULT {
+		return nil
+	}
+	p.nextToken()
+	return p.parseElementValue()
+}
+
+// builtinTargets are the kinds of declarations the annotation interfaces of java.lang apply to, JLS 9.6.4
+var builtinTargets = map[string][]string{
+	"Override":            {"METHOD"},
+	"FunctionalInterface": {"TYPE"},
+	"SafeVarargs":         {"CONSTRUCTOR", "METHOD"},
+	"Deprecated":          {"TYPE", "FIELD", "METHOD", "PARAMETER", "CONSTRUCTOR", "LOCAL_VARIABLE", "ANNOTATION_TYPE"},
+	"SuppressWarnings":    {"TYPE", "FIELD", "METHOD", "PARAMETER", "CONSTRUCTOR", "LOCAL_VARIABLE", "ANNOTATION_TYPE"},
+}
+
+// objectMethods are the signatures of the methods of Object that a class may override
+var objectMethods = []string{"equals(Object)", "hashCode()", "toString()", "clone()", "finalize()"}
+
+// annotation returns the annotation of the annotation interface name in m, or nil.
+// The annotation interfaces of java.lang may be named by their qualified name too
+func (m modifiers) annotation(name string) *annotation {
+	for _, a := range m.annotations {
+		if a.kind.name == name || a.kind.name == "java.lang."+name {
+			return a
+		}
+	}
+	return nil
+}
+
+// checkAnnotations checks the annotations of c and of its members, parameters and locals against the annotation
+// interfaces they use, and enforces the built-in annotations @Override and @FunctionalInterface, JLS 9.6.4
+func (p *Parser) checkAnnotations(f *file, c *class) {
+	kind := []string{"TYPE"}
+	if c.isAnnotation {
+		kind = append(kind, "ANNOTATION_TYPE")
+		p.checkElements(f, c)
+	}
+	p.checkUses(f, c, c.annotations, kind...)
+	if a := c.annotation("FunctionalInterface"); a != nil {
+		if _, function := f.functionalInterface(classType(c.binaryName(), c.pos)); !c.isInterface || c.isAnnotation || function == nil {
+			p.errorAt(a.pos, "Unexpected @FunctionalInterface annotation: %s is not a functional interface", c.name)
+		}
+	}
+	for _, fld := range c.fields {
+		p.checkUses(f, c, fld.annotations, "FIELD")
+	}
+	for _, m := range c.methods {
+		if m.implicit {
+			continue
+		}
+		kind := "METHOD"
+		if m.isConstructor {
+			kind = "CONSTRUCTOR"
+		}
+		p.checkUses(f, c, m.annotations, kind)
+		if a := m.annotation("Override"); a != nil && !m.isConstructor && (m.isStatic || !f.overrides(c, m)) {
+			p.errorAt(a.pos, "method does not override or implement a method from a supertype")
+		}
+		for _, param := range m.parameters {
+			p.checkUses(f, c, param.annotations, "PARAMETER")
+		}
+	}
+	var locals []*localVars
+	for _, m := range c.bodies() {
+		inspectAll(m.statements, func(n Node) bool {
+			if l, ok := n.(*localVars); ok {
+				locals = append(locals, l)
+			}
+			return true
+		})
+	}
+	for _, l := range locals {
+		p.checkUses(f, c, l.annotations, "LOCAL_VARIABLE")
+	}
+}
+
+// checkUses reports the annotations that don't apply to a declaration of the given kinds, named after
+// the constants of ElementType, and the element values that don't match their annotation interface, JLS 9.7
+func (p *Parser) checkUses(f *file, c *class, annotations []*annotation, kinds ...string) {
+	for i, a := range annotations {
+		decl := f.class(f.nestedName(c, a.kind.name))
+		targets, builtin := builtinTargets[strings.TrimPrefix(a.kind.name, "java.lang.")]
+		switch {
+		case decl != nil && !decl.isAnnotation:
+			p.errorAt(a.pos, "%s is not an annotation type", a.kind.name)
+			continue
+		case decl != nil:
+			targets = decl.targets()
+		case !builtin:
+			// The annotation interface isn't known, like those of the class library
+			continue
+		}
+		if targets != nil && !slices.ContainsFunc(kinds, func(k string) bool { return slices.Contains(targets, k) }) {
+			p.errorAt(a.pos, "annotation type not applicable to this kind of declaration")
+		}
+		if slices.ContainsFunc(annotations[:i], func(o *annotation) bool { return o.kind.name == a.kind.name }) {
+			p.errorAt(a.pos, "%s is not a repeatable annotation type", a.kind.name)
+		}
+		if decl != nil {
+			p.checkElementValues(f, c, decl, a)
+		}
+	}
+}
+
+// checkElementValues reports the element values of a that decl doesn't declare or whose type doesn't match
+// the element, and the elements of decl without a default value that a leaves out. c is the class a is written in
+func (p *Parser) checkElementValues(f *file, c *class, decl *class, a *annotation) {
+	for _, e := range a.elements {
+		i := slices.IndexFunc(decl.methods, func(m *method) bool { return m.name == e.name.name })
+		if i < 0 {
+			p.errorAt(e.name.pos, "cannot find symbol: method %s() in %s", e.name.name, decl.name)
+			continue
+		}
+		p.checkElementValue(f, c, decl.methods[i].kind, e.value)
+	}
+	for _, m := range decl.methods {
+		if m.defaultValue == nil && !slices.ContainsFunc(a.elements, func(e *elementValue) bool { return e.name.name == m.name }) {
+			p.errorAt(a.pos, "annotation @%s is missing a default value for the element '%s'", a.kind.name, m.name)
+		}
+	}
+}
+
+// checkElementValue reports a value of an element of type kind that doesn't match it, JLS 9.7.1. A primitive
+// or String takes a value assignable to it, an enum one of its constants and an annotation interface an annotation
+// of it, and an array an array initializer of such values or a single one. c is the class the value is written in
+func (p *Parser) checkElementValue(f *file, c *class, kind *typeRef, value Expression) {
+	if init, ok := value.(*arrayInit); ok {
+		if !kind.isArray() {
+			p.errorAt(init.pos, "illegal initializer for %s", kind)
+			return
+		}
+		for _, e := range init.elements {
+			p.checkElementValue(f, c, arrayType(kind, -1), e)
+		}
+		return
+	}
+	if kind.isArray() {
+		kind = arrayType(kind, -1)
+	}
+	target := f.class(kind.name)
+	switch a, isAnnotation := value.(*annotation); {
+	case kind.kind != IDENTIFIER || kind.isString() || kind.name == "java.lang.String":
+		p.checkElement(c, kind, value)
+	case target != nil && target.isEnum:
+		var name string
+		switch v := value.(type) {
+		case *identifier:
+			name = v.name
+		case *fieldAccess:
+			name = v.name
+		}
+		if !slices.ContainsFunc(target.constants, func(e *enumConstant) bool { return e.name == name }) {
+			p.errorAt(value.Position(), "an enum annotation value must be an enum constant")
+		}
+	case target != nil && target.isAnnotation && !isAnnotation:
+		p.errorAt(value.Position(), "annotation value must be an annotation")
+	case target != nil && target.isAnnotation:
+		if decl := f.class(f.nestedName(c, a.kind.name)); decl != target {
+			p.errorAt(a.pos, "incompatible types: %s cannot be converted to %s", a.kind.name, kind)
+		} else {
+			p.checkElementValues(f, c, decl, a)
+		}
+	}
+}
+
+// targets returns the kinds of declarations the annotation interface c applies to, listed by its @Target
+// annotation, or nil when it applies to every declaration
+func (c *class) targets() []string {
+	a := c.annotation("Target")
+	if a == nil && c.annotation("java.lang.annotation.Target") != nil {
+		a = c.annotation("java.lang.annotation.Target")
+	}
+	if a == nil || len(a.elements) == 0 {
+		return nil
+	}
+	var targets []string
+	inspect(a.elements[0].value, func(n Node) bool {
+		switch n := n.(type) {
+		case *fieldAccess:
+			targets = append(targets, n.name)
+			return false
+		case *identifier:
+			targets = append(targets, n.name)
+		}
+		return true
+	})
+	return targets
+}
+
+// checkElements reports the elements of the annotation interface c whose type isn't allowed, JLS 9.6.1,
+// and the default values that don't match their element.
+// An element is a primitive, String, Class, an enum or an annotation interface, or an array of them
+func (p *Parser) checkElements(f *file, c *class) {
+	for _, m := range c.methods {
+		kind := m.kind
+		if kind.dims > 1 {
+			p.errorAt(kind.pos, "invalid type for annotation member")
+			continue
+		}
+		if kind.kind != IDENTIFIER {
+			if kind.kind == VOID {
+				p.errorAt(kind.pos, "invalid type for annotation member")
+			}
+			continue
+		}
+		switch name := strings.TrimPrefix(kind.name, "java.lang."); {
+		case name == "String", name == "Class":
+		case name == "Object", f.class(name) != nil && !f.class(name).isEnum && !f.class(name).isAnnotation:
+			p.errorAt(kind.pos, "invalid type for annotation member")
+		}
+	}
+	for _, m := range c.methods {
+		if m.defaultValue != nil {
+			p.checkElementValue(f, c, m.kind, m.defaultValue)
+		}
+	}
+}
+
+// overrides reports whether m, a method of c, overrides or implements a method of a supertype, JLS 9.6.4.4.
+// The methods of Object and the accessors of record components count, and m is assumed to override
+// when c has a supertype that isn't known
+func (f *file) overrides(c *class, m *method) bool {
+	if slices.Contains(objectMethods, m.signature()) {
+		return true
+	}
+	if c.isRecord && len(m.parameters) == 0 && slices.ContainsFunc(c.components, func(comp *parameter) bool { return comp.name.name == m.name }) {
+		return true
+	}
+	seen := map[*class]bool{}
+	var walk func(s *class) bool
+	walk = func(s *class) bool {
+		if seen[s] {
+			return false
+		}
+		seen[s] = true
+		// The interfaces of the class library aren't generic here, so their methods are matched by name and arity
+		library := f.class(s.binaryName()) != s
+		args := c.supertypeArgs(s, map[*typeParam]*typeRef{})
+		for _, o := range s.methods {
+			if s == c || o.isConstructor || o.isStatic || o.visibility == PRIVATE {
+				continue
+			}
+			if library && o.name == m.name && len(o.parameters) == len(m.parameters) || !library && c.overrider(o, args) == m {
+				return true
+			}
+		}
+		supertypes := s.supertypes()
+		for _, t := range append([]*typeRef{s.extends}, s.implements...) {
+			if t == nil || f.class(t.name) != nil {
+				continue
+			}
+			known, _ := f.functionalInterface(t)
+			if known == nil {
+				return true
+			}
+			supertypes = append(supertypes, known)
+		}
+		return slices.ContainsFunc(supertypes, walk)
+	}
+	return walk(c)
+}
+
+// checkDeprecations warns about the uses of deprecated classes and members in c, JLS 9.6.4.6. Uses in the same
+// outermost class as the declaration, and in declarations that are deprecated or suppress deprecation warnings
+// themselves, are left out
+func (p *Parser) checkDeprecations(f *file, c *class) {
+	for o := c; o != nil; o = o.outer {
+		if o.suppressesDeprecation() {
+			return
+		}
+	}
+	for _, t := range append([]*typeRef{c.extends}, c.implements...) {
+		if t != nil {
+			p.checkDeprecated(f, c, t.pos, f.class(t.name))
+		}
+	}
+	use := func(n Node) bool {
+		switch n := n.(type) {
+		case *newObject:
+			target := f.class(n.kind.name)
+			p.checkDeprecated(f, c, n.kind.pos, target)
+			if n.constructor != nil && n.constructor.annotation("Deprecated") != nil && target != nil && !target.isDeprecated() {
+				p.checkDeprecated(f, c, n.pos, n.constructor)
+			}
+		case *call:
+			if n.method != nil {
+				p.checkDeprecated(f, c, n.pos, n.method)
+			}
+		case *identifier:
+			if n.outer != nil {
+				p.checkDeprecated(f, c, n.pos, n.outer.inheritedField(n.name))
+			} else if n.local == nil {
+				p.checkDeprecated(f, c, n.pos, f.class(n.name))
+			}
+		case *fieldAccess:
+			if id, ok := n.target.(*identifier); ok && id.local == nil && f.class(id.name) != nil {
+				p.checkDeprecated(f, c, n.pos, f.class(id.name).inheritedField(n.name))
+			}
+		case *localVars:
+			p.checkDeprecated(f, c, n.kind.pos, f.class(n.kind.name))
+		}
+		return true
+	}
+	for _, fld := range c.fields {
+		if !fld.suppressesDeprecation() {
+			p.checkDeprecated(f, c, fld.kind.pos, f.class(fld.kind.name))
+			inspect(fld.value, use)
+		}
+	}
+	for _, m := range c.bodies() {
+		if !m.suppressesDeprecation() && !m.implicit {
+			inspectAll(m.statements, use)
+		}
+	}
+}
+
+// checkDeprecated warns about a use at pos in c of the class, field or method d when it is deprecated
+func (p *Parser) checkDeprecated(f *file, c *class, pos *pos, d any) {
+	var owner *class
+	var name string
+	switch d := d.(type) {
+	case *class:
+		if d == nil || !d.isDeprecated() {
+			return
+		}
+		owner, name = d, d.name
+	case *field:
+		if d == nil || d.annotation("Deprecated") == nil {
+			return
+		}
+		owner, name = f.declaring(d.decl), d.name
+	case *method:
+		if d == nil || d.annotation("Deprecated") == nil {
+			return
+		}
+		owner, name = f.declaring(d.decl), d.signature()
+	default:
+		return
+	}
+	if owner == nil || owner.outermost() == c.outermost() {
+		return
+	}
+	if _, ok := d.(*class); ok {
+		pkg := "unnamed package"
+		if f.pkg.name != "" {
+			pkg = f.pkg.name
+		}
+		p.warnAt(pos, "[deprecation] %s in %s has been deprecated", name, pkg)
+		return
+	}
+	p.warnAt(pos, "[deprecation] %s in %s has been deprecated", name, owner.name)
+}
+
+// isDeprecated reports whether c or a class it is nested in is deprecated
+func (c *class) isDeprecated() bool {
+	for ; c != nil; c = c.outer {
+		if c.annotation("Deprecated") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressesDeprecation reports whether m is deprecated or suppresses deprecation warnings with
+// @SuppressWarnings("deprecation")
+func (m modifiers) suppressesDeprecation() bool {
+	if m.annotation("Deprecated") != nil {
+		return true
+	}
+	a := m.annotation("SuppressWarnings")
+	suppressed := false
+	if a != nil && len(a.elements) > 0 {
+		inspect(a.elements[0].value, func(n Node) bool {
+			if l, ok := n.(*literal); ok && l.value == "deprecation" {
+				suppressed = true
+			}
+			return true
+		})
+	}
+	return suppressed
+}
+
+// outermost returns the top level class c is nested in, or c itself
+func (c *class) outermost() *class {
+	for c.outer != nil {
+		c = c.outer
+	}
+	return c
+}
@@ -0,0 +1,137 @@
+package parser
+
+import "slices"
+
+// parseInitializer parses the initializer of a variable of type kind, following the assignment operator.
+// Array variables may be initialized with an array initializer like {1, 2}
+func (p *Parser) parseInitializer(kind *typeRef) Expression {
+	if p.peekToken.kind != OBRACE {
+		value := p.parseExpression(lowest)
+		targetFunction(kind, value)
+		p.inferDiamond(kind, value)
+		p.checkTypeArgs(kind, value)
+		return value
+	}
+	p.nextToken()
+	if init := p.parseArrayInit(kind); init != nil {
+		return init
+	}
+	return nil
+}
+
+// parseArrayInit parses the elements of an array initializer creating an array of kind, following its opening brace.
+// A trailing comma is allowed after the last element, JLS 10.6
+func (p *Parser) parseArrayInit(kind *typeRef) *arrayInit {
+	a := &arrayInit{expression: p.token.expression(), kind: kind}
+	elem := kind
+	switch {
+	case kind.isArray():
+		elem = arrayType(kind, -1)
+	case kind.kind != IDENTIFIER || kind.name != "var":
+		// An inferred local reports the missing type itself, see inferLocalVar
+		p.errorf("illegal initializer for %s", kind)
+	}
+	for p.peekToken.kind != CBRACE {
+		var e Expression
+		if p.peekToken.kind == OBRACE {
+			p.nextToken()
+			if init := p.parseArrayInit(elem); init != nil {
+				e = init
+			}
+		} else {
+			e = p.parseExpression(lowest)
+			p.checkElement(p.class, elem, e)
+		}
+		if e == nil {
+			return nil
+		}
+		a.elements = append(a.elements, e)
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectNext(CBRACE) {
+		return nil
+	}
+	return a
+}
+
+// checkElement reports an element of an array initializer in class c that can't be assigned to the element type kind.
+// Constants may be narrowed to byte, short and char when they fit, JLS 5.2
+func (p *Parser) checkElement(c *class, kind *typeRef, e Expression) {
+	from := p.typeOf(e)
+	if from == nil || assignable(from, kind) {
+		return
+	}
+	if value, ok := c.constant(e); ok && isNumericConstant(value) && kind.isNumeric() {
+		if lossyConversion(value, kind) {
+			p.errorAt(e.Position(), "incompatible types: possible lossy conversion from %s to %s", from, kind)
+		}
+		return
+	}
+	if from.isNumeric() && kind.isNumeric() {
+		p.errorAt(e.Position(), "incompatible types: possible lossy conversion from %s to %s", from, kind)
+		return
+	}
+	p.errorAt(e.Position(), "incompatible types: %s cannot be converted to %s", from, kind)
+}
+
+// parseNewArray parses the dimensions and initializer of an array creation expression, following the element type elem.
+// t is the new keyword. The lengths of the first dimensions may be given, like new int[2][], or the elements, like new int[]{1, 2}
+func (p *Parser) parseNewArray(t *token, elem *typeRef) Expression {
+	a := &newArray{expression: t.expression()}
+	dims := 0
+	for p.peekToken.kind == OBRACKET {
+		p.nextToken()
+		if p.peekToken.kind == CBRACKET {
+			p.nextToken()
+			dims++
+			continue
+		}
+		if dims > len(a.dims) {
+			p.errorAt(p.peekToken.pos, "']' expected")
+		}
+		length := p.parseExpression(lowest)
+		p.checkIndex(length)
+		if !p.expectNext(CBRACKET) {
+			return nil
+		}
+		a.dims = append(a.dims, length)
+		dims++
+	}
+	a.kind = arrayType(elem, dims)
+	// The element type must be reifiable, JLS 15.10.1
+	if elem.args != nil && len(elem.args) == 0 {
+		p.errorAt(t.pos, "cannot create array with '<>'")
+	} else if elem.param != nil || slices.ContainsFunc(elem.args, func(arg *typeRef) bool { return arg.kind != QUESTION || arg.bound != nil }) {
+		p.errorAt(t.pos, "generic array creation")
+	}
+	switch {
+	case p.peekToken.kind == OBRACE:
+		if len(a.dims) > 0 {
+			p.errorAt(p.peekToken.pos, "array creation with both dimension expression and initialization is illegal")
+		}
+		p.nextToken()
+		if a.init = p.parseArrayInit(a.kind); a.init == nil {
+			return nil
+		}
+	case len(a.dims) == 0:
+		p.errorAt(p.peekToken.pos, "array dimension missing")
+	}
+	return a
+}
+
+// checkIndex reports an array index or length that isn't an int after unary numeric promotion, JLS 15.10.3
+func (p *Parser) checkIndex(index Expression) {
+	kind := p.typeOf(index)
+	switch {
+	case kind == nil:
+	case kind.isNumeric():
+		if promoted := promote(kind, kind); promoted.kind != INT {
+			p.errorAt(index.Position(), "incompatible types: possible lossy conversion from %s to int", kind)
+		}
+	case kind.kind != IDENTIFIER || kind.isString() || kind.isArray():
+		p.errorAt(index.Position(), "incompatible types: %s cannot be converted to int", kind)
+	}
+}
@@ -0,0 +1,956 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// checkClass runs the checks that need every member of c, once the class is parsed
+func (p *Parser) checkClass(c *class) {
+	if c.isEnum {
+		c.methods = append(c.methods, enumMethods(c)...)
+	}
+	if c.isRecord {
+		c.methods = append(c.methods, recordMembers(c)...)
+		p.checkRecord(c)
+	}
+	p.checkSignatures(c)
+	if len(c.constructors()) == 0 && !c.isInterface && !c.isAnonymous {
+		c.methods = append(c.methods, defaultConstructor(c))
+	}
+	for _, b := range c.blocks {
+		if !b.isStatic {
+			b.throws = c.initializerThrows()
+		}
+		p.checkForwardReferences(c, &block{statements: b.statements}, b.index, b.isStatic)
+	}
+	for _, m := range c.constructors() {
+		if call := m.constructorCall(); c.isEnum && call != nil && call.name == "super" {
+			p.errorAt(call.pos, "call to super not allowed in enum constructor")
+		}
+	}
+	for i, f := range c.fields {
+		if f.value == nil {
+			continue
+		}
+		p.checkForwardReferences(c, f.value, i, f.isStatic)
+		if !f.isFinal {
+			continue
+		}
+		if value, ok := c.fieldConstant(f, map[*field]bool{}); ok {
+			raw, _ := c.constant(f.value)
+			if lossyConversion(raw, f.kind) {
+				p.errorAt(f.value.Position(), "incompatible types: possible lossy conversion from %s to %s", constantTypeName(raw), f.kind)
+				continue
+			}
+			f.constant = value
+		}
+	}
+}
+
+// checkForwardReferences reports uses of a field by its simple name in value, the initializer of c.fields[i]
+// or an initializer block preceding it, before the field is declared. static tells the static initializers apart.
+// Fields may still be assigned before their declaration, JLS 8.3.3
+func (p *Parser) checkForwardReferences(c *class, value Node, i int, static bool) {
+	inspect(value, func(n Node) bool {
+		switch n := n.(type) {
+		case *assignment:
+			if _, ok := n.target.(*identifier); ok && n.op == ASSIGN {
+				inspect(n.value, func(n Node) bool { return p.checkForwardReference(c, value, i, static, n) })
+				return false
+			}
+		}
+		return p.checkForwardReference(c, value, i, static, n)
+	})
+}
+
+func (p *Parser) checkForwardReference(c *class, value Node, i int, static bool, n Node) bool {
+	id, ok := n.(*identifier)
+	if !ok || id.local != nil {
+		return true
+	}
+	for j := i; j < len(c.fields); j++ {
+		if g := c.fields[j]; g.name == id.name && g.isStatic == static {
+			if g.value == value {
+				p.errorAt(id.pos, "self-reference in initializer")
+			} else {
+				p.errorAt(id.pos, "illegal forward reference")
+			}
+			break
+		}
+	}
+	return true
+}
+
+// checkFinalFields reports the assignments to final fields in the bodies of c, JLS 16.
+// A blank final field may only be assigned by the constructors and initializers of the class declaring it,
+// except for the compact constructor of a record, which assigns the fields itself
+func (p *Parser) checkFinalFields(c *class) {
+	for _, m := range c.bodies() {
+		initializer := m.isInitializer() || m.isConstructor && !m.compact
+		var check func(Node) bool
+		check = func(n Node) bool {
+			switch n := n.(type) {
+			case *lambda:
+				// The body of a lambda may run after the object is initialized
+				outer := initializer
+				initializer = false
+				inspect(n.body, check)
+				initializer = outer
+				return false
+			case *assignment:
+				p.checkFinalAssignment(c, m.isStatic, initializer, n.target)
+			case *unary:
+				if n.op == INCREMENT || n.op == DECREMENT {
+					p.checkFinalAssignment(c, m.isStatic, initializer, n.operand)
+				}
+			}
+			return true
+		}
+		inspectAll(m.statements, check)
+	}
+}
+
+// checkFinalAssignment reports target when it is a final field that may not be assigned in a static or instance
+// context of c, which is an initializer or not. Only the simple name or this.name of a blank final may be assigned
+func (p *Parser) checkFinalAssignment(c *class, static, initializer bool, target Expression) {
+	owner, simple := c, false
+	var name string
+	switch target := target.(type) {
+	case *identifier:
+		if target.local != nil {
+			return
+		}
+		if target.outer != nil {
+			owner = target.outer
+		}
+		name, simple = target.name, true
+	case *fieldAccess:
+		switch t := target.target.(type) {
+		case *thisExpr:
+			owner, simple = t.class, true
+		case *identifier:
+			// The name of a class, or a variable of a class type
+			owner = p.file.class(t.name)
+			if t.local != nil {
+				owner = nil
+				if !t.local.kind.isArray() {
+					owner = p.file.class(t.local.kind.name)
+				}
+			}
+		default:
+			owner = nil
+			if kind := p.typeOf(t); kind != nil && !kind.isArray() {
+				owner = p.file.class(kind.name)
+			}
+		}
+		name = target.name
+	default:
+		return
+	}
+	f := owner.inheritedField(name)
+	if f == nil || !f.isFinal {
+		return
+	}
+	if simple && f.value == nil && owner == c && slices.Contains(c.fields, f) && initializer && static == f.isStatic {
+		return
+	}
+	p.errorAt(target.Position(), "cannot assign a value to final variable %s", f.name)
+}
+
+// constantTypeName returns the Java type of a constant value
+func constantTypeName(v any) string {
+	for name, to := range constantTypes {
+		if constantRank(to(v)) == constantRank(v) {
+			return name
+		}
+	}
+	if _, ok := v.(string); ok {
+		return "String"
+	}
+	return "boolean"
+}
+
+// initializers returns the fields of c with an initializer, in declaration order.
+// They run when the class is initialized for static fields, and when an object is created otherwise.
+// Static constants are left out, since their value is inlined where they are used
+func (c *class) initializers(static bool) []*field {
+	var fields []*field
+	for _, f := range c.fields {
+		if f.value != nil && f.isStatic == static && !(static && f.constant != nil) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// checkSignatures reports methods and constructors declared twice with the same parameter types,
+// or with parameter types that differ only in their type arguments
+func (p *Parser) checkSignatures(c *class) {
+	seen := map[string]*method{}
+	for _, m := range c.methods {
+		sig := m.signature()
+		if prev := seen[sig]; prev != nil {
+			kind := "method"
+			if m.isConstructor {
+				kind = "constructor"
+			}
+			if declared := prev.declaredSignature(); declared != m.declaredSignature() {
+				p.errorAt(m.pos, "name clash: %s and %s have the same erasure", m.declaredSignature(), declared)
+			} else {
+				p.errorAt(m.pos, "%s %s is already defined in class %s", kind, sig, c.name)
+			}
+		}
+		seen[sig] = m
+	}
+}
+
+// signature returns the name and erased parameter types of m, like m(int,List)
+func (m *method) signature() string {
+	params := make([]string, len(m.parameters))
+	for i, param := range m.parameters {
+		params[i] = param.kind.erasure().String()
+	}
+	return fmt.Sprintf("%s(%s)", m.name, strings.Join(params, ","))
+}
+
+// substitutedSignature returns the signature of m with the type variables mapped by args replaced by their type arguments
+func (m *method) substitutedSignature(args map[*typeParam]*typeRef) string {
+	params := make([]string, len(m.parameters))
+	for i, param := range m.parameters {
+		params[i] = param.kind.substitute(args).erasure().String()
+	}
+	return fmt.Sprintf("%s(%s)", m.name, strings.Join(params, ","))
+}
+
+// declaredSignature returns the name and parameter types of m as declared, like m(int,List<T>)
+func (m *method) declaredSignature() string {
+	params := make([]string, len(m.parameters))
+	for i, param := range m.parameters {
+		params[i] = param.kind.String()
+	}
+	return fmt.Sprintf("%s(%s)", m.name, strings.Join(params, ","))
+}
+
+// constructors returns the constructors of c, in declaration order
+func (c *class) constructors() []*method {
+	var ctors []*method
+	for _, m := range c.methods {
+		if m.isConstructor {
+			ctors = append(ctors, m)
+		}
+	}
+	return ctors
+}
+
+// anonymousConstructor returns the constructor of the anonymous class created by n, which passes its arguments
+// to the constructor of the superclass, JLS 15.9.5.1
+func anonymousConstructor(n *newObject) *method {
+	c := n.body
+	call := &constructorCall{expression: expression{node{"super", c.pos}}, constructor: n.constructor}
+	m := &method{
+		decl:     &decl{node: node{c.name, c.pos}, kind: primitiveType(VOID, c.pos), isConstructor: true},
+		body:     body{statements: []Statement{&expressionStmt{statement: statement{call.node}, expr: call}}},
+		implicit: true,
+	}
+	count := len(n.args)
+	if n.constructor != nil {
+		// A variable arity constructor passes on the array of its last parameter as it is
+		count = len(n.constructor.parameters)
+	}
+	for i := range count {
+		param := &parameter{name: node{fmt.Sprintf("x%d", i), c.pos}, kind: classType("Object", c.pos)}
+		if n.constructor != nil {
+			param.kind, param.isVarargs = n.constructor.parameters[i].kind, n.constructor.parameters[i].isVarargs
+		}
+		m.parameters = append(m.parameters, param)
+		call.args = append(call.args, &identifier{expression: expression{param.name}})
+	}
+	return m
+}
+
+// defaultConstructor returns the constructor of a class that declares none, with the visibility of the class, JLS 8.8.9.
+// The default constructor of an enum is private
+func defaultConstructor(c *class) *method {
+	visibility := c.visibility
+	if c.isEnum {
+		visibility = PRIVATE
+	}
+	return &method{
+		decl: &decl{
+			node:          c.node,
+			kind:          primitiveType(VOID, c.pos),
+			isConstructor: true,
+			modifiers:     modifiers{visibility: visibility},
+		},
+		implicit: true,
+	}
+}
+
+// checkConstructorCalls reports this(...) calls anywhere but in the first statement of a constructor
+func (p *Parser) checkConstructorCalls(m *method) {
+	for i, stmt := range m.statements {
+		inspect(stmt, func(n Node) bool {
+			call, ok := n.(*constructorCall)
+			if ok && !(m.isConstructor && i == 0 && stmt.(*expressionStmt).expr == call) {
+				p.errorAt(call.pos, "call to %s must be first statement in constructor", call.name)
+			}
+			return true
+		})
+	}
+}
+
+// checkFile checks the class hierarchy and the generic types of f, and resolves the methods and constructors called,
+// once every file is parsed and linked, see linkSuperclasses
+func (p *Parser) checkFile(f *file) {
+	p.checkInheritance(f)
+	p.checkGenerics(f)
+	for _, c := range f.classes {
+		p.checkOverrides(f, c)
+		p.checkAbstractMethods(c)
+		static := false
+		check := func(n Node) bool {
+			p.resolveName(c, n)
+			p.resolveCall(f, c, static, n)
+			p.checkEnumSwitch(f, n)
+			p.checkFunction(f, c, n)
+			return true
+		}
+		for _, field := range c.fields {
+			static = field.isStatic
+			inspect(field.value, check)
+		}
+		for _, m := range c.bodies() {
+			static = m.isStatic
+			inspectAll(m.statements, check)
+		}
+		for _, m := range c.bodies() {
+			p.checkExceptions(f, m)
+			p.checkCaptures(m)
+		}
+		p.checkFieldExceptions(f, c)
+		p.checkFinalFields(c)
+		p.checkMembers(f, c)
+		p.checkAnnotations(f, c)
+		p.checkDeprecations(f, c)
+		for _, e := range c.constants {
+			e.constructor = p.resolveConstructor(c, e.args, e.pos)
+		}
+		for _, m := range c.constructors() {
+			p.checkRecursiveConstructor(m)
+			if m.constructorCall() == nil && c.superclass != nil {
+				p.checkImplicitSuper(f, m, p.resolveConstructor(c.superclass, nil, m.pos))
+			}
+		}
+	}
+}
+
+// resolveCall resolves the method or constructor called by n, when it is declared in f.
+// c is the class containing the call, and static is set when the call is in a static context of c
+func (p *Parser) resolveCall(f *file, c *class, static bool, n Node) {
+	switch n := n.(type) {
+	case *newObject:
+		if n.outer != nil {
+			p.resolveInnerClass(f, n)
+		}
+		target := f.class(n.kind.name)
+		switch {
+		case target == nil:
+		case target.isEnum:
+			p.errorAt(n.pos, "enum classes may not be instantiated")
+		case n.body != nil && target.isInterface:
+			if len(n.args) > 0 {
+				p.errorAt(n.pos, "anonymous class implements interface; cannot have arguments")
+			}
+		case target.isAbstract && n.body == nil:
+			p.errorAt(n.pos, "%s is abstract; cannot be instantiated", target.name)
+		case target.inner && n.outer == nil && (static || !c.enclosedBy(target.outer)):
+			p.errorAt(n.pos, "non-static variable this cannot be referenced from a static context")
+		default:
+			n.constructor = p.resolveConstructor(target, n.args, n.pos)
+			targetArgs(n.constructor, n.args)
+		}
+		if n.body != nil {
+			n.body.methods = append(n.body.methods, anonymousConstructor(n))
+		}
+	case *constructorCall:
+		if n.name == "this" {
+			n.constructor = p.resolveConstructor(c, n.args, n.pos)
+		} else if c.superclass != nil {
+			n.constructor = p.resolveConstructor(c.superclass, n.args, n.pos)
+		}
+		targetArgs(n.constructor, n.args)
+	case *call:
+		var owner *class
+		switch target := n.target.(type) {
+		case nil:
+			// An unqualified call invokes a method of the innermost enclosing class declaring one with its name, JLS 15.12.1
+			owner = c
+			for o := c; o != nil; o = o.outer {
+				if len(o.methodsNamed(n.name)) > 0 {
+					owner = o
+					break
+				}
+			}
+			if owner != c {
+				n.outer = owner
+			}
+		case *thisExpr:
+			owner = target.class
+		case *superExpr:
+			owner = target.owner(f)
+			if target.iface != nil && owner == nil && f.class(target.iface.name) != nil {
+				p.errorAt(target.pos, "not an enclosing class: %s", target.iface.name)
+			}
+		case *identifier:
+			// A name that isn't a variable is taken to be a class, whose static methods are called
+			if target.local != nil && !target.local.kind.isArray() {
+				owner = f.class(target.local.kind.name)
+			} else {
+				owner = f.class(target.name)
+			}
+		case *newObject:
+			owner = f.class(target.kind.name)
+		}
+		candidates := owner.methodsNamed(n.name)
+		// Unqualified calls to methods c doesn't have may name a method imported with import static
+		if n.target == nil && len(candidates) == 0 {
+			candidates = f.staticImports(n.name)
+		}
+		if len(candidates) > 0 {
+			var applicable []*method
+			applicable, n.method = p.resolve(candidates, n.args)
+			if p.ambiguous(applicable, n.method, n.args) {
+				p.errorAt(n.pos, "reference to %s is ambiguous", n.name)
+			}
+			targetArgs(n.method, n.args)
+		}
+		if _, isSuper := n.target.(*superExpr); isSuper && n.method != nil && n.method.isAbstract {
+			p.errorAt(n.pos, "abstract method %s in %s cannot be accessed directly", n.method.signature(), f.root.classOf(n.method).name)
+		}
+		if n.outer != nil && n.method != nil && !n.method.isStatic && !c.enclosedBy(n.outer) {
+			p.errorAt(n.pos, "non-static method %s cannot be referenced from a static context", n.method.signature())
+		}
+	}
+}
+
+// targetArgs gives the lambdas and method references among args the parameter types of m, which is called with them.
+// Parameters whose type is a type variable don't give a functional interface
+func targetArgs(m *method, args []Expression) {
+	if m == nil || len(m.parameters) != len(args) {
+		return
+	}
+	for i, arg := range args {
+		if kind := m.parameters[i].kind; kind.param == nil {
+			targetFunction(kind, arg)
+		}
+	}
+}
+
+// linkSuperclasses resolves the superclass and interfaces of the classes of f, and reports supertypes of the wrong kind
+func (p *Parser) linkSuperclasses(f *file) {
+	for _, c := range f.classes {
+		if c.extends != nil {
+			c.superclass = f.class(c.extends.name)
+		}
+		// An anonymous class created from an interface implements it and extends Object
+		if c.isAnonymous && c.superclass != nil && c.superclass.isInterface {
+			c.implements, c.extends, c.superclass = []*typeRef{c.extends}, nil, nil
+		}
+		if c.superclass != nil && c.superclass.isInterface {
+			p.errorAt(c.extends.pos, "no interface expected here")
+			c.superclass = nil
+		}
+		for _, ref := range c.implements {
+			i := f.class(ref.name)
+			if i != nil && !i.isInterface {
+				p.errorAt(ref.pos, "interface expected here")
+				continue
+			}
+			if i != nil {
+				c.interfaces = append(c.interfaces, i)
+			}
+		}
+	}
+}
+
+// checkInheritance reports cyclic inheritance and extended final classes in f
+func (p *Parser) checkInheritance(f *file) {
+	for _, c := range f.classes {
+		if c.superclass != nil && c.superclass.isFinal {
+			p.errorAt(c.extends.pos, "cannot inherit from final %s", c.extends.name)
+		}
+		// The cycle is reported once, by the first class on it, which drops its supertypes to break it
+		if slices.ContainsFunc(c.supertypes(), func(s *class) bool { return s.reaches(c, map[*class]bool{}) }) {
+			p.errorAt(c.pos, "cyclic inheritance involving %s", c.name)
+			c.superclass, c.interfaces = nil, nil
+		}
+	}
+}
+
+// supertypes returns the direct superclass and superinterfaces of c declared in the file
+func (c *class) supertypes() []*class {
+	if c.superclass == nil {
+		return c.interfaces
+	}
+	return append([]*class{c.superclass}, c.interfaces...)
+}
+
+// reaches reports whether target is c or one of its supertypes
+func (c *class) reaches(target *class, seen map[*class]bool) bool {
+	if c == target {
+		return true
+	}
+	if seen[c] {
+		return false
+	}
+	seen[c] = true
+	return slices.ContainsFunc(c.supertypes(), func(s *class) bool { return s.reaches(target, seen) })
+}
+
+// accessRanks orders the visibilities from the most restrictive
+var accessRanks = []tokenKind{PRIVATE, PACKAGE, PROTECTED, PUBLIC}
+
+// checkOverrides reports the methods of c that can't override or hide the method they share a signature with
+// in a superclass, JLS 8.4.8. c is declared in f
+func (p *Parser) checkOverrides(f *file, c *class) {
+	for _, m := range c.methods {
+		if m.isConstructor {
+			continue
+		}
+		overridden, owner := c.inheritedMethod(m.signature())
+		if overridden == nil || overridden.visibility == PRIVATE {
+			continue
+		}
+		var reason string
+		undeclared := slices.IndexFunc(m.throws, func(t *typeRef) bool { return !f.declares(overridden, t) })
+		switch {
+		case overridden.isFinal:
+			reason = "overridden method is final"
+		case m.isStatic && !overridden.isStatic:
+			reason = "overriding method is static"
+		case !m.isStatic && overridden.isStatic:
+			reason = "overridden method is static"
+		case m.kind.String() != overridden.kind.String() && !(m.kind.kind == IDENTIFIER && overridden.kind.kind == IDENTIFIER):
+			reason = fmt.Sprintf("return type %s is not compatible with %s", m.kind, overridden.kind)
+		case slices.Index(accessRanks, m.visibility) < slices.Index(accessRanks, overridden.visibility):
+			reason = fmt.Sprintf("attempting to assign weaker access privileges; was %s", overridden.visibility)
+		case undeclared >= 0:
+			reason = fmt.Sprintf("overridden method does not throw %s", m.throws[undeclared])
+		default:
+			continue
+		}
+		verb := "override"
+		if m.isStatic && overridden.isStatic {
+			verb = "hide"
+		}
+		p.errorAt(m.pos, "%s in %s cannot %s %s in %s; %s", m.signature(), c.name, verb, overridden.signature(), owner.name, reason)
+	}
+}
+
+// lookupMethod returns the method with the signature sig declared by c or its closest supertype declaring it,
+// and the type declaring it. Superclasses are searched before interfaces, so a class method wins over a default method
+func (c *class) lookupMethod(sig string) (*method, *class) {
+	if c == nil {
+		return nil, nil
+	}
+	for _, m := range c.methods {
+		if !m.isConstructor && m.signature() == sig {
+			return m, c
+		}
+	}
+	return c.inheritedMethod(sig)
+}
+
+// inheritedMethod returns the method with the signature sig c inherits from its supertypes, see lookupMethod
+func (c *class) inheritedMethod(sig string) (*method, *class) {
+	var found *method
+	var owner *class
+	for _, s := range c.supertypes() {
+		m, o := s.lookupMethod(sig)
+		// A default method overrides the abstract methods of other interfaces, but not those of the superclass, JLS 8.4.8
+		if m != nil && (found == nil || owner.isInterface && found.isAbstract && !m.isAbstract) {
+			found, owner = m, o
+		}
+	}
+	return found, owner
+}
+
+// methodsNamed returns the methods named name that are members of c, declared or inherited.
+// Overridden methods are left out
+func (c *class) methodsNamed(name string) []*method {
+	if c == nil {
+		return nil
+	}
+	var methods []*method
+	c.collectMethods(name, &methods, map[*class]bool{})
+	return methods
+}
+
+func (c *class) collectMethods(name string, methods *[]*method, seen map[*class]bool) {
+	if seen[c] {
+		return
+	}
+	seen[c] = true
+	for _, m := range c.methods {
+		if m.isConstructor || m.name != name {
+			continue
+		}
+		if !slices.ContainsFunc(*methods, func(o *method) bool { return o.signature() == m.signature() }) {
+			*methods = append(*methods, m)
+		}
+	}
+	for _, s := range c.supertypes() {
+		s.collectMethods(name, methods, seen)
+	}
+}
+
+// checkAbstractMethods reports a class that isn't abstract and inherits an abstract method it doesn't implement, JLS 8.1.1.1.
+// An enum whose constants all have a body is checked through the bodies instead
+func (p *Parser) checkAbstractMethods(c *class) {
+	p.checkDefaults(c)
+	if c.isAbstract || c.isEnum && len(c.constants) > 0 && countBodies(c.constants) == len(c.constants) {
+		return
+	}
+	for _, m := range c.abstractMethods() {
+		if impl, _ := c.lookupMethod(m.sig); impl == nil || impl.isAbstract {
+			p.errorAt(c.pos, "%s is not abstract and does not override abstract method %s in %s", c.name, m.sig, m.owner.name)
+			return
+		}
+	}
+}
+
+// checkDefaults reports a class or interface that inherits a default method along with another method of the same
+// signature from an unrelated interface, without overriding them, JLS 8.4.8.4 and 9.4.1.3
+func (p *Parser) checkDefaults(c *class) {
+	inherited := map[string][]abstractMethod{}
+	var sigs []string
+	for _, i := range c.superinterfaces() {
+		args := c.supertypeArgs(i, map[*typeParam]*typeRef{})
+		for _, m := range i.methods {
+			if m.isStatic || m.visibility == PRIVATE {
+				continue
+			}
+			sig := m.substitutedSignature(args)
+			if inherited[sig] == nil {
+				sigs = append(sigs, sig)
+			}
+			inherited[sig] = append(inherited[sig], abstractMethod{sig, m, i})
+		}
+	}
+	for _, sig := range sigs {
+		// A method of a class wins over those of interfaces
+		if m, _ := c.lookupMethod(sig); m == nil || c.declares(m) {
+			continue
+		}
+		// Of the methods inherited, only those not overridden from a subinterface count
+		methods := inherited[sig]
+		methods = slices.DeleteFunc(slices.Clone(methods), func(m abstractMethod) bool {
+			return slices.ContainsFunc(methods, func(o abstractMethod) bool { return o.owner != m.owner && o.owner.reaches(m.owner, map[*class]bool{}) })
+		})
+		d := slices.IndexFunc(methods, func(m abstractMethod) bool { return m.method.isDefault })
+		if len(methods) < 2 || d < 0 {
+			continue
+		}
+		other := methods[(d+1)%len(methods)]
+		inherits := "unrelated defaults"
+		if other.method.isAbstract {
+			inherits = "abstract and default"
+		}
+		first, second := methods[d].owner, other.owner
+		if d > slices.Index(methods, other) {
+			first, second = second, first
+		}
+		p.errorAt(c.pos, "types %s and %s are incompatible; %s %s inherits %s for %s from types %s and %s",
+			first.name, second.name, c.kindName(), c.name, inherits, sig, first.name, second.name)
+		return
+	}
+}
+
+// kindName returns the keyword declaring c, used to name it in diagnostics
+func (c *class) kindName() string {
+	switch {
+	case c.isEnum:
+		return "enum"
+	case c.isRecord:
+		return "record"
+	case c.isInterface:
+		return "interface"
+	}
+	return "class"
+}
+
+// superinterfaces returns the interfaces c implements or extends, directly or through its supertypes
+func (c *class) superinterfaces() []*class {
+	var interfaces []*class
+	seen := map[*class]bool{c: true}
+	var walk func(*class)
+	walk = func(t *class) {
+		for _, s := range t.supertypes() {
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			if s.isInterface {
+				interfaces = append(interfaces, s)
+			}
+			walk(s)
+		}
+	}
+	walk(c)
+	return interfaces
+}
+
+// declares reports whether m is declared by c or one of its superclasses
+func (c *class) declares(m *method) bool {
+	for ; c != nil; c = c.superclass {
+		if slices.Contains(c.methods, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// abstractMethod is an abstract method and the type declaring it.
+// sig is the signature of the method in the class it is looked up from, see abstractMethods
+type abstractMethod struct {
+	sig    string
+	method *method
+	owner  *class
+}
+
+// abstractMethods returns the abstract methods of c and its supertypes, once per signature
+func (c *class) abstractMethods() []abstractMethod {
+	var methods []abstractMethod
+	seen := map[*class]bool{}
+	var walk func(*class)
+	walk = func(t *class) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		// The signature is the one an implementation in c has, with the type arguments c gives t substituted
+		args := c.supertypeArgs(t, map[*typeParam]*typeRef{})
+		for _, m := range t.methods {
+			if sig := m.substitutedSignature(args); m.isAbstract && !slices.ContainsFunc(methods, func(o abstractMethod) bool { return o.sig == sig }) {
+				methods = append(methods, abstractMethod{sig, m, t})
+			}
+		}
+		for _, s := range t.supertypes() {
+			walk(s)
+		}
+	}
+	walk(c)
+	return methods
+}
+
+// class returns the class named name that is visible in f, or nil.
+// Simple names are looked up in f, the single-type imports, the package of f and the imports on demand in that order, JLS 6.4.1.
+// Qualified names are looked up in the packages of the source root
+func (f *file) class(name string) *class {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return f.root.pkg(name[:i]).class(name[i+1:])
+	}
+	for _, c := range f.classes {
+		if c.binaryName() == name {
+			return c
+		}
+	}
+	// The member classes of imported classes are found through the top level class
+	if top, _, ok := strings.Cut(name, "$"); ok {
+		if c := f.class(top); c != nil {
+			return c.nestedClass(name)
+		}
+	}
+	for _, imp := range f.imports {
+		if !imp.isStatic && imp.alias == name {
+			return f.root.pkg(imp.pkgName).class(name)
+		}
+	}
+	if c := f.pkg.class(name); c != nil {
+		return c
+	}
+	for _, imp := range f.imports {
+		if !imp.isStatic && imp.alias == "*" {
+			if c := f.root.pkg(imp.pkgName).class(name); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// resolveConstructor returns the constructor of c applicable to args, see resolve.
+// An error is reported when no constructor is applicable
+func (p *Parser) resolveConstructor(c *class, args []Expression, pos *pos) *method {
+	applicable, ctor := p.resolve(c.constructors(), args)
+	if p.ambiguous(applicable, ctor, args) {
+		p.errorAt(pos, "reference to %s is ambiguous", c.name)
+	}
+	if len(applicable) == 0 {
+		kinds := make([]string, len(args))
+		for i, arg := range args {
+			kinds[i] = "?"
+			if kind := p.typeOf(arg); kind != nil {
+				kinds[i] = kind.String()
+			}
+		}
+		p.errorAt(pos, "no suitable constructor found for %s(%s)", c.name, strings.Join(kinds, ","))
+	}
+	return ctor
+}
+
+// resolve returns the candidates applicable to args and the most specific of them, JLS 15.12.2.
+// Variable arity methods are only considered when no method is applicable by its fixed arity.
+// The most specific method is nil when the choice is ambiguous
+func (p *Parser) resolve(candidates []*method, args []Expression) ([]*method, *method) {
+	var applicable []*method
+	varargs := false
+	for _, varargs = range []bool{false, true} {
+		for _, m := range candidates {
+			if p.applicable(m, args, varargs) {
+				applicable = append(applicable, m)
+			}
+		}
+		if len(applicable) > 0 {
+			break
+		}
+	}
+	if len(applicable) == 1 {
+		return applicable, applicable[0]
+	}
+	// The most specific method has parameter types that every other candidate accepts
+	for _, m := range applicable {
+		specific := true
+		for _, other := range applicable {
+			for i := range max(len(args), len(m.parameters), len(other.parameters)) {
+				specific = specific && assignable(m.parameterType(i, varargs), other.parameterType(i, varargs))
+			}
+		}
+		if specific {
+			return applicable, m
+		}
+	}
+	return applicable, nil
+}
+
+// ambiguous reports whether none of several applicable methods is the most specific one, though the type
+// of every argument is known
+func (p *Parser) ambiguous(applicable []*method, m *method, args []Expression) bool {
+	return len(applicable) > 1 && m == nil && !slices.ContainsFunc(args, func(arg Expression) bool {
+		l, ok := arg.(*literal)
+		return p.typeOf(arg) == nil && (!ok || l.kind != NULL_LITERAL)
+	})
+}
+
+// applicable reports whether m may be called with args, arguments of unknown type are accepted.
+// With varargs the trailing arguments of a variable arity method are taken as the elements of its last parameter
+func (p *Parser) applicable(m *method, args []Expression, varargs bool) bool {
+	switch {
+	case varargs && (!m.isVarargs() || len(args) < len(m.parameters)-1):
+		return false
+	case !varargs && len(m.parameters) != len(args):
+		return false
+	}
+	for i, arg := range args {
+		param := m.parameterType(i, varargs)
+		if l, ok := arg.(*literal); ok && l.kind == NULL_LITERAL {
+			if slices.Contains(primitives, param.kind) && !param.isArray() {
+				return false
+			}
+			continue
+		}
+		if kind := p.typeOf(arg); kind != nil && !assignable(kind, param) {
+			return false
+		}
+	}
+	return true
+}
+
+// isVarargs reports whether m is a variable arity method, JLS 8.4.1
+func (m *method) isVarargs() bool {
+	return len(m.parameters) > 0 && m.parameters[len(m.parameters)-1].isVarargs
+}
+
+// parameterType returns the type of the parameter of m that the i-th argument is passed for.
+// With varargs the arguments from the last parameter on are elements of its array
+func (m *method) parameterType(i int, varargs bool) *typeRef {
+	if last := len(m.parameters) - 1; varargs && i >= last {
+		return arrayType(m.parameters[last].kind, -1)
+	}
+	return m.parameters[i].kind
+}
+
+// widening lists the primitive types each primitive type widens to, JLS 5.1.2
+var widening = map[string][]string{
+	"byte":  {"short", "int", "long", "float", "double"},
+	"short": {"int", "long", "float", "double"},
+	"char":  {"int", "long", "float", "double"},
+	"int":   {"long", "float", "double"},
+	"long":  {"float", "double"},
+	"float": {"double"},
+}
+
+// assignable reports whether a value of type from can be passed where to is expected.
+// Class types are accepted since the class hierarchy isn't resolved, except String for a primitive
+func assignable(from, to *typeRef) bool {
+	if from.String() == to.String() {
+		return true
+	}
+	fromPrimitive := from.kind != IDENTIFIER && !from.isArray()
+	toPrimitive := to.kind != IDENTIFIER && !to.isArray()
+	switch {
+	case fromPrimitive && toPrimitive:
+		return slices.Contains(widening[from.name], to.name)
+	case toPrimitive:
+		return !from.isString() && !from.isArray()
+	case fromPrimitive:
+		return !to.isString() && !to.isArray()
+	}
+	return from.isArray() == to.isArray() || to.name == "Object"
+}
+
+// checkRecursiveConstructor reports a constructor that ends up calling itself through this(...)
+func (p *Parser) checkRecursiveConstructor(m *method) {
+	seen := map[*method]bool{}
+	for next := m; next != nil; next = next.delegate() {
+		if seen[next] {
+			if next == m {
+				p.errorAt(m.pos, "recursive constructor invocation")
+			}
+			return
+		}
+		seen[next] = true
+	}
+}
+
+// delegate returns the constructor m calls with this(...), or nil
+func (m *method) delegate() *method {
+	if call := m.constructorCall(); call != nil {
+		return call.constructor
+	}
+	return nil
+}
+
+// constructorCall returns the explicit constructor call starting the body of m, or nil
+func (m *method) constructorCall() *constructorCall {
+	if len(m.statements) == 0 {
+		return nil
+	}
+	if stmt, ok := m.statements[0].(*expressionStmt); ok {
+		call, _ := stmt.expr.(*constructorCall)
+		return call
+	}
+	return nil
+}
+
+// classOf returns the class of f declaring m, or nil
+func (f *file) classOf(m *method) *class {
+	for _, c := range f.classes {
+		if slices.Contains(c.methods, m) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package parser
+
+import "strings"
+
+// skipTrivia skips whitespace and comments up to the next significant rune
+func (l *lexer) skipTrivia() {
+	for {
+		b, _ := l.reader.Peek(2)
+		switch {
+		case len(b) > 0 && isWhitespace(rune(b[0])):
+			l.next()
+		case len(b) == 2 && b[0] == '/' && (b[1] == '/' || b[1] == '*'):
+			l.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// skipComment skips a line or block comment, the reader must be positioned at its opening '/'.
+// Javadoc comments are kept and attached to the next emitted token
+func (l *lexer) skipComment() {
+	line, column := l.line, l.column
+	l.next()
+	if l.next() == '/' {
+		for r := l.next(); r != eof && r != '\n'; r = l.next() {
+		}
+		return
+	}
+	// "/**/" is an empty block comment, not Javadoc
+	b, _ := l.reader.Peek(2)
+	isDoc := len(b) == 2 && b[0] == '*' && b[1] != '/'
+	var text []rune
+	for {
+		r := l.next()
+		if r == eof {
+			l.errorAt(&pos{line: line, start: column, end: column + 1}, "/*", "unclosed comment")
+			return
+		}
+		if b, _ := l.reader.Peek(1); r == '*' && len(b) == 1 && b[0] == '/' {
+			l.next()
+			break
+		}
+		text = append(text, r)
+	}
+	if isDoc {
+		l.doc = javadoc(string(text[1:]))
+	}
+}
+
+// javadoc strips the leading asterisks and indentation from the lines of a Javadoc comment
+func javadoc(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "*")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// errorAt emits an error at p without touching the runes buffer,
+// for errors that are detected away from where they occurred
+func (l *lexer) errorAt(p *pos, value string, msgs ...string) {
+	l.tokens = append(l.tokens, &token{pos: p, value: value + ": " + strings.Join(msgs, "\n\t- "), kind: ERROR})
+}
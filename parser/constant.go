@@ -0,0 +1,476 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Constant values use the same Go types as token.literal, with int8 and int16 for byte and short.
+// Arithmetic follows Java, integers wrap around and floats keep their precision
+
+// constantTypes maps the primitive types and String to the Go type of their constant values
+var constantTypes = map[string]func(any) any{
+	"byte":   func(v any) any { return int8(toInt32(v)) },
+	"short":  func(v any) any { return int16(toInt32(v)) },
+	"char":   func(v any) any { return uint16(toInt32(v)) },
+	"int":    func(v any) any { return toInt32(v) },
+	"long":   func(v any) any { return toInt64(v) },
+	"float":  func(v any) any { return float32(toFloat64(v)) },
+	"double": func(v any) any { return toFloat64(v) },
+}
+
+// constant returns the value of e if it is a constant expression, JLS 15.29.
+// Simple names are looked up among the final locals and the fields of c
+func (c *class) constant(e Expression) (any, bool) {
+	return c.evaluate(e, map[*field]bool{})
+}
+
+// evaluate is constant, visiting holds the fields being evaluated to stop on cyclic initializers
+func (c *class) evaluate(e Expression, visiting map[*field]bool) (any, bool) {
+	switch e := e.(type) {
+	case *literal:
+		return e.value, e.kind != NULL_LITERAL
+	case *identifier:
+		if e.local != nil {
+			if !e.local.isFinal || e.local.value == nil {
+				return nil, false
+			}
+			return c.convert(e.local.value, e.local.kind, visiting)
+		}
+		return c.fieldConstant(c.field(e.name), visiting)
+	case *fieldAccess:
+		// Only constants of this class are resolved, qualified with its name
+		if target, ok := e.target.(*identifier); ok && target.local == nil && c != nil && target.name == c.name {
+			return c.fieldConstant(c.field(e.name), visiting)
+		}
+	case *cast:
+		value, ok := c.evaluate(e.operand, visiting)
+		if !ok {
+			return nil, false
+		}
+		if e.kind.isString() {
+			s, ok := value.(string)
+			return s, ok
+		}
+		if to, ok := constantTypes[e.kind.String()]; ok && isNumericConstant(value) {
+			return to(value), true
+		}
+		if b, ok := value.(bool); ok && e.kind.String() == "boolean" {
+			return b, true
+		}
+	case *unary:
+		if value, ok := c.evaluate(e.operand, visiting); ok {
+			return unaryConstant(e.op, value)
+		}
+	case *binary:
+		left, ok := c.evaluate(e.left, visiting)
+		if !ok {
+			return nil, false
+		}
+		// && and || are constant even though the right operand is evaluated lazily at runtime
+		right, ok := c.evaluate(e.right, visiting)
+		if !ok {
+			return nil, false
+		}
+		return binaryConstant(e.op, left, right)
+	case *conditional:
+		cond, ok := c.evaluate(e.cond, visiting)
+		b, isBool := cond.(bool)
+		if !ok || !isBool {
+			return nil, false
+		}
+		then, ok := c.evaluate(e.then, visiting)
+		if !ok {
+			return nil, false
+		}
+		otherwise, ok := c.evaluate(e.otherwise, visiting)
+		if !ok {
+			return nil, false
+		}
+		if isNumericConstant(then) && isNumericConstant(otherwise) {
+			then, otherwise = promoteConstants(then, otherwise)
+		}
+		if b {
+			return then, true
+		}
+		return otherwise, true
+	}
+	return nil, false
+}
+
+// field returns the field of c named name, or nil
+func (c *class) field(name string) *field {
+	if c == nil {
+		return nil
+	}
+	for _, f := range c.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// fieldConstant returns the value of a constant variable, a final primitive or String field with a constant initializer
+func (c *class) fieldConstant(f *field, visiting map[*field]bool) (any, bool) {
+	if f == nil || !f.isFinal || f.value == nil || visiting[f] {
+		return nil, false
+	}
+	visiting[f] = true
+	defer delete(visiting, f)
+	return c.convert(f.value, f.kind, visiting)
+}
+
+// convert evaluates e and converts it to the declared type of the variable it initializes, JLS 5.2
+func (c *class) convert(e Expression, kind *typeRef, visiting map[*field]bool) (any, bool) {
+	value, ok := c.evaluate(e, visiting)
+	if !ok {
+		return nil, false
+	}
+	switch {
+	case kind.isString():
+		s, ok := value.(string)
+		return s, ok
+	case kind.String() == "boolean":
+		b, ok := value.(bool)
+		return b, ok
+	}
+	to, ok := constantTypes[kind.String()]
+	if !ok || !isNumericConstant(value) {
+		return nil, false
+	}
+	return to(value), true
+}
+
+// lossyConversion reports whether assigning the constant value to kind loses information.
+// Constants of type byte, short, char and int may be narrowed to byte, short and char when the value fits, JLS 5.2
+func lossyConversion(value any, kind *typeRef) bool {
+	to, ok := constantTypes[kind.String()]
+	if !ok || !isNumericConstant(value) {
+		return false
+	}
+	converted := to(value)
+	from, target := constantRank(value), constantRank(converted)
+	char := constantRank(uint16(0))
+	if from <= target && !(target == char && from < char) {
+		return false
+	}
+	if from > constantRank(int32(0)) || target > char {
+		return true
+	}
+	return toInt64(converted) != toInt64(value)
+}
+
+func isNumericConstant(v any) bool {
+	return constantRank(v) >= 0
+}
+
+// constantRank orders the numeric constant types by width, it is -1 for other values
+func constantRank(v any) int {
+	switch v.(type) {
+	case int8:
+		return 0
+	case int16:
+		return 1
+	case uint16:
+		return 2
+	case int32:
+		return 3
+	case int64:
+		return 4
+	case float32:
+		return 5
+	case float64:
+		return 6
+	}
+	return -1
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float32:
+		return floatToInt64(float64(v))
+	case float64:
+		return floatToInt64(v)
+	}
+	return 0
+}
+
+// toInt32 converts v to int, JLS 5.1.3. Floats saturate to the int range rather than the long range,
+// and are narrowed further to byte, short and char from there
+func toInt32(v any) int32 {
+	switch v := v.(type) {
+	case float32:
+		return int32(max(min(floatToInt64(float64(v)), math.MaxInt32), math.MinInt32))
+	case float64:
+		return int32(max(min(floatToInt64(v), math.MaxInt32), math.MinInt32))
+	}
+	return int32(toInt64(v))
+}
+
+// floatToInt64 rounds toward zero and saturates like Java, NaN becomes 0
+func floatToInt64(f float64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int64(f)
+}
+
+func toFloat64(v any) float64 {
+	switch v := v.(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return float64(toInt64(v))
+}
+
+// promoteConstants applies binary numeric promotion to two numeric constants, JLS 5.6
+func promoteConstants(a, b any) (any, any) {
+	rank := max(constantRank(a), constantRank(b), constantRank(int32(0)))
+	to := map[int]string{3: "int", 4: "long", 5: "float", 6: "double"}[rank]
+	return constantTypes[to](a), constantTypes[to](b)
+}
+
+func unaryConstant(op tokenKind, v any) (any, bool) {
+	if b, ok := v.(bool); ok {
+		return !b, op == NOT
+	}
+	if !isNumericConstant(v) {
+		return nil, false
+	}
+	v, _ = promoteConstants(v, v)
+	switch op {
+	case PLUS:
+		return v, true
+	case MINUS:
+		switch v := v.(type) {
+		case int32:
+			return -v, true
+		case int64:
+			return -v, true
+		case float32:
+			return -v, true
+		case float64:
+			return -v, true
+		}
+	case BIT_NOT:
+		switch v := v.(type) {
+		case int32:
+			return ^v, true
+		case int64:
+			return ^v, true
+		}
+	}
+	return nil, false
+}
+
+func binaryConstant(op tokenKind, left, right any) (any, bool) {
+	if op == PLUS {
+		if l, ok := left.(string); ok {
+			return l + constantString(right), true
+		}
+		if r, ok := right.(string); ok {
+			return constantString(left) + r, true
+		}
+	}
+	if l, ok := left.(bool); ok {
+		r, ok := right.(bool)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case AND, BIT_AND:
+			return l && r, true
+		case OR, BIT_OR:
+			return l || r, true
+		case BIT_XOR, NOT_EQUALS:
+			return l != r, true
+		case EQUALS:
+			return l == r, true
+		}
+		return nil, false
+	}
+	if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		// Constant strings are interned, so == compares their contents
+		switch {
+		case ok && op == EQUALS:
+			return l == r, true
+		case ok && op == NOT_EQUALS:
+			return l != r, true
+		}
+		return nil, false
+	}
+	if !isNumericConstant(left) || !isNumericConstant(right) {
+		return nil, false
+	}
+	if precedences[op] == shift {
+		// The shift distance is masked to the width of the promoted left operand
+		left, _ = promoteConstants(left, left)
+		switch l := left.(type) {
+		case int32:
+			return shiftConstant(op, l, uint(toInt64(right)&31))
+		case int64:
+			return shiftConstant(op, l, uint(toInt64(right)&63))
+		}
+		return nil, false
+	}
+	left, right = promoteConstants(left, right)
+	switch l := left.(type) {
+	case int32:
+		return integerConstant(op, l, right.(int32))
+	case int64:
+		return integerConstant(op, l, right.(int64))
+	case float32:
+		return floatConstant(op, l, right.(float32))
+	case float64:
+		return floatConstant(op, l, right.(float64))
+	}
+	return nil, false
+}
+
+func shiftConstant[T int32 | int64](op tokenKind, v T, n uint) (any, bool) {
+	switch op {
+	case SHIFT_LEFT:
+		return v << n, true
+	case SHIFT_RIGHT:
+		return v >> n, true
+	default:
+		// >>> shifts in zeros, so the value is shifted as unsigned
+		if v, ok := any(v).(int32); ok {
+			return int32(uint32(v) >> n), true
+		}
+		return T(uint64(v) >> n), true
+	}
+}
+
+// integerConstant evaluates an int or long operation, division by zero isn't a constant
+func integerConstant[T int32 | int64](op tokenKind, l, r T) (any, bool) {
+	switch op {
+	case PLUS:
+		return l + r, true
+	case MINUS:
+		return l - r, true
+	case MULTIPLY:
+		return l * r, true
+	case DIVIDE, PERCENT:
+		if r == 0 {
+			return nil, false
+		}
+		// Go panics on MinInt / -1 while Java wraps around
+		if r == -1 {
+			if op == DIVIDE {
+				return -l, true
+			}
+			return T(0), true
+		}
+		if op == DIVIDE {
+			return l / r, true
+		}
+		return l % r, true
+	case BIT_AND:
+		return l & r, true
+	case BIT_OR:
+		return l | r, true
+	case BIT_XOR:
+		return l ^ r, true
+	}
+	return compareConstants(op, l, r)
+}
+
+func floatConstant[T float32 | float64](op tokenKind, l, r T) (any, bool) {
+	switch op {
+	case PLUS:
+		return l + r, true
+	case MINUS:
+		return l - r, true
+	case MULTIPLY:
+		return l * r, true
+	case DIVIDE:
+		return l / r, true
+	case PERCENT:
+		return T(math.Mod(float64(l), float64(r))), true
+	}
+	return compareConstants(op, l, r)
+}
+
+// compareConstants evaluates the relational and equality operators, comparisons with NaN are false except !=
+func compareConstants[T int32 | int64 | float32 | float64](op tokenKind, l, r T) (any, bool) {
+	switch op {
+	case EQUALS:
+		return l == r, true
+	case NOT_EQUALS:
+		return l != r, true
+	case LT:
+		return l < r, true
+	case GT:
+		return l > r, true
+	case LT_EQUALS:
+		return l <= r, true
+	case GT_EQUALS:
+		return l >= r, true
+	}
+	return nil, false
+}
+
+// constantString converts a constant to a string the way string concatenation does, JLS 5.1.11
+func constantString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case uint16:
+		return string(rune(v))
+	case float32:
+		return javaFloatString(float64(v), 32)
+	case float64:
+		return javaFloatString(v, 64)
+	}
+	return strconv.FormatInt(toInt64(v), 10)
+}
+
+// javaFloatString formats f like Double.toString and Float.toString.
+// Values from 10^-3 up to 10^7 are written in plain notation, others in computerized scientific notation
+func javaFloatString(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	if abs := math.Abs(f); abs == 0 || abs >= 1e-3 && abs < 1e7 {
+		s := strconv.FormatFloat(f, 'f', -1, bits)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(f, 'E', -1, bits)
+	mantissa, exponent, _ := strings.Cut(s, "E")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(n)
+}
@@ -0,0 +1,151 @@
+package parser
+
+import "fmt"
+
+// parseEnumConstants parses the constants at the start of an enum body, up to the semicolon ending them.
+// The semicolon may be left out when the enum declares nothing else, JLS 8.9.1
+func parseEnumConstants(p *Parser) parseStateFn {
+	enum := p.class
+	for p.peekToken.kind == IDENTIFIER {
+		doc := p.peekToken.doc
+		p.nextToken()
+		c := &enumConstant{
+			field: &field{decl: &decl{
+				node:      p.token.node(),
+				kind:      classType(enum.binaryName(), p.token.pos),
+				isFinal:   true,
+				modifiers: modifiers{visibility: PUBLIC, isStatic: true},
+				doc:       doc,
+			}},
+			ordinal: len(enum.constants),
+		}
+		if p.peekToken.kind == OPAREN {
+			p.nextToken()
+			c.args = p.parseArguments()
+		}
+		if p.peekToken.kind == OBRACE {
+			p.nextToken()
+			// javac names the class of the nth constant body Enum$n
+			c.body = &class{
+				node:        node{fmt.Sprintf("%s$%d", enum.binaryName(), countBodies(enum.constants)+1), p.token.pos},
+				isAnonymous: true,
+				superclass:  enum,
+				outer:       enum,
+			}
+			if !p.parseClassBody(c.body) {
+				return nil
+			}
+		}
+		enum.constants = append(enum.constants, c)
+		p.addField(c.field)
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	// An enum is implicitly final, unless a constant has a class body extending it, JLS 8.9
+	enum.isFinal = countBodies(enum.constants) == 0
+	switch p.peekToken.kind {
+	case SEMICOLON:
+		p.nextToken()
+	case CBRACE:
+	default:
+		p.errorAt(p.peekToken.pos, "',', '}', or ';' expected")
+		return nil
+	}
+	return parseDeclaration
+}
+
+func countBodies(constants []*enumConstant) int {
+	n := 0
+	for _, c := range constants {
+		if c.body != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// enumConstructor applies the implicit modifiers of enum constructors, which are private, JLS 8.9.2
+func (p *Parser) enumConstructor(mods *modifiers) {
+	switch mods.visibility {
+	case PUBLIC, PROTECTED:
+		p.errorf("modifier %s not allowed here", mods.visibility)
+	default:
+		mods.visibility = PRIVATE
+	}
+}
+
+// enumMethods returns the methods synthesised for enum c, JLS 8.9.3.
+// ordinal and name are inherited from java.lang.Enum, but are declared here as the superclass isn't parsed
+func enumMethods(c *class) []*method {
+	synthesise := func(name string, kind *typeRef, isStatic bool, params ...*parameter) *method {
+		return &method{
+			decl: &decl{
+				node:      node{name, c.pos},
+				kind:      kind,
+				isFinal:   !isStatic,
+				modifiers: modifiers{visibility: PUBLIC, isStatic: isStatic},
+			},
+			parameters: params,
+			implicit:   true,
+		}
+	}
+	return []*method{
+		synthesise("values", arrayType(classType(c.binaryName(), c.pos), 1), true),
+		synthesise("valueOf", classType(c.binaryName(), c.pos), true, &parameter{name: node{"name", c.pos}, kind: classType("String", c.pos)}),
+		synthesise("ordinal", primitiveType(INT, c.pos), false),
+		synthesise("name", classType("String", c.pos), false),
+	}
+}
+
+// checkEnumSwitch links a switch on an enum of f to the enum, once its type is known.
+// Its labels must name constants of the enum, and a switch expression without default must cover all of them
+func (p *Parser) checkEnumSwitch(f *file, n Node) {
+	var sw *switchBlock
+	var isExpr bool
+	switch n := n.(type) {
+	case *switchStmt:
+		sw = &n.switchBlock
+	case *switchExpr:
+		sw, isExpr = &n.switchBlock, true
+	default:
+		return
+	}
+	kind := p.typeOf(sw.selector)
+	if kind == nil || kind.kind != IDENTIFIER || kind.isArray() {
+		return
+	}
+	enum := f.class(kind.name)
+	if enum == nil || !enum.isEnum {
+		return
+	}
+	sw.enum = enum
+	covered, hasDefault := map[*enumConstant]bool{}, false
+	for _, c := range sw.cases {
+		hasDefault = hasDefault || c.isDefault
+		for _, label := range c.labels {
+			e := enum.enumConstant(label)
+			if e == nil {
+				p.errorAt(label.Position(), "an enum switch case label must be the unqualified name of an enumeration constant")
+				continue
+			}
+			covered[e] = true
+		}
+	}
+	if isExpr && !hasDefault && len(covered) < len(enum.constants) {
+		p.errorAt(sw.selector.Position(), "the switch expression does not cover all possible input values")
+	}
+}
+
+// enumConstant returns the constant of c named by the case label e, or nil
+func (c *class) enumConstant(e Expression) *enumConstant {
+	if id, ok := e.(*identifier); ok && id.local == nil {
+		for _, constant := range c.constants {
+			if constant.name == id.name {
+				return constant
+			}
+		}
+	}
+	return nil
+}
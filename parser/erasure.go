@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+)
+
+// erasure returns the erasure of t, JLS 4.6. Type arguments are dropped,
+// and type variables are replaced by the erasure of their leftmost bound, or Object without bounds
+func (t *typeRef) erasure() *typeRef {
+	switch {
+	case t.kind == QUESTION:
+		return t.upperBound().erasure()
+	case t.param != nil:
+		bound := classType("Object", t.pos)
+		if len(t.param.bounds) > 0 {
+			bound = t.param.bounds[0].erasure()
+		}
+		e := arrayType(bound, t.dims)
+		e.pos = t.pos
+		return e
+	case t.args != nil:
+		e := *t
+		e.args = nil
+		return &e
+	}
+	return t
+}
+
+// eraseFile erases the types of f once it is checked, so the backends only see erased types.
+// Calls of methods returning a type variable got their cast in checkMembers,
+// and classes get the bridge methods that make their methods override the erased methods of generic supertypes
+func (p *Parser) eraseFile(f *file) {
+	for _, c := range f.classes {
+		c.methods = append(c.methods, c.bridges()...)
+	}
+	for _, c := range f.classes {
+		c.walkTypes(func(t *typeRef, _ bool) { *t = *t.erasure() })
+		for _, m := range c.methods {
+			if m.implicit {
+				m.kind = m.kind.erasure()
+			}
+		}
+	}
+}
+
+// castCall sets the cast of the call n to a method whose return type is a type variable, like T get(),
+// when the type arguments of the receiver or the arguments bind it to a type narrower than its erasure.
+// c is the class containing the call
+func (p *Parser) castCall(f *file, c *class, n Node) {
+	call, ok := n.(*call)
+	if !ok || call.method == nil {
+		return
+	}
+	kind := call.method.kind
+	if cast := kind.substitute(p.memberTypeArgs(f, c, call)).erasure(); cast.String() != kind.erasure().String() {
+		call.cast = cast
+	}
+}
+
+// memberTypeArgs returns the type arguments of the resolved call, bound by the type of its receiver
+// and inferred from its arguments. c is the class containing the call
+func (p *Parser) memberTypeArgs(f *file, c *class, call *call) map[*typeParam]*typeRef {
+	receiver, recvType := c, (*typeRef)(nil)
+	switch target := call.target.(type) {
+	case nil, *thisExpr:
+	case *superExpr:
+		receiver = target.owner(f)
+		recvType = p.typeOf(target)
+	default:
+		recvType = p.typeOf(target)
+		receiver = nil
+		if recvType != nil && !recvType.isArray() {
+			receiver = f.class(recvType.name)
+		}
+	}
+	args := map[*typeParam]*typeRef{}
+	if owner := f.root.classOf(call.method); receiver != nil && owner != nil {
+		args = receiver.supertypeArgs(owner, typeArgs(receiver, recvType))
+	}
+	maps.Copy(args, p.inferTypeArgs(call.method, call))
+	return args
+}
+
+// classOf returns the class of the source root declaring m, or nil
+func (a *AST) classOf(m *method) *class {
+	for _, f := range a.files {
+		if c := f.classOf(m); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// bridges returns the bridge methods of c, JLS 15.12.4.5. A method of c overriding a method of a generic supertype
+// whose parameter types erase differently, like compareTo(A) implementing compareTo(T) of Comparable<A>,
+// gets a bridge with the erased parameter types of the overridden method, which casts its arguments and calls it
+func (c *class) bridges() []*method {
+	if c.isInterface {
+		return nil
+	}
+	var bridges []*method
+	seen := map[*class]bool{}
+	var walk func(s *class)
+	walk = func(s *class) {
+		if seen[s] {
+			return
+		}
+		seen[s] = true
+		args := c.supertypeArgs(s, map[*typeParam]*typeRef{})
+		for _, o := range s.methods {
+			if s == c || o.isConstructor || o.isStatic || o.visibility == PRIVATE {
+				continue
+			}
+			sig := o.signature()
+			impl := c.overrider(o, args)
+			if impl == nil || impl.signature() == sig || slices.ContainsFunc(append(c.methods, bridges...), func(m *method) bool { return m.signature() == sig }) {
+				continue
+			}
+			bridges = append(bridges, c.bridge(o, impl))
+		}
+		for _, t := range s.supertypes() {
+			walk(t)
+		}
+	}
+	walk(c)
+	return bridges
+}
+
+// overrider returns the method declared by c whose parameter types are those of o
+// with the type variables mapped by args substituted, or nil
+func (c *class) overrider(o *method, args map[*typeParam]*typeRef) *method {
+	for _, m := range c.methods {
+		if m.isConstructor || m.isStatic || m.name != o.name || len(m.parameters) != len(o.parameters) {
+			continue
+		}
+		if slices.EqualFunc(m.parameters, o.parameters, func(a, b *parameter) bool {
+			return a.kind.erasure().String() == b.kind.substitute(args).erasure().String()
+		}) {
+			return m
+		}
+	}
+	return nil
+}
+
+// bridge returns the bridge method with the erased signature of o, calling impl.
+// Its statement is return this.m((A) a, ...), or an expression statement when impl returns void
+func (c *class) bridge(o, impl *method) *method {
+	pos := impl.pos
+	m := &method{
+		decl: &decl{
+			node:      node{impl.name, pos},
+			kind:      o.kind.erasure(),
+			modifiers: modifiers{visibility: impl.visibility},
+		},
+		implicit: true,
+	}
+	call := &call{expression: expression{node{impl.name, pos}}, target: &thisExpr{expression: expression{node{"this", pos}}, class: c}, method: impl}
+	for i, param := range o.parameters {
+		m.parameters = append(m.parameters, &parameter{name: param.name, kind: param.kind.erasure()})
+		arg := &identifier{expression: expression{param.name}, local: &localVar{node: param.name, kind: param.kind.erasure()}}
+		call.args = append(call.args, &cast{expression: expression{node{"(", pos}}, kind: impl.parameters[i].kind.erasure(), operand: arg})
+	}
+	if impl.kind.kind == VOID {
+		m.statements = []Statement{&expressionStmt{statement: statement{call.node}, expr: call}}
+	} else {
+		m.statements = []Statement{&returnStmt{statement: statement{node{"return", pos}}, value: call}}
+	}
+	return m
+}
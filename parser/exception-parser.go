@@ -0,0 +1,468 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// parseThrow parses a throw statement, the value must be an object
+func (p *Parser) parseThrow() Statement {
+	p.nextToken()
+	s := &throwStmt{statement: statement{p.token.node()}, value: p.parseExpression(lowest)}
+	if kind := p.typeOf(s.value); kind != nil && (kind.kind != IDENTIFIER || kind.isArray() || kind.isString()) {
+		p.errorAt(s.value.Position(), "incompatible types: %s cannot be converted to Throwable", kind)
+	}
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return s
+}
+
+// parseTry parses a try statement with its resources, catch clauses and finally block
+func (p *Parser) parseTry() Statement {
+	p.nextToken()
+	s := &tryStmt{statement: statement{p.token.node()}}
+	if !p.parseTryBlock(s) {
+		return nil
+	}
+	for p.peekToken.kind == CATCH {
+		c := p.parseCatch()
+		if c == nil {
+			return nil
+		}
+		s.catches = append(s.catches, c)
+	}
+	if p.peekToken.kind == FINALLY {
+		p.nextToken()
+		if !p.expectNext(OBRACE) {
+			return nil
+		}
+		if s.finally = p.parseBlock(); s.finally == nil {
+			return nil
+		}
+	}
+	if len(s.catches) == 0 && s.finally == nil && len(s.resources) == 0 {
+		p.errorAt(s.pos, "'try' without 'catch', 'finally' or resource declarations")
+	}
+	return s
+}
+
+// parseTryBlock parses the resources and the block of s, the resources are only in scope in the block
+func (p *Parser) parseTryBlock(s *tryStmt) bool {
+	p.openScope()
+	defer p.closeScope()
+	if p.peekToken.kind == OPAREN {
+		p.nextToken()
+		if !p.parseResources(s) {
+			return false
+		}
+	}
+	if !p.expectNext(OBRACE) {
+		return false
+	}
+	s.body = p.parseBlock()
+	return s.body != nil
+}
+
+// parseResources parses the resources of a try-with-resources statement up to the closing parenthesis.
+// A resource declares an implicitly final variable, or names an effectively final one, and a trailing semicolon is allowed
+func (p *Parser) parseResources(s *tryStmt) bool {
+	for {
+		if p.isLocalVarDecl() {
+			decl := p.parseLocalVarType()
+			if !p.expectNext(IDENTIFIER) {
+				return false
+			}
+			v := &localVar{node: p.token.node(), kind: decl.kind, isFinal: true, inferred: decl.inferred, resource: true}
+			if !p.expectNext(ASSIGN) {
+				return false
+			}
+			v.value = p.parseInitializer(v.kind)
+			if v.inferred {
+				p.inferLocalVar(v, false)
+			}
+			p.declare(v)
+			decl.vars = []*localVar{v}
+			s.resources = append(s.resources, decl)
+		} else {
+			stmt := p.parseExpressionStmt()
+			switch stmt.expr.(type) {
+			case *identifier, *fieldAccess:
+			default:
+				p.errorAt(stmt.pos, "the try-with-resources resource must either be a variable declaration or an expression denoting a reference to a final or effectively final variable")
+			}
+			s.resources = append(s.resources, stmt)
+		}
+		if !p.expectNext(SEMICOLON, CPAREN) {
+			return false
+		}
+		if p.token.kind == CPAREN {
+			return true
+		}
+		if p.peekToken.kind == CPAREN {
+			p.nextToken()
+			return true
+		}
+	}
+}
+
+// parseCatch parses a catch clause, whose parameter may have alternative types separated by |
+func (p *Parser) parseCatch() *catchClause {
+	p.nextToken()
+	c := &catchClause{node: p.token.node()}
+	p.expectNext(OPAREN)
+	isFinal := false
+	if p.peekToken.kind == FINAL {
+		p.nextToken()
+		isFinal = true
+	}
+	for {
+		if !p.expectNext(IDENTIFIER) {
+			return nil
+		}
+		c.kinds = append(c.kinds, p.parseTypeName())
+		if p.peekToken.kind != BIT_OR {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectNext(IDENTIFIER) {
+		return nil
+	}
+	// The type of a multi-catch parameter is narrowed to the closest common superclass of the alternatives by checkCatch
+	c.param = &localVar{node: p.token.node(), kind: c.kinds[0], isFinal: isFinal, multiCatch: len(c.kinds) > 1}
+	if !p.expectNext(CPAREN) || !p.expectNext(OBRACE) {
+		return nil
+	}
+	p.openScope()
+	defer p.closeScope()
+	p.declare(c.param)
+	if c.body = p.parseBlock(); c.body == nil {
+		return nil
+	}
+	return c
+}
+
+// parseThrows parses the exception types of the throws clause of a method or constructor, if it has one
+func (p *Parser) parseThrows() {
+	if p.peekToken.kind == THROWS {
+		p.nextToken()
+		p.decl.throws = p.parseTypeList()
+	}
+}
+
+// exceptionSupers maps the exceptions of the class library to their superclass, up to Throwable
+var exceptionSupers = map[string]string{
+	"Exception":                       "Throwable",
+	"Error":                           "Throwable",
+	"RuntimeException":                "Exception",
+	"IOException":                     "Exception",
+	"FileNotFoundException":           "IOException",
+	"InterruptedException":            "Exception",
+	"CloneNotSupportedException":      "Exception",
+	"ReflectiveOperationException":    "Exception",
+	"ClassNotFoundException":          "ReflectiveOperationException",
+	"IllegalArgumentException":        "RuntimeException",
+	"NumberFormatException":           "IllegalArgumentException",
+	"IllegalStateException":           "RuntimeException",
+	"NullPointerException":            "RuntimeException",
+	"ArithmeticException":             "RuntimeException",
+	"ClassCastException":              "RuntimeException",
+	"ArrayStoreException":             "RuntimeException",
+	"NegativeArraySizeException":      "RuntimeException",
+	"UnsupportedOperationException":   "RuntimeException",
+	"IndexOutOfBoundsException":       "RuntimeException",
+	"ArrayIndexOutOfBoundsException":  "IndexOutOfBoundsException",
+	"StringIndexOutOfBoundsException": "IndexOutOfBoundsException",
+	"AssertionError":                  "Error",
+	"OutOfMemoryError":                "Error",
+	"StackOverflowError":              "Error",
+}
+
+// exceptionChain returns the simple names of t and its superclasses up to Throwable,
+// or nil when t isn't known to be a Throwable
+func (f *file) exceptionChain(t *typeRef) []string {
+	if t == nil || t.kind != IDENTIFIER || t.isArray() {
+		return nil
+	}
+	var chain []string
+	for name := t.name; !slices.Contains(chain, name[strings.LastIndex(name, ".")+1:]); {
+		simple := name[strings.LastIndex(name, ".")+1:]
+		chain = append(chain, simple)
+		if simple == "Throwable" {
+			return chain
+		}
+		if c := f.class(name); c != nil && c.extends != nil {
+			name = c.extends.name
+		} else if super, ok := exceptionSupers[simple]; ok && c == nil {
+			name = super
+		} else {
+			return nil
+		}
+	}
+	return nil
+}
+
+// isChecked reports whether t is a checked exception, one that isn't a RuntimeException or an Error, JLS 11.1.1.
+// Exceptions the hierarchy of isn't known are taken to be unchecked
+func (f *file) isChecked(t *typeRef) bool {
+	chain := f.exceptionChain(t)
+	return chain != nil && !slices.Contains(chain, "RuntimeException") && !slices.Contains(chain, "Error")
+}
+
+// isException reports whether t is the exception class named name or one of its subclasses
+func (f *file) isException(t *typeRef, name string) bool {
+	return slices.Contains(f.exceptionChain(t), name[strings.LastIndex(name, ".")+1:])
+}
+
+// declares reports whether the throws clause of m allows it to throw the exceptions of type t, which are either unchecked
+// or subclasses of a declared exception, JLS 11.2.3
+func (f *file) declares(m *method, t *typeRef) bool {
+	return !f.isChecked(t) || slices.ContainsFunc(m.throws, func(d *typeRef) bool { return f.isException(t, d.name) })
+}
+
+// thrown is an exception a statement may throw, pos is the throw statement or call that throws it
+type thrown struct {
+	kind *typeRef
+	pos  *pos
+}
+
+// exceptionFlow finds the exceptions that the statements of a method may throw, JLS 11.2
+type exceptionFlow struct {
+	*Parser
+	f *file
+	// rethrown maps catch parameters to the exceptions of the try block they catch,
+	// which throwing the parameter rethrows, JLS 11.2.2
+	rethrown map[*localVar][]thrown
+}
+
+// checkExceptions reports the checked exceptions that m may throw but neither catches nor declares in its throws clause
+func (p *Parser) checkExceptions(f *file, m *method) {
+	p.checkEscaping(f, m, m.statements)
+	for _, t := range m.throws {
+		if f.class(t.name) != nil && f.exceptionChain(t) == nil {
+			p.errorAt(t.pos, "incompatible types: %s cannot be converted to Throwable", t)
+		}
+	}
+}
+
+// checkEscaping reports the checked exceptions that stmts may throw but neither catch nor m declares,
+// and those the lambda bodies in stmts may throw but the function they implement doesn't declare
+func (p *Parser) checkEscaping(f *file, m *method, stmts []Statement) {
+	flow := &exceptionFlow{Parser: p, f: f, rethrown: map[*localVar][]thrown{}}
+	for _, t := range flow.escaping(stmts...) {
+		if !f.declares(m, t.kind) {
+			p.errorAt(t.pos, "unreported exception %s; must be caught or declared to be thrown", t.kind)
+		}
+	}
+	// The body of a lambda may only throw what the function it implements declares
+	var lambdas []*lambda
+	inspectAll(stmts, func(n Node) bool {
+		if l, ok := n.(*lambda); ok && l.function != nil {
+			lambdas = append(lambdas, l)
+		}
+		return true
+	})
+	for _, l := range lambdas {
+		for _, t := range flow.escaping(l.statements()...) {
+			if !f.declares(l.function, t.kind) {
+				p.errorAt(t.pos, "unreported exception %s; must be caught or declared to be thrown", t.kind)
+			}
+		}
+	}
+}
+
+// checkFieldExceptions reports the checked exceptions that the field initializers of c may throw, JLS 11.2.3.
+// Instance field initializers may throw what the instance initializers may, see initializerThrows,
+// and static ones none
+func (p *Parser) checkFieldExceptions(f *file, c *class) {
+	for _, fld := range c.fields {
+		if fld.value == nil {
+			continue
+		}
+		init := &method{decl: &decl{node: fld.node, modifiers: fld.modifiers}}
+		if !fld.isStatic {
+			init.throws = c.initializerThrows()
+		}
+		p.checkEscaping(f, init, []Statement{&expressionStmt{expr: fld.value}})
+	}
+}
+
+// checkImplicitSuper reports the checked exceptions that the implicit super() call of the constructor m may throw
+// and m doesn't declare, JLS 8.8.7. super is the constructor of the superclass it calls
+func (p *Parser) checkImplicitSuper(f *file, m, super *method) {
+	if super == nil {
+		return
+	}
+	for _, t := range super.throws {
+		switch {
+		case f.declares(m, t):
+		case m.implicit:
+			p.errorAt(m.pos, "unreported exception %s in default constructor", t)
+		default:
+			p.errorAt(m.pos, "unreported exception %s; must be caught or declared to be thrown", t)
+		}
+	}
+}
+
+// escaping returns the exceptions that stmts may throw and don't catch
+func (e *exceptionFlow) escaping(stmts ...Statement) []thrown {
+	var out []thrown
+	throws := func(kinds []*typeRef, pos *pos) {
+		for _, kind := range kinds {
+			out = append(out, thrown{kind, pos})
+		}
+	}
+	inspectAll(stmts, func(n Node) bool {
+		switch n := n.(type) {
+		case *tryStmt:
+			out = append(out, e.escapingTry(n)...)
+			return false
+		case *lambda:
+			// The body runs when the function is called, see checkExceptions
+			return false
+		case *throwStmt:
+			var local *localVar
+			if id, ok := n.value.(*identifier); ok {
+				local = id.local
+			}
+			if rethrown, ok := e.rethrown[local]; ok && local != nil {
+				for _, t := range rethrown {
+					throws([]*typeRef{t.kind}, n.pos)
+				}
+			} else if kind := e.typeOf(n.value); kind != nil {
+				throws([]*typeRef{kind}, n.pos)
+			}
+		case *call:
+			if n.method != nil {
+				throws(n.method.throws, n.pos)
+			}
+		case *newObject:
+			if n.constructor != nil {
+				throws(n.constructor.throws, n.pos)
+			}
+		case *constructorCall:
+			if n.constructor != nil {
+				throws(n.constructor.throws, n.pos)
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// escapingTry returns the exceptions that s may throw and doesn't catch, and reports its misplaced catch clauses
+func (e *exceptionFlow) escapingTry(s *tryStmt) []thrown {
+	body := e.escaping(s.resources...)
+	for _, r := range slices.Backward(s.resources) {
+		body = append(body, e.closeExceptions(r)...)
+	}
+	body = append(body, e.escaping(s.body.statements...)...)
+	var out []thrown
+	for _, t := range body {
+		if !slices.ContainsFunc(s.catches, func(c *catchClause) bool { return e.catches(c, t.kind) }) {
+			out = append(out, t)
+		}
+	}
+	for i, c := range s.catches {
+		e.checkCatch(c, s.catches[:i], body)
+		out = append(out, e.escaping(c.body.statements...)...)
+	}
+	if s.finally != nil {
+		out = append(out, e.escaping(s.finally.statements...)...)
+	}
+	return out
+}
+
+// catches reports whether c catches the exceptions of type kind
+func (e *exceptionFlow) catches(c *catchClause, kind *typeRef) bool {
+	return slices.ContainsFunc(c.kinds, func(alt *typeRef) bool { return e.f.isException(kind, alt.name) })
+}
+
+// checkCatch reports the alternatives of c that are already caught by the clauses before it,
+// or checked exceptions that the try block doesn't throw, and records what throwing its parameter rethrows
+func (e *exceptionFlow) checkCatch(c *catchClause, before []*catchClause, body []thrown) {
+	f := e.f
+	var caught []thrown
+	for i, alt := range c.kinds {
+		chain := f.exceptionChain(alt)
+		switch {
+		case chain == nil && f.class(alt.name) != nil || alt.isString():
+			e.errorAt(alt.pos, "incompatible types: %s cannot be converted to Throwable", alt)
+			continue
+		case slices.ContainsFunc(before, func(b *catchClause) bool { return e.catches(b, alt) }):
+			e.errorAt(alt.pos, "exception %s has already been caught", alt)
+			continue
+		}
+		for _, other := range c.kinds[:i] {
+			if f.isException(alt, other.name) || f.isException(other, alt.name) {
+				e.errorAt(alt.pos, "Alternatives in a multi-catch statement cannot be related by subclassing")
+			}
+		}
+		thrownHere := false
+		for _, t := range body {
+			switch {
+			case f.isException(t.kind, alt.name):
+				caught = append(caught, t)
+				thrownHere = true
+			case f.isException(alt, t.kind.name):
+				caught = append(caught, thrown{alt, t.pos})
+				thrownHere = true
+			}
+		}
+		if !thrownHere && f.isChecked(alt) && alt.name != "Exception" && alt.name != "Throwable" {
+			e.errorAt(alt.pos, "exception %s is never thrown in body of corresponding try statement", alt)
+		}
+		// Unchecked exceptions may be thrown anywhere, so rethrowing the parameter may throw them too
+		if !f.isChecked(alt) {
+			caught = append(caught, thrown{alt, c.pos})
+		}
+	}
+	e.rethrown[c.param] = caught
+	if len(c.kinds) > 1 {
+		c.param.kind = f.commonSuperclass(c.kinds)
+	}
+}
+
+// closeExceptions returns the exceptions thrown by the implicit call to close() on the resource r
+func (e *exceptionFlow) closeExceptions(r Statement) []thrown {
+	var kind *typeRef
+	var pos *pos
+	switch r := r.(type) {
+	case *localVars:
+		kind, pos = r.vars[0].kind, r.vars[0].pos
+	case *expressionStmt:
+		kind, pos = e.typeOf(r.expr), r.pos
+	}
+	if kind == nil || kind.name == "var" {
+		return nil
+	}
+	if !e.f.isSubtype(kind, classType("AutoCloseable", pos)) {
+		e.errorAt(pos, "incompatible types: try-with-resources not applicable to variable type")
+		return nil
+	}
+	switch kind.name {
+	case "AutoCloseable":
+		return []thrown{{classType("Exception", pos), pos}}
+	case "Closeable":
+		return []thrown{{classType("IOException", pos), pos}}
+	}
+	if close, _ := e.f.class(kind.name).lookupMethod("close()"); close != nil {
+		out := make([]thrown, len(close.throws))
+		for i, t := range close.throws {
+			out[i] = thrown{t, pos}
+		}
+		return out
+	}
+	return nil
+}
+
+// commonSuperclass returns the closest superclass shared by the exception types kinds, or Throwable
+func (f *file) commonSuperclass(kinds []*typeRef) *typeRef {
+	for _, name := range f.exceptionChain(kinds[0]) {
+		if !slices.ContainsFunc(kinds, func(k *typeRef) bool { return !f.isException(k, name) }) {
+			return classType(name, kinds[0].pos)
+		}
+	}
+	return classType("Throwable", kinds[0].pos)
+}
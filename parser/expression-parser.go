@@ -0,0 +1,321 @@
+package parser
+
+import "slices"
+
+// precedence is the binding power of an operator, from loosest to tightest.
+// See https://docs.oracle.com/javase/specs/jls/se17/html/jls-15.html
+type precedence int
+
+const (
+	lowest         precedence = iota
+	assign                    // = and compound assignments, right associative
+	ternary                   // ?:, right associative
+	logicalOr                 // ||
+	logicalAnd                // &&
+	bitOr                     // |
+	bitXor                    // ^
+	bitAnd                    // &
+	equality                  // == !=
+	relational                // < > <= >= instanceof
+	shift                     // << >> >>>
+	additive                  // + -
+	multiplicative            // * / %
+	prefix                    // + - ! ~ ++ -- and casts
+	postfix                   // ++ -- . [] and method calls
+)
+
+// precedences holds the binding power of every infix and postfix operator
+var precedences = map[tokenKind]precedence{
+	ASSIGN:                      assign,
+	PLUS_ASSIGN:                 assign,
+	MINUS_ASSIGN:                assign,
+	MULTIPLY_ASSIGN:             assign,
+	DIVIDE_ASSIGN:               assign,
+	PERCENT_ASSIGN:              assign,
+	BIT_AND_ASSIGN:              assign,
+	BIT_OR_ASSIGN:               assign,
+	BIT_XOR_ASSIGN:              assign,
+	SHIFT_LEFT_ASSIGN:           assign,
+	SHIFT_RIGHT_ASSIGN:          assign,
+	UNSIGNED_SHIFT_RIGHT_ASSIGN: assign,
+	QUESTION:                    ternary,
+	OR:                          logicalOr,
+	AND:                         logicalAnd,
+	BIT_OR:                      bitOr,
+	BIT_XOR:                     bitXor,
+	BIT_AND:                     bitAnd,
+	EQUALS:                      equality,
+	NOT_EQUALS:                  equality,
+	LT:                          relational,
+	GT:                          relational,
+	LT_EQUALS:                   relational,
+	GT_EQUALS:                   relational,
+	INSTANCEOF:                  relational,
+	SHIFT_LEFT:                  shift,
+	SHIFT_RIGHT:                 shift,
+	UNSIGNED_SHIFT_RIGHT:        shift,
+	PLUS:                        additive,
+	MINUS:                       additive,
+	MULTIPLY:                    multiplicative,
+	DIVIDE:                      multiplicative,
+	PERCENT:                     multiplicative,
+	INCREMENT:                   postfix,
+	DECREMENT:                   postfix,
+	DOT:                         postfix,
+	OBRACKET:                    postfix,
+	DOUBLE_COLON:                postfix,
+}
+
+// parseExpression parses the expression following the current token, and stops at its last token.
+// Only operators binding tighter than prec are consumed, which gives left associativity
+func (p *Parser) parseExpression(prec precedence) Expression {
+	// A lambda body extends as far as possible, so it can't be an operand. Case labels are parsed at ternary
+	// precedence, which keeps case x -> from being taken for a lambda
+	if prec < ternary && p.isLambda() {
+		return p.parseLambda()
+	}
+	p.nextToken()
+	left := p.parsePrefix()
+	for prec < precedences[p.peekToken.kind] {
+		p.nextToken()
+		left = p.parseInfix(left)
+	}
+	return left
+}
+
+func (t *token) expression() expression {
+	return expression{t.node()}
+}
+
+// parsePrefix parses the expression starting at the current token, up to the first infix operator
+func (p *Parser) parsePrefix() Expression {
+	t := p.token
+	if t.kind.isLiteral() {
+		switch {
+		case t.isMinimum() && t.kind == LONG_LITERAL:
+			p.errorf("long number too large: %s", t.value)
+		case t.isMinimum():
+			p.errorf("integer number too large: %s", t.value)
+		}
+		return &literal{expression: t.expression(), kind: t.kind, value: t.literal}
+	}
+	switch t.kind {
+	case IDENTIFIER:
+		if p.peekToken.kind == OPAREN {
+			return p.parseCall(nil)
+		}
+		return &identifier{expression: t.expression(), local: p.scope.lookup(t.value)}
+	case OPAREN:
+		if p.isCast() {
+			kind := p.parseType()
+			p.expectNext(CPAREN)
+			c := &cast{expression: expression{node{kind.String(), t.pos}}, kind: kind}
+			if p.isLambda() {
+				c.operand = p.parseLambda()
+			} else {
+				c.operand = p.parseExpression(prefix)
+			}
+			targetFunction(kind, c.operand)
+			return c
+		}
+		expr := p.parseExpression(lowest)
+		p.expectNext(CPAREN)
+		return expr
+	case THIS, SUPER:
+		if p.decl != nil && p.decl.isStatic {
+			p.errorf("non-static variable %s cannot be referenced from a static context", t.value)
+		}
+		if p.peekToken.kind == OPAREN {
+			p.nextToken()
+			return &constructorCall{expression: t.expression(), args: p.parseArguments()}
+		}
+		if t.kind == SUPER {
+			if p.peekToken.kind != DOT && p.peekToken.kind != DOUBLE_COLON {
+				p.errorAt(p.peekToken.pos, "expected %s, got %s", DOT, p.peekToken.kind)
+			}
+			return &superExpr{expression: t.expression(), class: p.class}
+		}
+		return &thisExpr{expression: t.expression(), class: p.class}
+	case NEW:
+		return p.parseNew()
+	case SWITCH:
+		s := &switchExpr{expression: t.expression()}
+		if !p.parseSwitch(&s.switchBlock, s) {
+			return nil
+		}
+		return s
+	case PLUS, MINUS, NOT, BIT_NOT:
+		if t.kind == MINUS && p.peekToken.isMinimum() {
+			p.nextToken()
+			operand := &literal{expression: p.token.expression(), kind: p.token.kind, value: p.token.literal}
+			return &unary{expression: t.expression(), op: t.kind, operand: operand}
+		}
+		return &unary{expression: t.expression(), op: t.kind, operand: p.parseExpression(prefix)}
+	case INCREMENT, DECREMENT:
+		operand := p.parseExpression(prefix)
+		p.checkAssignable(operand)
+		return &unary{expression: t.expression(), op: t.kind, operand: operand}
+	}
+	p.errorf("unexpected token (expression): %s", t.kind)
+	return nil
+}
+
+// parseInfix parses the operator at the current token, with left as its left-hand side
+func (p *Parser) parseInfix(left Expression) Expression {
+	t := p.token
+	switch t.kind {
+	case QUESTION:
+		then := p.parseExpression(lowest)
+		p.expectNext(COLON)
+		return &conditional{expression: t.expression(), cond: left, then: then, otherwise: p.parseExpression(assign)}
+	case INSTANCEOF:
+		return &instanceOf{expression: t.expression(), operand: left, kind: p.parseType()}
+	case INCREMENT, DECREMENT:
+		p.checkAssignable(left)
+		return &unary{expression: t.expression(), op: t.kind, operand: left, postfix: true}
+	case DOT:
+		switch p.peekToken.kind {
+		case THIS:
+			p.nextToken()
+			return p.parseQualifiedThis(left)
+		case SUPER:
+			p.nextToken()
+			return p.parseQualifiedSuper(left)
+		case NEW:
+			// An inner class may be created with an explicit enclosing instance, like outer.new Inner()
+			p.nextToken()
+			e := p.parseNew()
+			if n, ok := e.(*newObject); ok {
+				n.outer = left
+			}
+			return e
+		}
+		// A generic method may be called with explicit type arguments, like List.<String>of()
+		var typeArgs []*typeRef
+		if p.peekToken.kind == LT {
+			typeArgs = p.parseTypeArgs()
+		}
+		p.expectNext(IDENTIFIER)
+		if p.peekToken.kind == OPAREN || typeArgs != nil {
+			c := p.parseCall(left).(*call)
+			c.typeArgs = typeArgs
+			return c
+		}
+		return &fieldAccess{expression: p.token.expression(), target: left}
+	case DOUBLE_COLON:
+		return p.parseMethodRef(left)
+	case OBRACKET:
+		if kind := p.typeOf(left); kind != nil && !kind.isArray() {
+			p.errorAt(t.pos, "array required, but %s found", kind)
+		}
+		index := p.parseExpression(lowest)
+		p.checkIndex(index)
+		p.expectNext(CBRACKET)
+		return &arrayIndex{expression: t.expression(), array: left, index: index}
+	}
+	prec := precedences[t.kind]
+	if prec == assign {
+		p.checkAssignable(left)
+		value := p.parseExpression(lowest)
+		if kind := p.typeOf(left); kind != nil && t.kind == ASSIGN {
+			targetFunction(kind, value)
+		}
+		return &assignment{expression: t.expression(), op: t.kind, target: left, value: value}
+	}
+	return &binary{expression: t.expression(), op: t.kind, left: left, right: p.parseExpression(prec)}
+}
+
+// checkAssignable reports an error unless target is a variable that may be assigned.
+// Only final locals with an initializer are checked, blank finals need definite assignment analysis
+func (p *Parser) checkAssignable(target Expression) {
+	switch target := target.(type) {
+	case *identifier:
+		switch v := p.scope.lookup(target.name); {
+		case v == nil:
+		case v.resource:
+			p.errorAt(target.pos, "auto-closeable resource %s may not be assigned", target.name)
+		case v.multiCatch:
+			p.errorAt(target.pos, "multi-catch parameter %s may not be assigned", target.name)
+		case v.isFinal && v.value != nil:
+			p.errorAt(target.pos, "cannot assign a value to final variable %s", target.name)
+		}
+	case *fieldAccess:
+		if target.name == "length" && p.typeOf(target.target).isArray() {
+			p.errorAt(target.pos, "cannot assign a value to final variable length")
+		}
+	case *arrayIndex, nil:
+	default:
+		p.errorf("unexpected type: the left-hand side of an assignment must be a variable")
+	}
+}
+
+// parseCall parses a method call, the current token must be the method name
+func (p *Parser) parseCall(target Expression) Expression {
+	c := &call{expression: p.token.expression(), target: target}
+	p.nextToken()
+	c.args = p.parseArguments()
+	return c
+}
+
+// parseNew parses an instance or array creation expression, the current token must be new
+func (p *Parser) parseNew() Expression {
+	t := p.token
+	if !p.expectNext(append(primitives, IDENTIFIER)...) {
+		return nil
+	}
+	kind := p.parseTypeName()
+	if p.peekToken.kind == OBRACKET {
+		return p.parseNewArray(t, kind)
+	}
+	if kind.kind != IDENTIFIER {
+		p.errorAt(p.peekToken.pos, "'[' expected")
+		return nil
+	}
+	if kind.param != nil {
+		p.errorAt(kind.pos, "unexpected type; required: class, found: type parameter %s", kind)
+	}
+	n := &newObject{expression: t.expression(), kind: kind}
+	if !p.expectNext(OPAREN) {
+		return nil
+	}
+	n.args = p.parseArguments()
+	if p.peekToken.kind == OBRACE {
+		p.nextToken()
+		if !p.parseAnonymousClass(n) {
+			return nil
+		}
+	}
+	return n
+}
+
+// parseArguments parses an argument list, the current token must be the opening parenthesis
+func (p *Parser) parseArguments() []Expression {
+	var args []Expression
+	if p.peekToken.kind == CPAREN {
+		p.nextToken()
+		return args
+	}
+	for {
+		args = append(args, p.parseExpression(lowest))
+		if !p.expectNext(COMMA, CPAREN) || p.token.kind == CPAREN {
+			return args
+		}
+	}
+}
+
+// castOperands are the tokens that can start the operand of a cast to a reference type.
+// A '+' or '-' would make the parenthesized name the left-hand side of a binary expression instead
+var castOperands = append([]tokenKind{IDENTIFIER, OPAREN, NOT, BIT_NOT, THIS, SUPER, NEW}, literals...)
+
+// isCast reports whether the parenthesis at the current token starts a cast,
+// by scanning ahead for a type followed by a closing parenthesis
+func (p *Parser) isCast() bool {
+	n := p.scanType(1)
+	if n == 0 || p.peekAt(n).kind != CPAREN {
+		return false
+	}
+	if slices.Contains(primitives, p.peekAt(1).kind) && p.peekAt(n-1).kind != CBRACKET {
+		return true
+	}
+	return slices.Contains(castOperands, p.peekAt(n+1).kind)
+}
@@ -0,0 +1,556 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// parseTypeParams parses the type parameters following the current token, like <K, V extends Comparable<V>>.
+// A type parameter is in scope in the bounds of every parameter of the list, so owner holds them while they are parsed
+func (p *Parser) parseTypeParams(owner *[]*typeParam, c *class) {
+	p.nextToken()
+	for {
+		if !p.expectNext(IDENTIFIER) {
+			return
+		}
+		param := &typeParam{node: p.token.node(), class: c}
+		if slices.ContainsFunc(*owner, func(o *typeParam) bool { return o.name == param.name }) {
+			p.errorf("%s is already defined", param.name)
+		}
+		*owner = append(*owner, param)
+		if p.peekToken.kind == EXTENDS {
+			p.nextToken()
+			for {
+				param.bounds = append(param.bounds, p.parseType())
+				if p.peekToken.kind != BIT_AND {
+					break
+				}
+				p.nextToken()
+			}
+		}
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	p.closeTypeArgs()
+	p.linkTypeParams(*owner)
+}
+
+// parseTypeArgs parses the type arguments following the current token, like <String, List<? extends T>>.
+// The diamond <> returns an empty list that isn't nil
+func (p *Parser) parseTypeArgs() []*typeRef {
+	p.nextToken()
+	args := []*typeRef{}
+	if p.peekToken.kind == GT {
+		p.nextToken()
+		return args
+	}
+	for {
+		var arg *typeRef
+		if p.peekToken.kind == QUESTION {
+			p.nextToken()
+			arg = &typeRef{node: p.token.node(), kind: QUESTION}
+			if p.peekToken.kind == EXTENDS || p.peekToken.kind == SUPER {
+				p.nextToken()
+				arg.isSuper = p.token.kind == SUPER
+				arg.bound = p.parseType()
+			}
+		} else {
+			arg = p.parseType()
+			if slices.Contains(primitives, arg.kind) && !arg.isArray() {
+				p.errorAt(arg.pos, "unexpected type; required: reference, found: %s", arg)
+			}
+		}
+		args = append(args, arg)
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.closeTypeArgs() {
+		return nil
+	}
+	return args
+}
+
+// closeTypeArgs expects the > closing a list of type arguments or parameters.
+// The lexer reads the closing brackets of nested lists as a shift operator like >>, which is split in two
+func (p *Parser) closeTypeArgs() bool {
+	switch t := p.peekToken; t.kind {
+	case SHIFT_RIGHT, UNSIGNED_SHIFT_RIGHT, GT_EQUALS, SHIFT_RIGHT_ASSIGN, UNSIGNED_SHIFT_RIGHT_ASSIGN:
+		rest := &token{pos: &pos{t.line, t.start + 1, t.end}, value: t.value[1:], kind: operators[t.value[1:]]}
+		p.peekToken = &token{pos: &pos{t.line, t.start, t.start}, value: ">", kind: GT, doc: t.doc}
+		p.ahead = append([]*token{rest}, p.ahead...)
+	}
+	return p.expectNext(GT)
+}
+
+// scanTypeArgs returns the offset of the token after the type arguments starting at peekAt(n), or 0 if there are none.
+// Only the tokens that may appear in type arguments are accepted, so a less than operator isn't taken for them
+func (p *Parser) scanTypeArgs(n int) int {
+	depth := 0
+	for {
+		switch p.peekAt(n).kind {
+		case LT:
+			depth++
+		case GT:
+			depth--
+		case SHIFT_RIGHT:
+			depth -= 2
+		case UNSIGNED_SHIFT_RIGHT:
+			depth -= 3
+		case IDENTIFIER, DOT, COMMA, QUESTION, EXTENDS, SUPER, OBRACKET, CBRACKET,
+			BOOLEAN, BYTE, SHORT, INT, LONG, CHAR, FLOAT, DOUBLE:
+		default:
+			return 0
+		}
+		n++
+		switch {
+		case depth == 0:
+			return n
+		case depth < 0:
+			return 0
+		}
+	}
+}
+
+// typeVar returns the type parameter named name in scope, declared by the method being parsed, its class
+// or the classes it is nested in
+func (p *Parser) typeVar(name string) *typeParam {
+	lists := [][]*typeParam{p.typeParams}
+	for c := p.class; c != nil; c = c.outer {
+		lists = append(lists, c.typeParams)
+	}
+	for _, params := range lists {
+		if i := slices.IndexFunc(params, func(t *typeParam) bool { return t.name == name }); i >= 0 {
+			return params[i]
+		}
+	}
+	return nil
+}
+
+// linkTypeParams links the type variables in the bounds of params to the parameters of the list declared after them,
+// and reports a parameter whose bound leads back to itself
+func (p *Parser) linkTypeParams(params []*typeParam) {
+	var link func(t *typeRef)
+	link = func(t *typeRef) {
+		if i := slices.IndexFunc(params, func(param *typeParam) bool { return param.name == t.name }); i >= 0 && t.kind == IDENTIFIER {
+			t.param = params[i]
+		}
+		if t.bound != nil {
+			link(t.bound)
+		}
+		for _, arg := range t.args {
+			link(arg)
+		}
+	}
+	for _, param := range params {
+		for _, bound := range param.bounds {
+			link(bound)
+		}
+	}
+	for _, param := range params {
+		seen := map[*typeParam]bool{}
+		for next := param; next != nil && len(next.bounds) > 0; next = next.bounds[0].param {
+			if seen[next] {
+				p.errorAt(param.pos, "cyclic inheritance involving %s", param.name)
+				param.bounds = nil
+				break
+			}
+			seen[next] = true
+		}
+	}
+}
+
+// inferDiamond gives the instance creation value assigned to a variable of type kind, like new Box<>(),
+// the type arguments of kind, JLS 15.9.3. A wildcard is inferred as its upper bound
+func (p *Parser) inferDiamond(kind *typeRef, value Expression) {
+	n, ok := value.(*newObject)
+	if !ok || n.kind.args == nil || len(n.kind.args) > 0 || len(kind.args) == 0 || kind.isArray() {
+		return
+	}
+	n.kind.args = make([]*typeRef, len(kind.args))
+	for i, arg := range kind.args {
+		n.kind.args[i] = arg.upperBound()
+	}
+}
+
+// checkTypeArgs reports a value assigned to a variable of type kind whose type has different type arguments,
+// like a List<Integer> assigned to a List<String>. Parameterized types are invariant, JLS 4.10.2
+func (p *Parser) checkTypeArgs(kind *typeRef, value Expression) {
+	from := p.typeOf(value)
+	if from == nil || from.name != kind.name || len(from.args) == 0 || len(from.args) != len(kind.args) {
+		return
+	}
+	for i, arg := range kind.args {
+		if arg.kind != QUESTION && from.args[i].kind != QUESTION && arg.String() != from.args[i].String() {
+			p.errorAt(value.Position(), "incompatible types: %s cannot be converted to %s", from, kind)
+			return
+		}
+	}
+}
+
+// checkMembers types the calls of c to generic methods with the type arguments of the receiver and the arguments
+// substituted, see castCall, and reports the arguments and assigned values that don't match the substituted types.
+// It runs once the calls are resolved, before eraseFile erases the types
+func (p *Parser) checkMembers(f *file, c *class) {
+	var nodes []Node
+	collect := func(n Node) bool {
+		nodes = append(nodes, n)
+		return true
+	}
+	for _, fld := range c.fields {
+		inspect(fld.value, collect)
+	}
+	for _, m := range c.bodies() {
+		inspectAll(m.statements, collect)
+	}
+	for _, n := range nodes {
+		p.castCall(f, c, n)
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *call:
+			p.checkArguments(f, c, n)
+		case *localVars:
+			for _, v := range n.vars {
+				p.checkSubstituted(f, v.kind, v.value)
+			}
+		case *assignment:
+			if n.op == ASSIGN {
+				p.checkSubstituted(f, p.typeOf(n.target), n.value)
+			}
+		}
+	}
+	for _, fld := range c.fields {
+		p.checkSubstituted(f, fld.kind, fld.value)
+	}
+}
+
+// checkArguments reports the arguments of the call n to a member of a parameterized type, or to a generic method,
+// that don't match the parameter types with the type arguments substituted. c is the class containing the call
+func (p *Parser) checkArguments(f *file, c *class, n *call) {
+	if n.method == nil || n.method.isVarargs() || len(n.args) != len(n.method.parameters) {
+		return
+	}
+	args := p.memberTypeArgs(f, c, n)
+	for i, arg := range n.args {
+		if param := n.method.parameters[i].kind; param.param != nil {
+			p.checkConversion(f, param.substitute(args), arg)
+		}
+	}
+}
+
+// checkSubstituted reports value, assigned to a variable of type kind, when it is a call to a method returning
+// a type variable whose substituted type doesn't match kind
+func (p *Parser) checkSubstituted(f *file, kind *typeRef, value Expression) {
+	if call, ok := value.(*call); ok && call.cast != nil && kind != nil {
+		p.checkConversion(f, kind, value)
+	}
+}
+
+// checkConversion reports value when its type can't be converted to kind by assignment, JLS 5.2.
+// Types that depend on type variables or on classes outside the source root are accepted
+func (p *Parser) checkConversion(f *file, kind *typeRef, value Expression) {
+	from := p.typeOf(value)
+	if from == nil || kind.param != nil || kind.kind == QUESTION || kind.name == "var" {
+		return
+	}
+	ok := true
+	toPrimitive := kind.kind != IDENTIFIER && !kind.isArray()
+	fromPrimitive := from.kind != IDENTIFIER && !from.isArray()
+	switch {
+	case from.param != nil:
+	case toPrimitive && fromPrimitive:
+		ok = assignable(from, kind)
+	case toPrimitive:
+		// Unboxing, followed by a widening conversion
+		ok = slices.ContainsFunc(primitives, func(k tokenKind) bool {
+			unboxed := primitiveType(k, from.pos)
+			return boxes[unboxed.name] == from.name && assignable(unboxed, kind)
+		})
+	default:
+		ok = f.isSubtype(from.boxed(), kind)
+	}
+	if !ok {
+		p.errorAt(value.Position(), "incompatible types: %s cannot be converted to %s", from, kind)
+	}
+}
+
+// checkGenerics reports the misused type arguments and type variables in the types written in the classes of f
+func (p *Parser) checkGenerics(f *file) {
+	for _, c := range f.classes {
+		c.walkTypes(func(t *typeRef, static bool) { p.checkTypeRef(f, t, static) })
+	}
+}
+
+// checkTypeRef reports the type arguments of t that don't match the type parameters of its class, JLS 4.5,
+// and the type variables of a class referenced from a static context
+func (p *Parser) checkTypeRef(f *file, t *typeRef, static bool) {
+	if t.bound != nil {
+		p.checkTypeRef(f, t.bound, static)
+	}
+	for _, arg := range t.args {
+		p.checkTypeRef(f, arg, static)
+	}
+	if t.param != nil && t.param.class != nil && static {
+		p.errorAt(t.pos, "non-static type variable %s cannot be referenced from a static context", t.name)
+	}
+	if t.args == nil {
+		return
+	}
+	c := f.class(t.name)
+	if c == nil && t.param == nil {
+		return
+	}
+	switch {
+	case len(t.args) == 0 && c != nil && len(c.typeParams) == 0:
+		p.errorAt(t.pos, "cannot infer type arguments for %s; reason: cannot use '<>' with non-generic class %s", t.name, t.name)
+	case c == nil || len(c.typeParams) == 0:
+		p.errorAt(t.pos, "type %s does not take parameters", t.name)
+	case len(t.args) == 0:
+	case len(t.args) != len(c.typeParams):
+		p.errorAt(t.pos, "wrong number of type arguments; required %d", len(c.typeParams))
+	default:
+		args := typeArgs(c, t)
+		for i, param := range c.typeParams {
+			if t.args[i].kind == QUESTION {
+				continue
+			}
+			for _, bound := range param.bounds {
+				if !f.isSubtype(t.args[i], bound.substitute(args)) {
+					p.errorAt(t.args[i].pos, "type argument %s is not within bounds of type-variable %s", t.args[i], param.name)
+					break
+				}
+			}
+		}
+	}
+}
+
+// walkTypes calls fn for the types written in the declarations and statements of c, but not for their type arguments.
+// static is set for the types in a static context, where the type parameters of c aren't in scope
+func (c *class) walkTypes(fn func(t *typeRef, static bool)) {
+	if c.extends != nil {
+		fn(c.extends, false)
+	}
+	for _, ref := range c.implements {
+		fn(ref, false)
+	}
+	for _, param := range c.typeParams {
+		for _, bound := range param.bounds {
+			fn(bound, false)
+		}
+	}
+	expressions := func(n Node, static bool) bool {
+		switch n := n.(type) {
+		case *localVars:
+			fn(n.kind, static)
+			for _, v := range n.vars {
+				if v.kind != n.kind {
+					fn(v.kind, static)
+				}
+			}
+		case *forEach:
+			fn(n.variable.kind, static)
+		case *cast:
+			fn(n.kind, static)
+		case *instanceOf:
+			fn(n.kind, static)
+		case *newObject:
+			fn(n.kind, static)
+		case *newArray:
+			fn(n.kind, static)
+		case *arrayInit:
+			fn(n.kind, static)
+		case *lambda:
+			for _, param := range n.params {
+				if !param.inferred {
+					fn(param.kind, static)
+				}
+			}
+		case *call:
+			for _, arg := range n.typeArgs {
+				fn(arg, static)
+			}
+		case *tryStmt:
+			for _, c := range n.catches {
+				for _, kind := range c.kinds {
+					fn(kind, static)
+				}
+			}
+		}
+		return true
+	}
+	for _, f := range c.fields {
+		fn(f.kind, f.isStatic)
+		inspect(f.value, func(n Node) bool { return expressions(n, f.isStatic) })
+	}
+	for _, m := range c.bodies() {
+		if m.implicit {
+			continue
+		}
+		fn(m.kind, m.isStatic)
+		for _, param := range m.parameters {
+			fn(param.kind, m.isStatic)
+		}
+		for _, t := range m.throws {
+			fn(t, m.isStatic)
+		}
+		for _, param := range m.typeParams {
+			for _, bound := range param.bounds {
+				fn(bound, m.isStatic)
+			}
+		}
+		inspectAll(m.statements, func(n Node) bool { return expressions(n, m.isStatic) })
+	}
+}
+
+// typeArgs maps the type parameters of c to the type arguments of t, a parameterized type of c.
+// Raw types and the diamond map nothing, so their type variables erase to their bounds
+func typeArgs(c *class, t *typeRef) map[*typeParam]*typeRef {
+	args := map[*typeParam]*typeRef{}
+	if t != nil && len(t.args) == len(c.typeParams) {
+		for i, param := range c.typeParams {
+			args[param] = t.args[i]
+		}
+	}
+	return args
+}
+
+// supertypeArgs maps the type parameters of s to their type arguments, when s is a supertype of c
+// whose type parameters are mapped by args. The extends and implements clauses leading to s are followed
+func (c *class) supertypeArgs(s *class, args map[*typeParam]*typeRef) map[*typeParam]*typeRef {
+	if c == s {
+		return args
+	}
+	for _, t := range c.supertypes() {
+		if ref := c.supertypeRef(t); ref != nil && t.reaches(s, map[*class]bool{}) {
+			return t.supertypeArgs(s, typeArgs(t, ref.substitute(args)))
+		}
+	}
+	return map[*typeParam]*typeRef{}
+}
+
+// supertypeRef returns the type in the extends or implements clause of c naming its supertype s
+func (c *class) supertypeRef(s *class) *typeRef {
+	if s == c.superclass {
+		return c.extends
+	}
+	for _, ref := range c.implements {
+		if ref.name == s.name || strings.HasSuffix(ref.name, "."+s.name) {
+			return ref
+		}
+	}
+	return nil
+}
+
+// substitute returns t with the type variables mapped by args replaced by their type arguments.
+// A wildcard type argument replaces a type variable by its upper bound
+func (t *typeRef) substitute(args map[*typeParam]*typeRef) *typeRef {
+	if arg, ok := args[t.param]; ok && t.param != nil {
+		return arrayType(arg.upperBound(), t.dims)
+	}
+	if t.bound == nil && len(t.args) == 0 {
+		return t
+	}
+	s := *t
+	if t.bound != nil {
+		s.bound = t.bound.substitute(args)
+	}
+	s.args = make([]*typeRef, len(t.args))
+	for i, arg := range t.args {
+		s.args[i] = arg.substitute(args)
+	}
+	return &s
+}
+
+// upperBound returns the bound of a wildcard ? extends T, Object for other wildcards, and t itself for other types
+func (t *typeRef) upperBound() *typeRef {
+	switch {
+	case t.kind != QUESTION:
+		return t
+	case t.bound != nil && !t.isSuper:
+		return t.bound
+	}
+	return classType("Object", t.pos)
+}
+
+// inferTypeArgs maps the type parameters of the generic method m to the types of the arguments of c
+// passed for the parameters of those types, boxing primitives. Explicit type arguments are used when c has them
+func (p *Parser) inferTypeArgs(m *method, c *call) map[*typeParam]*typeRef {
+	args := map[*typeParam]*typeRef{}
+	if len(c.typeArgs) > 0 && len(c.typeArgs) == len(m.typeParams) {
+		for i, param := range m.typeParams {
+			args[param] = c.typeArgs[i]
+		}
+		return args
+	}
+	for i, param := range m.parameters {
+		if i >= len(c.args) || !slices.Contains(m.typeParams, param.kind.param) {
+			continue
+		}
+		kind := p.typeOf(c.args[i])
+		if _, ok := args[param.kind.param]; ok || kind == nil || kind.dims < param.kind.dims {
+			continue
+		}
+		args[param.kind.param] = arrayType(kind, -param.kind.dims).boxed()
+	}
+	return args
+}
+
+// jdkSupertypes lists the supertypes of the classes of java.lang used as type arguments,
+// so their bounds can be checked without the class library
+var jdkSupertypes = map[string][]string{
+	"String":    {"CharSequence", "Comparable", "Serializable"},
+	"Integer":   {"Number", "Comparable", "Serializable"},
+	"Long":      {"Number", "Comparable", "Serializable"},
+	"Short":     {"Number", "Comparable", "Serializable"},
+	"Byte":      {"Number", "Comparable", "Serializable"},
+	"Double":    {"Number", "Comparable", "Serializable"},
+	"Float":     {"Number", "Comparable", "Serializable"},
+	"Character": {"Comparable", "Serializable"},
+	"Boolean":   {"Comparable", "Serializable"},
+	"Number":    {"Serializable"},
+}
+
+// isSubtype reports whether sub is a subtype of sup, ignoring their type arguments.
+// Types declared outside the source root and not listed in jdkSupertypes are assumed to be subtypes
+func (f *file) isSubtype(sub, sup *typeRef) bool {
+	switch {
+	case sub.param != nil:
+		return len(sub.param.bounds) == 0 || slices.ContainsFunc(sub.param.bounds, func(b *typeRef) bool { return f.isSubtype(b, sup) })
+	case sup.param != nil || sup.name == "Object" || sub.name == sup.name && sub.dims == sup.dims:
+		return true
+	case sub.isArray() || sup.isArray():
+		return false
+	}
+	if c := f.class(sub.name); c != nil {
+		return f.hasSupertype(c, sup, map[*class]bool{})
+	}
+	if supers, ok := jdkSupertypes[sub.name]; ok {
+		return slices.Contains(supers, sup.name)
+	}
+	return f.class(sup.name) == nil
+}
+
+// hasSupertype reports whether the class c declared in the source root has the supertype sup
+func (f *file) hasSupertype(c *class, sup *typeRef, seen map[*class]bool) bool {
+	if seen[c] {
+		return false
+	}
+	seen[c] = true
+	refs := c.implements
+	if c.extends != nil {
+		refs = append([]*typeRef{c.extends}, refs...)
+	}
+	for _, ref := range refs {
+		if ref.name == sup.name {
+			return true
+		}
+		if s := f.class(ref.name); s != nil && f.hasSupertype(s, sup, seen) || s == nil && f.class(sup.name) == nil {
+			return true
+		}
+	}
+	return false
+}
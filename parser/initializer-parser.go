@@ -0,0 +1,71 @@
+package parser
+
+import "slices"
+
+// parseInitBlock parses a static or instance initializer of p.class, following its modifiers, JLS 8.6 and 8.7.
+// The body is parsed like that of a void method without parameters
+func (p *Parser) parseInitBlock(mods modifiers, isFinal bool) parseStateFn {
+	p.nextToken()
+	switch {
+	case mods.visibility != PACKAGE || isFinal || mods.isAbstract || mods.isDefault:
+		p.errorf("illegal start of type")
+	case p.class.isInterface:
+		p.errorf("initializers not allowed in interfaces")
+	case p.class.isRecord && !mods.isStatic:
+		p.errorf("instance initializers not allowed in records")
+	}
+	name := "<init>"
+	if mods.isStatic {
+		name = "<clinit>"
+	}
+	p.decl = &decl{
+		node:      node{name, p.token.pos},
+		kind:      primitiveType(VOID, p.token.pos),
+		modifiers: modifiers{visibility: PRIVATE, isStatic: mods.isStatic},
+	}
+	p.method = &method{decl: p.decl}
+	p.targets = nil
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+	p.method.statements = body.statements
+	p.class.blocks = append(p.class.blocks, &initBlock{method: p.method, index: len(p.class.fields)})
+	return parseDeclaration
+}
+
+// isInitializer reports whether d is the implicit method of an initializer block
+func (d *decl) isInitializer() bool {
+	return d.name == "<init>" || d.name == "<clinit>"
+}
+
+// initializerThrows returns the checked exceptions the instance initializers of c may throw, JLS 11.2.3.
+// Those of an anonymous class may throw any exception, and those of a named class the exceptions
+// that each of its constructors declares, provided it declares one
+func (c *class) initializerThrows() []*typeRef {
+	if c.isAnonymous {
+		return []*typeRef{classType("Throwable", c.pos)}
+	}
+	ctors := c.constructors()
+	if len(ctors) == 0 || ctors[0].implicit {
+		return nil
+	}
+	var throws []*typeRef
+	for _, t := range ctors[0].throws {
+		if !slices.ContainsFunc(ctors[1:], func(m *method) bool {
+			return !slices.ContainsFunc(m.throws, func(d *typeRef) bool { return d.name == t.name })
+		}) {
+			throws = append(throws, t)
+		}
+	}
+	return throws
+}
+
+// bodies returns the methods of c followed by the methods of its initializer blocks
+func (c *class) bodies() []*method {
+	methods := slices.Clone(c.methods)
+	for _, b := range c.blocks {
+		methods = append(methods, b.method)
+	}
+	return methods
+}
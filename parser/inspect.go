@@ -0,0 +1,112 @@
+package parser
+
+// inspect walks the statements and expressions under n in source order, calling fn for every node.
+// The children of a node are skipped when fn returns false
+func inspect(n Node, fn func(Node) bool) {
+	if n == nil || !fn(n) {
+		return
+	}
+	switch n := n.(type) {
+	case *unary:
+		inspect(n.operand, fn)
+	case *binary:
+		inspect(n.left, fn)
+		inspect(n.right, fn)
+	case *conditional:
+		inspect(n.cond, fn)
+		inspect(n.then, fn)
+		inspect(n.otherwise, fn)
+	case *assignment:
+		inspect(n.target, fn)
+		inspect(n.value, fn)
+	case *cast:
+		inspect(n.operand, fn)
+	case *instanceOf:
+		inspect(n.operand, fn)
+	case *call:
+		inspect(n.target, fn)
+		inspectAll(n.args, fn)
+	case *fieldAccess:
+		inspect(n.target, fn)
+	case *newObject:
+		inspect(n.outer, fn)
+		inspectAll(n.args, fn)
+	case *constructorCall:
+		inspectAll(n.args, fn)
+	case *lambda:
+		inspect(n.body, fn)
+	case *methodRef:
+		inspect(n.target, fn)
+	case *arrayIndex:
+		inspect(n.array, fn)
+		inspect(n.index, fn)
+	case *newArray:
+		inspectAll(n.dims, fn)
+		if n.init != nil {
+			inspect(n.init, fn)
+		}
+	case *arrayInit:
+		inspectAll(n.elements, fn)
+	case *switchExpr:
+		inspectSwitch(&n.switchBlock, fn)
+	case *expressionStmt:
+		inspect(n.expr, fn)
+	case *localVars:
+		for _, v := range n.vars {
+			inspect(v.value, fn)
+		}
+	case *block:
+		inspectAll(n.statements, fn)
+	case *ifStmt:
+		inspect(n.cond, fn)
+		inspect(n.then, fn)
+		inspect(n.otherwise, fn)
+	case *whileStmt:
+		inspect(n.cond, fn)
+		inspect(n.body, fn)
+	case *doWhile:
+		inspect(n.body, fn)
+		inspect(n.cond, fn)
+	case *forStmt:
+		inspectAll(n.init, fn)
+		inspect(n.cond, fn)
+		inspectAll(n.update, fn)
+		inspect(n.body, fn)
+	case *forEach:
+		inspect(n.iterable, fn)
+		inspect(n.body, fn)
+	case *labeled:
+		inspect(n.body, fn)
+	case *returnStmt:
+		inspect(n.value, fn)
+	case *yield:
+		inspect(n.value, fn)
+	case *throwStmt:
+		inspect(n.value, fn)
+	case *tryStmt:
+		inspectAll(n.resources, fn)
+		inspect(n.body, fn)
+		for _, c := range n.catches {
+			inspect(c.body, fn)
+		}
+		if n.finally != nil {
+			inspect(n.finally, fn)
+		}
+	case *switchStmt:
+		inspectSwitch(&n.switchBlock, fn)
+	}
+}
+
+func inspectAll[T Node](nodes []T, fn func(Node) bool) {
+	for _, n := range nodes {
+		inspect(n, fn)
+	}
+}
+
+func inspectSwitch(s *switchBlock, fn func(Node) bool) {
+	inspect(s.selector, fn)
+	for _, c := range s.cases {
+		inspectAll(c.labels, fn)
+		inspectAll(c.body, fn)
+	}
+}
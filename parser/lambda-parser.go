@@ -0,0 +1,408 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// isLambda reports whether a lambda expression starts at peekToken, a parameter name or a parenthesized
+// parameter list followed by an arrow
+func (p *Parser) isLambda() bool {
+	switch p.peekToken.kind {
+	case IDENTIFIER:
+		return p.peekAt(2).kind == ARROW
+	case OPAREN:
+		depth := 0
+		for n := 1; ; n++ {
+			switch p.peekAt(n).kind {
+			case OPAREN:
+				depth++
+			case CPAREN:
+				if depth--; depth == 0 {
+					return p.peekAt(n+1).kind == ARROW
+				}
+			case EOF, SEMICOLON, OBRACE, CBRACE:
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// parseLambda parses the lambda expression at peekToken. The body is parsed like a method body in the scope of the
+// parameters, so return statements return from the lambda and jumps can't leave it
+func (p *Parser) parseLambda() Expression {
+	p.nextToken()
+	l := &lambda{expression: p.token.expression()}
+	outer := p.scope
+	p.openScope()
+	defer p.closeScope()
+	if p.token.kind == IDENTIFIER {
+		l.params = []*localVar{p.inferredParam()}
+	} else if !p.parseLambdaParams(l) {
+		return nil
+	}
+	for _, param := range l.params {
+		p.declare(param)
+	}
+	if !p.expectNext(ARROW) {
+		return nil
+	}
+	l.node = p.token.node()
+	targets, enclosing := p.targets, p.lambda
+	p.targets, p.lambda = nil, l
+	defer func() { p.targets, p.lambda = targets, enclosing }()
+	if p.peekToken.kind == OBRACE {
+		p.nextToken()
+		body := p.parseBlock()
+		if body == nil {
+			return nil
+		}
+		l.body = body
+	} else if l.body = p.parseExpression(lowest); l.body == nil {
+		return nil
+	}
+	inspect(l.body, func(n Node) bool {
+		if id, ok := n.(*identifier); ok && id.local != nil && outer.lookup(id.name) == id.local && !slices.Contains(l.captured, id.local) {
+			l.captured = append(l.captured, id.local)
+		}
+		return true
+	})
+	return l
+}
+
+// statements returns the body of l as a list of statements, an expression body is evaluated by a single statement
+func (l *lambda) statements() []Statement {
+	if body, ok := l.body.(*block); ok {
+		return body.statements
+	}
+	return []Statement{&expressionStmt{statement: statement{l.node}, expr: l.body.(Expression)}}
+}
+
+// inferredParam returns the lambda parameter named by the current token, whose type is inferred like a var local
+func (p *Parser) inferredParam() *localVar {
+	t := p.token
+	return &localVar{node: t.node(), kind: classType("var", t.pos), inferred: true}
+}
+
+// parseLambdaParams parses the parenthesized parameters of l, following the opening parenthesis.
+// The parameters are either all inferred, like (a, b), or all declared with a type or var, like (int a, var b)
+func (p *Parser) parseLambdaParams(l *lambda) bool {
+	inferred := 0
+	for p.peekToken.kind != CPAREN {
+		if p.peekToken.kind == IDENTIFIER && (p.peekAt(2).kind == COMMA || p.peekAt(2).kind == CPAREN) {
+			p.nextToken()
+			l.params = append(l.params, p.inferredParam())
+			inferred++
+		} else {
+			decl := p.parseLocalVarType()
+			if !p.expectNext(IDENTIFIER) {
+				return false
+			}
+			l.params = append(l.params, &localVar{node: p.token.node(), kind: arrayType(decl.kind, p.parseDims()), isFinal: decl.isFinal, inferred: decl.inferred})
+		}
+		if p.peekToken.kind != CPAREN && !p.expectNext(COMMA) {
+			return false
+		}
+	}
+	p.nextToken()
+	if inferred > 0 && inferred < len(l.params) {
+		p.errorAt(l.pos, "invalid lambda parameter declaration (cannot mix implicitly-typed and explicitly-typed parameters)")
+	}
+	return true
+}
+
+// parseMethodRef parses the method name or new following the double colon at the current token, with target before it
+func (p *Parser) parseMethodRef(target Expression) Expression {
+	if !p.expectNext(IDENTIFIER, NEW) {
+		return nil
+	}
+	return &methodRef{expression: p.token.expression(), target: target}
+}
+
+// targetFunction gives value, when it is a lambda or a method reference, the functional interface type kind
+// of the variable, parameter or return value it is assigned to
+func targetFunction(kind *typeRef, value Expression) {
+	switch v := value.(type) {
+	case *lambda:
+		if v.kind == nil {
+			v.kind = kind
+		}
+	case *methodRef:
+		if v.kind == nil {
+			v.kind = kind
+		}
+	case *conditional:
+		targetFunction(kind, v.then)
+		targetFunction(kind, v.otherwise)
+	}
+}
+
+// functionalLibrary declares the functional interfaces of the class library that callbacks use the most,
+// in java.lang, java.util and java.util.function
+const functionalLibrary = `
+interface Runnable { void run(); }
+interface Callable<V> { V call() throws Exception; }
+interface Comparator<T> { int compare(T o1, T o2); }
+interface Supplier<T> { T get(); }
+interface Consumer<T> { void accept(T t); }
+interface BiConsumer<T, U> { void accept(T t, U u); }
+interface Function<T, R> { R apply(T t); }
+interface BiFunction<T, U, R> { R apply(T t, U u); }
+interface UnaryOperator<T> { T apply(T t); }
+interface BinaryOperator<T> { T apply(T t1, T t2); }
+interface Predicate<T> { boolean test(T t); }
+interface BiPredicate<T, U> { boolean test(T t, U u); }
+`
+
+// libraryInterfaces are the interfaces of functionalLibrary, parsed once without being erased
+var libraryInterfaces = sync.OnceValue(func() []*class {
+	p := NewFromString("functional.java", functionalLibrary, "")
+	for p.state != nil && p.peekToken.kind != EOF {
+		p.state = p.state(p)
+	}
+	p.lexer.Close()
+	return p.ast.files[0].classes
+})
+
+// functionalInterface returns the class of the functional interface type t, declared in the source root
+// or in functionalLibrary, and its abstract method, JLS 9.8. The method is nil when the class isn't a functional interface,
+// and both are nil when the type isn't known
+func (f *file) functionalInterface(t *typeRef) (*class, *abstractMethod) {
+	c := f.class(t.name)
+	if c == nil {
+		i := slices.IndexFunc(libraryInterfaces(), func(l *class) bool { return l.name == t.name || "java.util.function."+l.name == t.name })
+		if i < 0 {
+			return nil, nil
+		}
+		c = libraryInterfaces()[i]
+	}
+	if !c.isInterface || t.isArray() {
+		return c, nil
+	}
+	var function *abstractMethod
+	for _, m := range c.abstractMethods() {
+		// The public methods of Object don't count, an interface may redeclare them
+		if slices.Contains([]string{"equals(Object)", "hashCode()", "toString()"}, m.sig) {
+			continue
+		}
+		if function != nil {
+			return c, nil
+		}
+		function = &m
+	}
+	return c, function
+}
+
+// checkFunction types the lambda or method reference n against its functional interface, JLS 15.27.3 and 15.13.2.
+// The inferred parameters of a lambda get the parameter types of the function, with the type arguments of the interface
+func (p *Parser) checkFunction(f *file, c *class, n Node) {
+	var kind *typeRef
+	switch n := n.(type) {
+	case *lambda:
+		kind = n.kind
+	case *methodRef:
+		kind = n.kind
+	default:
+		return
+	}
+	if kind == nil {
+		return
+	}
+	i, function := f.functionalInterface(kind)
+	// Unknown classes may be functional interfaces of the class library
+	if i == nil && kind.kind == IDENTIFIER && !kind.isArray() && !kind.isString() && kind.name != "Object" {
+		return
+	}
+	if function == nil {
+		p.errorAt(n.Position(), "incompatible types: %s is not a functional interface", kind)
+		return
+	}
+	args := i.supertypeArgs(function.owner, typeArgs(i, kind))
+	params := make([]*typeRef, len(function.method.parameters))
+	for j, param := range function.method.parameters {
+		params[j] = param.kind.substitute(args).upperBound()
+	}
+	switch n := n.(type) {
+	case *lambda:
+		n.function = function.method
+		p.checkLambda(c, n, params, function.method.kind.substitute(args).upperBound())
+	case *methodRef:
+		n.function = function.method
+		p.resolveMethodRef(f, c, n, params)
+	}
+}
+
+// checkLambda reports a lambda l whose parameters or body don't match the function it implements,
+// whose parameter types are params and return type is result. c is the class containing l
+func (p *Parser) checkLambda(c *class, l *lambda, params []*typeRef, result *typeRef) {
+	if len(l.params) != len(params) {
+		p.errorAt(l.pos, "incompatible types: incompatible parameter types in lambda expression")
+		return
+	}
+	for i, param := range l.params {
+		switch {
+		case param.inferred:
+			param.kind = params[i]
+		case param.kind.erasure().String() != params[i].erasure().String() && params[i].param == nil:
+			p.errorAt(param.pos, "incompatible types: incompatible parameter types in lambda expression")
+			return
+		}
+	}
+	void := result.kind == VOID
+	switch body := l.body.(type) {
+	case *block:
+		var returns []*returnStmt
+		inspect(body, func(n Node) bool {
+			if r, ok := n.(*returnStmt); ok {
+				returns = append(returns, r)
+			}
+			_, nested := n.(*lambda)
+			return !nested
+		})
+		for _, r := range returns {
+			switch {
+			case void && r.value != nil:
+				p.errorAt(r.value.Position(), "incompatible types: bad return type in lambda expression: unexpected return value")
+			case !void && r.value == nil:
+				p.errorAt(r.pos, "incompatible types: bad return type in lambda expression: missing return value")
+			case !void:
+				p.checkLambdaResult(r.value, result)
+			}
+		}
+		// A value-compatible block can't complete normally, JLS 15.27.2
+		switch {
+		case void || !c.completesNormally(body):
+		case slices.ContainsFunc(returns, func(r *returnStmt) bool { return r.value != nil }):
+			p.errorAt(l.pos, "lambda body is neither value nor void compatible")
+		default:
+			p.errorAt(l.pos, "incompatible types: bad return type in lambda expression: missing return value")
+		}
+	case Expression:
+		kind := p.typeOf(body)
+		switch {
+		case void && !isStatementExpression(body) && kind != nil:
+			p.errorAt(body.Position(), "incompatible types: bad return type in lambda expression: %s cannot be converted to void", kind)
+		case void && !isStatementExpression(body):
+			p.errorAt(body.Position(), "incompatible types: bad return type in lambda expression")
+		case kind != nil && kind.kind == VOID && !void:
+			p.errorAt(body.Position(), "incompatible types: bad return type in lambda expression: void cannot be converted to %s", result)
+		case !void:
+			p.checkLambdaResult(body, result)
+		}
+	}
+}
+
+// checkLambdaResult reports a value returned by a lambda whose type can't be assigned to the result of its function
+func (p *Parser) checkLambdaResult(value Expression, result *typeRef) {
+	if kind := p.typeOf(value); kind != nil && kind.kind != VOID && result.param == nil && !assignable(kind, result) {
+		p.errorAt(value.Position(), "incompatible types: bad return type in lambda expression: %s cannot be converted to %s", kind, result)
+	}
+}
+
+// resolveMethodRef resolves the method or constructor r refers to, called with arguments of types params.
+// A type name followed by a method name refers to a static method, or to an instance method called on the first
+// argument, JLS 15.13.1. Only references to classes of the source root are resolved
+func (p *Parser) resolveMethodRef(f *file, c *class, r *methodRef, params []*typeRef) {
+	var owner *class
+	static := false
+	switch target := r.target.(type) {
+	case *thisExpr:
+		owner = c
+	case *superExpr:
+		owner = target.owner(f)
+	case *identifier:
+		if target.local == nil {
+			owner, static = f.class(target.name), true
+		} else if !target.local.kind.isArray() {
+			owner = f.class(target.local.kind.name)
+		}
+	case *fieldAccess:
+		owner, static = f.class(fmt.Sprint(target)), true
+	}
+	if owner == nil {
+		return
+	}
+	matches := func(m *method, params []*typeRef) bool {
+		return len(m.parameters) == len(params) && slices.EqualFunc(m.parameters, params, func(a *parameter, b *typeRef) bool {
+			return b.param != nil || b.kind == IDENTIFIER && b.name == "var" || assignable(b, a.kind)
+		})
+	}
+	candidates := owner.methodsNamed(r.name)
+	if r.name == "new" {
+		candidates, static = owner.constructors(), false
+	}
+	for _, m := range candidates {
+		// An instance method referred to by a type name is called on the first argument
+		if static && !m.isStatic && len(params) > 0 && matches(m, params[1:]) || (m.isStatic || !static) && matches(m, params) {
+			r.method = m
+			break
+		}
+	}
+	if r.method == nil {
+		p.errorAt(r.pos, "invalid method reference: cannot find symbol %s in %s", r.name, owner.name)
+	}
+}
+
+// checkCaptures reports the locals that lambdas and local or anonymous classes in m capture but that aren't effectively
+// final, JLS 15.27.2 and 8.1.3. A local is effectively final when it is never assigned after its initializer,
+// or assigned once without one
+func (p *Parser) checkCaptures(m *method) {
+	assigned := map[*localVar]int{}
+	blank := map[*localVar]bool{}
+	for _, stmt := range m.statements {
+		inspectClasses(stmt, func(n Node) bool {
+			switch n := n.(type) {
+			case *localVars:
+				for _, v := range n.vars {
+					blank[v] = v.value == nil
+				}
+			case *assignment:
+				if id, ok := n.target.(*identifier); ok && id.local != nil {
+					assigned[id.local]++
+				}
+			case *unary:
+				if id, ok := n.operand.(*identifier); ok && id.local != nil && (n.op == INCREMENT || n.op == DECREMENT) {
+					assigned[id.local]++
+				}
+			}
+			return true
+		})
+	}
+	// capture is a lambda or class body, and what it is called in the error
+	type capture struct {
+		captured []*localVar
+		body     func(func(Node) bool)
+		kind     string
+	}
+	var captures []capture
+	inspectAll(m.statements, func(n Node) bool {
+		switch n := n.(type) {
+		case *lambda:
+			captures = append(captures, capture{n.captured, func(fn func(Node) bool) { inspect(n.body, fn) }, "a lambda expression"})
+		case *localClass:
+			captures = append(captures, capture{n.class.captured, n.class.inspectBody, "an inner class"})
+		case *newObject:
+			if n.body != nil {
+				captures = append(captures, capture{n.body.captured, n.body.inspectBody, "an inner class"})
+			}
+		}
+		return true
+	})
+	for _, c := range captures {
+		for _, v := range c.captured {
+			if v.isFinal || assigned[v] == 0 || assigned[v] == 1 && blank[v] {
+				continue
+			}
+			var use *identifier
+			c.body(func(n Node) bool {
+				if id, ok := n.(*identifier); ok && id.local == v && use == nil {
+					use = id
+				}
+				return use == nil
+			})
+			p.errorAt(use.pos, "local variables referenced from %s must be final or effectively final", c.kind)
+		}
+	}
+}
@@ -11,6 +11,10 @@ import (
 
 type lexStateFn func(*lexer) lexStateFn
 
+// eof is returned by next when the reader is exhausted
+const eof = -1
+
+// lexer is a pull lexer, each call to nextToken runs the state machine until a token is emitted.
 type lexer struct {
 	reader    *bufio.Reader
 	line      int
@@ -18,273 +22,232 @@ type lexer struct {
 	runes     []rune
 	state     lexStateFn
 	prevToken string
-	tokens    chan *token
-	running   bool
-	cleanup   func()
+	// tokens holds emitted tokens not yet returned by nextToken
+	tokens []*token
+	// source is closed by Close, when the lexer opened it
+	source io.Closer
+	// doc is the last Javadoc comment, attached to the next emitted token
+	doc string
+	// atEOF, last and lastColumn let backup undo the previous call to next
+	atEOF      bool
+	last       rune
+	lastColumn int
 }
 
-func newLexer(src string) (*lexer, error) {
-	file, err := os.Open(src)
+// newLexer returns a lexer reading the file at path, the file is closed with the lexer
+func newLexer(path string) (*lexer, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	chanTokens := make(chan *token)
-	return &lexer{
-		reader: bufio.NewReader(file),
-		line:   1,
-		column: 1,
-		state:  lexClass,
-		tokens: chanTokens,
-		cleanup: func() {
-			file.Close()
-			close(chanTokens)
-		},
-	}, nil
+	l := newReaderLexer(file)
+	l.source = file
+	return l, nil
 }
 
-func isWhitespace(r rune) bool {
-	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+// newReaderLexer returns a lexer reading r, closing r is left to the caller
+func newReaderLexer(r io.Reader) *lexer {
+	l := &lexer{reader: bufio.NewReader(r)}
+	l.Reset(r)
+	return l
 }
 
-func isNumber(r rune) bool {
-	return '0' <= r && r <= '9'
+// newStringLexer returns a lexer reading the source code in src
+func newStringLexer(src string) *lexer {
+	return newReaderLexer(strings.NewReader(src))
 }
 
-func (l *lexer) enforceWhitespace(kind tokenKind) {
-	if !isWhitespace(l.next()) {
-		l.emit(
-			ERROR,
-			fmt.Sprintf("%s must be followed by a space and then '%s'", l.prevToken, kind),
-		)
-	}
+// Reset closes the current source and starts lexing r from the beginning.
+// The buffers of the lexer are reused
+func (l *lexer) Reset(r io.Reader) {
+	l.Close()
+	l.reader.Reset(r)
+	l.line, l.column = 1, 1
+	l.runes = l.runes[:0]
+	l.tokens = l.tokens[:0]
+	l.state = lexToken
+	l.prevToken, l.doc = "", ""
+	l.atEOF = false
 }
 
-// lexClass lexes class declarations and returns lexField state
-func lexClass(l *lexer) lexStateFn {
-	w := l.readWord()
-	if w == "" {
+// Close closes the source if the lexer opened it, any following call to nextToken returns EOF
+func (l *lexer) Close() error {
+	l.state = nil
+	if l.source == nil {
 		return nil
 	}
-	if l.isAccessModifier() {
-		l.enforceWhitespace(CLASS)
-		w = l.readWord()
-	}
-	if w != "class" {
-		l.emit(
-			ERROR,
-			"missing class declaration",
-			"class declarations can start with an access modifier (public, private or protected) followed by the 'class' keyword",
-		)
-	} else {
-		l.emit(CLASS)
-	}
-	l.enforceWhitespace(IDENTIFIER)
-	className := l.readWord()
-	l.emit(IDENTIFIER)
-	if className == "" {
-		l.emit(
-			ERROR,
-			"missing class name",
-			"an identifier must follow the 'class' keyword",
-		)
-	}
-	r := l.read()
-	if r != TOKEN_OBRACE {
-		l.emit(
-			ERROR,
-			fmt.Sprintf("expected '%c' found '%s'", TOKEN_OBRACE, l.readToken()),
-			"multiple identifiers are not supported",
-		)
-		// Try to recover by reading until the opening brace
-		l.readUntil(TOKEN_OBRACE)
-		l.emit(
-			ERROR,
-			"missing opening brace for class body",
-		)
-	}
-	l.emit(OBRACE)
-	return lexField
+	err := l.source.Close()
+	l.source = nil
+	return err
 }
 
-// lexField lexes fields inside a class
-// returns lexMethod
-func lexField(l *lexer) lexStateFn {
-	if l.read() == TOKEN_CBRACE {
-		l.emit(CBRACE)
-		return lexClass
-	}
-	l.readWord()
-	if l.isAccessModifier() {
-		l.enforceWhitespace(KEYWORD)
-		l.readWord()
-	}
-	if l.isFieldModifier() {
-		l.enforceWhitespace(KEYWORD)
-		l.readType()
-	}
-	l.enforceWhitespace(KEYWORD)
-	l.readToken()
-	l.emit(IDENTIFIER)
-
-	// Read access modifier, optional static or final modifier, read type, read identifier ...
-	// '(' indicates method
-	// ';' indicates declaration
-	// '=' indicates declaration with initialization
+// keywords maps every reserved word to its token kind.
+// The literals true, false and null are handled by keywordLiteral,
+// and contextual keywords such as var, yield and record are lexed as identifiers
+var keywords = map[string]tokenKind{
+	"abstract":     ABSTRACT,
+	"assert":       ASSERT,
+	"boolean":      BOOLEAN,
+	"break":        BREAK,
+	"byte":         BYTE,
+	"case":         CASE,
+	"catch":        CATCH,
+	"char":         CHAR,
+	"class":        CLASS,
+	"const":        CONST,
+	"continue":     CONTINUE,
+	"default":      DEFAULT,
+	"do":           DO,
+	"double":       DOUBLE,
+	"else":         ELSE,
+	"enum":         ENUM,
+	"extends":      EXTENDS,
+	"final":        FINAL,
+	"finally":      FINALLY,
+	"float":        FLOAT,
+	"for":          FOR,
+	"goto":         GOTO,
+	"if":           IF,
+	"implements":   IMPLEMENTS,
+	"import":       IMPORT,
+	"instanceof":   INSTANCEOF,
+	"int":          INT,
+	"interface":    INTERFACE,
+	"long":         LONG,
+	"native":       NATIVE,
+	"new":          NEW,
+	"package":      PACKAGE,
+	"private":      PRIVATE,
+	"protected":    PROTECTED,
+	"public":       PUBLIC,
+	"return":       RETURN,
+	"short":        SHORT,
+	"static":       STATIC,
+	"strictfp":     STRICTFP,
+	"super":        SUPER,
+	"switch":       SWITCH,
+	"synchronized": SYNCHRONIZED,
+	"this":         THIS,
+	"throw":        THROW,
+	"throws":       THROWS,
+	"transient":    TRANSIENT,
+	"try":          TRY,
+	"void":         VOID,
+	"volatile":     VOLATILE,
+	"while":        WHILE,
+}
 
-	r := l.read()
-	switch r {
-	case TOKEN_OPAREN:
-		l.emit(OPAREN)
-		return lexMethod
-	case TOKEN_SEMICOLON:
-		l.emit(SEMICOLON)
-	case '=':
-		l.emit(ASSIGN)
-		// TODO: handle field initialization
-	default:
-		l.emit(ERROR, "expected '(', ';', or '=' after identifier")
-	}
-	return lexField
+// operators maps separators and operators to their token kind.
+// They are lexed with maximal munch, the longest operator matching the input wins
+var operators = map[string]tokenKind{
+	"(":    OPAREN,
+	")":    CPAREN,
+	"{":    OBRACE,
+	"}":    CBRACE,
+	"[":    OBRACKET,
+	"]":    CBRACKET,
+	";":    SEMICOLON,
+	",":    COMMA,
+	".":    DOT,
+	"...":  ELLIPSIS,
+	"@":    AT,
+	"::":   DOUBLE_COLON,
+	"=":    ASSIGN,
+	">":    GT,
+	"<":    LT,
+	"!":    NOT,
+	"~":    BIT_NOT,
+	"?":    QUESTION,
+	":":    COLON,
+	"->":   ARROW,
+	"==":   EQUALS,
+	">=":   GT_EQUALS,
+	"<=":   LT_EQUALS,
+	"!=":   NOT_EQUALS,
+	"&&":   AND,
+	"||":   OR,
+	"++":   INCREMENT,
+	"--":   DECREMENT,
+	"+":    PLUS,
+	"-":    MINUS,
+	"*":    MULTIPLY,
+	"/":    DIVIDE,
+	"&":    BIT_AND,
+	"|":    BIT_OR,
+	"^":    BIT_XOR,
+	"%":    PERCENT,
+	"<<":   SHIFT_LEFT,
+	">>":   SHIFT_RIGHT,
+	">>>":  UNSIGNED_SHIFT_RIGHT,
+	"+=":   PLUS_ASSIGN,
+	"-=":   MINUS_ASSIGN,
+	"*=":   MULTIPLY_ASSIGN,
+	"/=":   DIVIDE_ASSIGN,
+	"&=":   BIT_AND_ASSIGN,
+	"|=":   BIT_OR_ASSIGN,
+	"^=":   BIT_XOR_ASSIGN,
+	"%=":   PERCENT_ASSIGN,
+	"<<=":  SHIFT_LEFT_ASSIGN,
+	">>=":  SHIFT_RIGHT_ASSIGN,
+	">>>=": UNSIGNED_SHIFT_RIGHT_ASSIGN,
 }
 
-// lexMethod lexes parameters inside method parentheses and returns lexMethodBody
-func lexMethod(l *lexer) lexStateFn {
-	for {
-		r := l.read()
-		if r == TOKEN_COMMA {
-			l.emit(COMMA)
-		}
-		if !l.readType() {
-			l.emit(
-				ERROR,
-				"invalid parameter type",
-				"expected a valid type in parameter list",
-			)
-		}
-		l.enforceWhitespace(PARAMETER)
-		l.readToken()
-		l.emit(PARAMETER)
-		r = l.read()
-		if r == TOKEN_COMMA {
-			continue
-		}
-		if r != TOKEN_CPAREN {
-			l.emit(
-				ERROR,
-				"missing closing parenthesis in parameter list",
-				"parameters must be separated by commas",
-				"end the list with a closing parenthesis",
-			)
-		}
-		// TODO: This is not robust, its fails if theres no whitespace between type and parameter name
-		l.emit(CPAREN)
-		l.enforceWhitespace(OBRACE)
-		r = l.read()
-		if r != TOKEN_OBRACE {
-			l.emit(
-				ERROR,
-				"missing opening brace for method body",
-			)
-		}
-		l.emit(OBRACE)
-		return lexMethodBody
-	}
+// maxOperatorLen is the length of the longest operator, >>>=
+const maxOperatorLen = 4
+
+func isWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == '\f'
 }
 
-func lexMethodBody(l *lexer) lexStateFn {
-	for {
-		switch r := l.read(); r {
-		case TOKEN_CBRACE:
-			l.emit(CBRACE)
-			return lexField
-		case TOKEN_OPAREN:
-			l.emit(OPAREN)
-			return lexMethodArguments
-		case '.':
-			l.emit(DOT)
-		case TOKEN_SEMICOLON:
-			l.emit(SEMICOLON)
-		case '+':
-			l.emit(PLUS)
-		case '-':
-			l.emit(MINUS)
-		case '*':
-			l.emit(MULTIPLY)
-		case '/':
-			l.emit(DIVIDE)
-		case '%':
-			l.emit(PERCENT)
-		case '=':
-			l.emit(ASSIGN)
-		case '!':
-			l.emit(NOT)
-		case '<':
-			l.emit(LT)
-		case '>':
-			l.emit(GT)
-		default:
-			if unicode.IsLetter(r) {
-				l.readWhile(unicode.IsLetter)
-				l.emit(IDENTIFIER)
-			}
-			if isNumber(r) {
-				l.readWhile(isNumber)
-				l.emit(LITERAL)
-			}
-		}
-	}
+func isNumber(r rune) bool {
+	return '0' <= r && r <= '9'
 }
 
-func (l *lexer) isAccessModifier() bool {
-	switch l.currToken() {
-	case "public":
-		l.emit(PUBLIC)
-	case "private":
-		l.emit(PRIVATE)
-	case "protected":
-		l.emit(PROTECTED)
-	}
-	return l.runesIsEmpty()
+func isIdentifierStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '$'
 }
 
-func (l *lexer) isFieldModifier() bool {
-	switch l.currToken() {
-	case "static":
-		l.emit(STATIC)
-	case "final":
-		l.emit(FINAL)
-	}
-	return l.runesIsEmpty()
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r)
 }
 
-func (l *lexer) readStringLiteral() {
-	l.nextUntil(TOKEN_QUOTE)
-	l.read() // consume closing quote
+// lexToken lexes the next token wherever it appears in the file.
+// Structure is left to the parser, so this is the only state
+func lexToken(l *lexer) lexStateFn {
+	l.skipTrivia()
+	r := l.next()
+	if r == eof {
+		return nil
+	}
+	l.runes = append(l.runes, r)
+	switch {
+	case r == TOKEN_QUOTE || r == TOKEN_APOSTROPHE:
+		l.lexQuoted(r)
+	case isNumber(r), r == TOKEN_DOT && isNumber(l.peek()):
+		l.lexNumber()
+	case isIdentifierStart(r):
+		l.readWhile(isIdentifierPart)
+		l.emitWord(IDENTIFIER)
+	default:
+		l.lexSymbol(r)
+	}
+	return lexToken
 }
 
-func lexMethodArguments(l *lexer) lexStateFn {
-	for {
-		switch r := l.read(); r {
-		case TOKEN_QUOTE:
-			l.readStringLiteral()
-		case TOKEN_COMMA:
-			// TODO: improve if statement, to check runes buffer for unexpected commas
-			// consider storing prevtoken as runes buffer
-			if l.prevToken != "" && l.prevToken != "," {
-				l.emit(COMMA)
-				l.enforceWhitespace(ARGUMENT)
-			} else {
-				l.emit(ERROR, "unexpected comma in argument list")
+// lexSymbol emits the longest separator or operator starting with r.
+// Operators are ASCII, so the reader is peeked by bytes to avoid consuming more than the operator
+func (l *lexer) lexSymbol(r rune) {
+	ahead, _ := l.reader.Peek(maxOperatorLen - 1)
+	for n := len(ahead); n >= 0; n-- {
+		if kind, ok := operators[string(r)+string(ahead[:n])]; ok {
+			for range n {
+				l.runes = append(l.runes, l.next())
 			}
-		case TOKEN_CPAREN:
-			l.emit(CPAREN)
-			return lexMethodBody
-		default:
-			l.readToken()
-			// l.emit(ERROR, fmt.Sprintf("unexpected token '%s' in argument list", l.readToken()))
+			l.emit(kind)
+			return
 		}
-		l.emit(ARGUMENT)
 	}
+	l.emit(ERROR, fmt.Sprintf("illegal character: '%c'", r))
 }
 
 // readWhile reads runes while the condition is true
@@ -301,132 +264,24 @@ func (l *lexer) readWhile(cond func(rune) bool) {
 	}
 }
 
-// nextUntil adds runes until the delimiter is found.
-// reads everything except the delimiter
-func (l *lexer) nextUntil(delim rune) {
-	l.until(l.next, delim)
-}
-
-// readUntil adds runes until the delimiter is found.
-// ignores spaces and quotes.
-// reads everything except the delimiter
-func (l *lexer) readUntil(delim rune) {
-	l.until(l.read, delim)
-}
-
-func (l *lexer) until(fn func() rune, delim rune) {
-	for r := fn(); r != delim; r = fn() {
-		l.runes = append(l.runes, r)
-	}
-	l.backup()
-}
-
-// readNumber reads a sequence of digits
-// func (l *lexer) readNumber() {
-// 	l.readWhile(unicode.IsDigit)
-// }
-
-// TODO: Write a more robust and strict switch statement
-func (l *lexer) readType() bool {
-	l.readWhile(func(r rune) bool {
-		return unicode.IsLetter(r) || r == '[' || r == ']' || r == '<' || r == '>' || r == '.'
-	})
-	return l.isType()
-}
-
-// readToken reads an alphanumeric token (letters, digits, underscores)
-func (l *lexer) readToken() string {
-	l.readWhile(func(r rune) bool {
-		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
-	})
-	return l.currToken()
-}
-
-func (l *lexer) readWord() string {
-	l.readWhile(unicode.IsLetter)
-	return l.currToken()
-}
-
 // currToken returns the current token as a string
 func (l *lexer) currToken() string {
 	return string(l.runes)
 }
 
-// contains returns true if the current token contains any of the provided runes
-// func (l *lexer) contains(runes ...rune) bool {
-// 	for _, r := range l.runes {
-// 		if slices.Contains(runes, r) {
-// 			return true
-// 		}
-// 	}
-// 	return false
-// }
-
-// isTokenGenericType returns true if the current token ends with '>'
-func (l *lexer) isGeneric() bool {
-	return l.runes[len(l.runes)-1] == '>'
-}
-
-func (l *lexer) runesIsEmpty() bool {
-	return len(l.runes) == 0
-}
-
-// TODO: isType emits PRIMITIVE or REFERENCE token if current token is a type
-// Strings with [ or ] are handled as standard types by removing the brackets before checking
-// TODO: Add robust handling for generics and arrays, and error reporting
-func (l *lexer) isType() bool {
-	if l.isGeneric() {
-		l.emit(NOT_SUPPORTED, "generic types are not supported")
-		return l.runesIsEmpty()
-	}
-	// Handle array types by removing brackets
-	switch strings.Split(l.currToken(), "[")[0] {
-	case "void":
-		l.emit(VOID)
-	case "boolean", "Boolean":
-		l.emit(BOOLEAN)
-	case "int", "Integer":
-		l.emit(INT)
-	case "float", "Float":
-		l.emit(FLOAT)
-	case "double", "Double":
-		l.emit(DOUBLE)
-	case "char", "Character":
-		l.emit(CHAR)
-	case "String":
-		l.emit(STRING)
-	default:
-		l.emit(NOT_SUPPORTED)
-	}
-	return l.runesIsEmpty()
-}
-
-// read returns the next non-whitespace rune.
-// whitespace and quotes are not added to runes buffer
-func (l *lexer) read() rune {
-	for {
-		r := l.next()
-		if isWhitespace(r) {
-			continue
-		}
-		if r != TOKEN_QUOTE {
-			l.runes = append(l.runes, r)
-		}
-		return r
-	}
-}
-
-// next returns the next rune from the reader.
+// next returns the next rune from the reader, or eof when the reader is exhausted.
 // no filtering of any kind is done here
 func (l *lexer) next() rune {
 	r, _, e := l.reader.ReadRune()
 	if e != nil {
-		if e == io.EOF {
-			l.emit(EOF)
-		} else {
+		if e != io.EOF {
 			panic(e)
 		}
+		l.atEOF = true
+		return eof
 	}
+	l.atEOF = false
+	l.last, l.lastColumn = r, l.column
 	if r == '\n' {
 		l.line++
 		l.column = 1
@@ -436,47 +291,61 @@ func (l *lexer) next() rune {
 	return r
 }
 
+// nextToken runs the state machine until it emits a token, and returns it.
+// EOF is returned once the source is exhausted or the lexer is closed
 func (l *lexer) nextToken() *token {
-	if !l.running {
-		l.running = true
-		go func() {
-			defer l.cleanup()
-			// Start the state machine
-			for l.state != nil {
-				l.state = l.state(l)
-			}
+	for len(l.tokens) == 0 {
+		if l.state == nil {
+			l.Close()
 			l.emit(EOF)
-		}()
+			break
+		}
+		l.state = l.state(l)
 	}
-	return <-l.tokens
+	t := l.tokens[0]
+	l.tokens = l.tokens[1:]
+	return t
 }
 
-// func (l *lexer) peek() rune {
-// 	r := l.next()
-// 	l.backup()
-// 	return r
-// }
+func (l *lexer) peek() rune {
+	r := l.next()
+	l.backup()
+	return r
+}
 
+// backup steps back over the rune returned by the last call to next
 func (l *lexer) backup() {
+	if l.atEOF {
+		l.atEOF = false
+		return
+	}
 	if err := l.reader.UnreadRune(); err != nil {
 		l.emit(CRITICAL, "unable to backup lexer reader")
 	}
-	// Handles backing up when rune is a newline
-	if l.column == 1 {
+	if l.last == '\n' {
 		l.line--
-	} else {
-		l.column--
 	}
+	l.column = l.lastColumn
 }
 
 func (l *lexer) emit(kind tokenKind, msgs ...string) {
-	t := &token{l.pos(), l.currToken(), kind}
+	t := &token{pos: l.pos(), value: l.currToken(), kind: kind}
 	if len(msgs) > 0 {
 		t.value += ": " + strings.Join(msgs, "\n\t- ")
 	}
+	l.send(t)
+}
+
+// emitLiteral emits a literal token carrying its decoded value
+func (l *lexer) emitLiteral(kind tokenKind, value any) {
+	l.send(&token{pos: l.pos(), value: l.currToken(), kind: kind, literal: value})
+}
+
+func (l *lexer) send(t *token) {
+	t.doc, l.doc = l.doc, ""
 	l.prevToken = t.value
-	l.runes = nil
-	l.tokens <- t
+	l.runes = l.runes[:0]
+	l.tokens = append(l.tokens, t)
 }
 
 func (l *lexer) pos() *pos {
@@ -1,23 +1,303 @@
 package parser
 
-//
-// import (
-// 	"path"
-// 	"testing"
-//
-// 	"github.com/JoachimTislov/lite-jnc/env"
-// )
-//
-// func TestLexer(t *testing.T) {
-// 	path := path.Join(env.Home(), "projects/lite-jnc/src/Main.javaa")
-// 	lexer, err := newLexer(path)
-// 	if err != nil {
-// 		t.Error(err)
-// 	}
-//
-// 	first := lexer.next()
-// 	second := lexer.next()
-// 	if first == second {
-// 		t.Error("First and second rune is the same, state is not working")
-// 	}
-// }
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lex returns the tokens of src, without the trailing EOF
+func lex(src string) []*token {
+	var tokens []*token
+	l := newStringLexer(src)
+	for t := l.nextToken(); t.kind != EOF; t = l.nextToken() {
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// lexOne returns the only token of src
+func lexOne(t *testing.T, src string) *token {
+	t.Helper()
+	tokens := lex(src)
+	if len(tokens) != 1 {
+		t.Fatalf("%s: expected 1 token, got %v", src, tokens)
+	}
+	return tokens[0]
+}
+
+func TestLexer(t *testing.T) {
+	src := `public class Main {
+  public static void main(String[] args) {
+    System.out.print("Hello World!");
+  }
+}`
+	want := []tokenKind{
+		PUBLIC, CLASS, IDENTIFIER, OBRACE,
+		PUBLIC, STATIC, VOID, IDENTIFIER, OPAREN, IDENTIFIER, OBRACKET, CBRACKET, IDENTIFIER, CPAREN, OBRACE,
+		IDENTIFIER, DOT, IDENTIFIER, DOT, IDENTIFIER, OPAREN, STRING_LITERAL, CPAREN, SEMICOLON,
+		CBRACE,
+		CBRACE,
+	}
+	tokens := lex(src)
+	if len(tokens) != len(want) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(want), len(tokens), tokens)
+	}
+	for i, tok := range tokens {
+		if tok.kind != want[i] {
+			t.Errorf("token %d: expected %s, got %s", i, want[i], tok.kind)
+		}
+	}
+	if print := tokens[19]; print.line != 3 || print.start != 16 || print.end != 20 {
+		t.Errorf("expected print at 3:16-20, got %s", print.pos)
+	}
+}
+
+func TestLexerReset(t *testing.T) {
+	l := newStringLexer("class A {}")
+	l.nextToken()
+	l.Reset(strings.NewReader("int"))
+	if tok := l.nextToken(); tok.kind != INT || tok.line != 1 || tok.start != 1 {
+		t.Errorf("expected int at 1:1 after reset, got %s", tok)
+	}
+	l.Close()
+	if tok := l.nextToken(); tok.kind != EOF {
+		t.Errorf("expected EOF after close, got %s", tok)
+	}
+}
+
+func TestLexerNumericLiterals(t *testing.T) {
+	tests := []struct {
+		src   string
+		kind  tokenKind
+		value any
+	}{
+		{"0", INT_LITERAL, int32(0)},
+		{"1_000_000", INT_LITERAL, int32(1000000)},
+		{"2147483647", INT_LITERAL, int32(2147483647)},
+		{"0x7fff_ffff", INT_LITERAL, int32(0x7fffffff)},
+		{"0xFFFFFFFF", INT_LITERAL, int32(-1)},
+		{"017", INT_LITERAL, int32(15)},
+		{"0_7", INT_LITERAL, int32(7)},
+		{"0b1010", INT_LITERAL, int32(10)},
+		{"9223372036854775807L", LONG_LITERAL, int64(9223372036854775807)},
+		{"0xFFFFFFFFFFFFFFFFl", LONG_LITERAL, int64(-1)},
+		{"1.5", DOUBLE_LITERAL, 1.5},
+		{".5e1", DOUBLE_LITERAL, 5.0},
+		{"1e-3d", DOUBLE_LITERAL, 0.001},
+		{"2.", DOUBLE_LITERAL, 2.0},
+		{"3f", FLOAT_LITERAL, float32(3)},
+		{"09.5", DOUBLE_LITERAL, 9.5},
+		{"0x1.8p1", DOUBLE_LITERAL, 3.0},
+		{"0x1p-1f", FLOAT_LITERAL, float32(0.5)},
+	}
+	for _, test := range tests {
+		tok := lexOne(t, test.src)
+		if tok.kind != test.kind || tok.literal != test.value {
+			t.Errorf("%s: expected %s %v, got %s %v", test.src, test.kind, test.value, tok.kind, tok.literal)
+		}
+	}
+}
+
+func TestLexerNumericLiteralErrors(t *testing.T) {
+	tests := map[string]string{
+		"3000000000":    "integer number too large",
+		"0x1_0000_0000": "integer number too large",
+		"1_":            "illegal underscore",
+		"0x_1":          "illegal underscore",
+		"1_.5":          "illegal underscore",
+		"0x":            "hexadecimal numbers must contain at least one hexadecimal digit",
+		"0b":            "binary numbers must contain at least one binary digit",
+		"09":            "illegal character in number: '9'",
+		"0b102":         "illegal character in number: '2'",
+		"1e":            "malformed floating-point literal",
+		"0x1.8":         "malformed floating-point literal",
+		"1e400":         "floating-point number too large",
+		"1e-50f":        "floating-point number too small",
+	}
+	// Malformed literals keep their kind, so the parser can carry on past them
+	for src, msg := range tests {
+		tok := lexOne(t, src)
+		if !tok.kind.isLiteral() || tok.err != msg {
+			t.Errorf("%s: expected error %q, got %s %q", src, msg, tok, tok.err)
+		}
+	}
+	// The minimum values decode without error, the parser only accepts them negated
+	for src, value := range map[string]any{"2147483648": int32(-2147483648), "9223372036854775808L": int64(-9223372036854775808)} {
+		if tok := lexOne(t, src); tok.err != "" || tok.literal != value || !tok.isMinimum() {
+			t.Errorf("%s: expected the minimum value, got %s %v", src, tok, tok.literal)
+		}
+	}
+}
+
+func TestLexerQuotedLiterals(t *testing.T) {
+	tests := []struct {
+		src   string
+		kind  tokenKind
+		value any
+	}{
+		{`"a\"b"`, STRING_LITERAL, `a"b`},
+		{`"tab\tnew\nline\\"`, STRING_LITERAL, "tab\tnew\nline\\"},
+		{`"\101\0\377"`, STRING_LITERAL, "A\x00\u00ff"},
+		{`"\u00e9\uuu0041"`, STRING_LITERAL, "éA"},
+		{`"\uD83D\uDE00"`, STRING_LITERAL, "😀"},
+		{`"😀"`, STRING_LITERAL, "😀"},
+		{`'c'`, CHAR_LITERAL, uint16('c')},
+		{`'\''`, CHAR_LITERAL, uint16('\'')},
+		{`'"'`, CHAR_LITERAL, uint16('"')},
+		{`'\u0041'`, CHAR_LITERAL, uint16('A')},
+		{`'\7'`, CHAR_LITERAL, uint16(7)},
+		{`true`, BOOLEAN_LITERAL, true},
+		{`false`, BOOLEAN_LITERAL, false},
+		{`null`, NULL_LITERAL, nil},
+	}
+	for _, test := range tests {
+		tok := lexOne(t, test.src)
+		if tok.kind != test.kind || tok.literal != test.value {
+			t.Errorf("%s: expected %s %q, got %s %q", test.src, test.kind, test.value, tok.kind, tok.literal)
+		}
+	}
+}
+
+func TestLexerQuotedLiteralErrors(t *testing.T) {
+	tests := map[string]string{
+		`"abc`:     "unclosed string literal",
+		"\"a\nb\"": "unclosed string literal",
+		`'ab'`:     "unclosed character literal",
+		`''`:       "empty character literal",
+		`'😀'`:      "unclosed character literal",
+		`"\q"`:     "illegal escape character",
+		`"\u12"`:   "illegal unicode escape",
+	}
+	for src, msg := range tests {
+		tokens := lex(src)
+		if len(tokens) == 0 || tokens[0].kind != ERROR || !strings.HasSuffix(tokens[0].value, msg) {
+			t.Errorf("%s: expected error %q, got %v", src, msg, tokens)
+		}
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	src := `// header
+/* block */
+/**
+ * Main is documented.
+ */
+class /* inline */ Main {} // trailing
+/**/ int`
+	tokens := lex(src)
+	if len(tokens) != 5 {
+		t.Fatalf("expected comments to be skipped, got %v", tokens)
+	}
+	if tokens[0].doc != "Main is documented." {
+		t.Errorf("expected Javadoc on class, got %q", tokens[0].doc)
+	}
+	if tokens[4].kind != INT || tokens[4].doc != "" {
+		t.Errorf("expected an empty block comment to not be Javadoc, got %s %q", tokens[4], tokens[4].doc)
+	}
+
+	tokens = lex("class Main {\n  /* unclosed\n}")
+	last := tokens[len(tokens)-1]
+	if last.kind != ERROR || last.line != 2 || last.start != 3 {
+		t.Errorf("expected unclosed comment error at 2:3, got %s", last)
+	}
+}
+
+func TestLexerOperators(t *testing.T) {
+	src := "a>>>=b>>=c>>>d>>e>=f>g ++ + += ... . :: : -> - -- -= &&& || |= ^= ~x?y:z !== <<= << <= < %= % /= *="
+	want := []tokenKind{
+		IDENTIFIER, UNSIGNED_SHIFT_RIGHT_ASSIGN, IDENTIFIER, SHIFT_RIGHT_ASSIGN, IDENTIFIER, UNSIGNED_SHIFT_RIGHT,
+		IDENTIFIER, SHIFT_RIGHT, IDENTIFIER, GT_EQUALS, IDENTIFIER, GT, IDENTIFIER,
+		INCREMENT, PLUS, PLUS_ASSIGN, ELLIPSIS, DOT, DOUBLE_COLON, COLON, ARROW, MINUS, DECREMENT, MINUS_ASSIGN,
+		AND, BIT_AND, OR, BIT_OR_ASSIGN, BIT_XOR_ASSIGN, BIT_NOT, IDENTIFIER, QUESTION, IDENTIFIER, COLON, IDENTIFIER,
+		NOT_EQUALS, ASSIGN, SHIFT_LEFT_ASSIGN, SHIFT_LEFT, LT_EQUALS, LT, PERCENT_ASSIGN, PERCENT, DIVIDE_ASSIGN, MULTIPLY_ASSIGN,
+	}
+	tokens := lex(src)
+	if len(tokens) != len(want) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(want), len(tokens), tokens)
+	}
+	for i, tok := range tokens {
+		if tok.kind != want[i] {
+			t.Errorf("token %d: expected %s, got %s (%s)", i, want[i], tok.kind, tok.value)
+		}
+	}
+	if tok := lex("x ..")[1]; tok.kind != DOT || tok.start != 3 || tok.end != 3 {
+		t.Errorf("expected '..' to lex as two periods, got %s", tok)
+	}
+}
+
+// generateJava returns a source file with n classes, mixing the tokens found in typical code
+func generateJava(n int) string {
+	var sb strings.Builder
+	for i := range n {
+		fmt.Fprintf(&sb, `/**
+ * Class%[1]d is generated.
+ */
+public class Class%[1]d {
+	private static final int LIMIT = %[1]d;
+	private double ratio = 0.5e-3;
+
+	// sum adds the numbers up to the limit
+	public static int sum(int from, String label) {
+		int total = 0x1F + from * 2 - 1_000;
+		System.out.print("label: \"" + label + "\"\n");
+		return total %% LIMIT;
+	}
+}
+
+`, i)
+	}
+	return sb.String()
+}
+
+// chanTokens runs l in a goroutine and pushes every token through an unbuffered channel, like the previous lexer did.
+// The tokens still come from the pull lexer, so the channel benchmark only measures the cost of the goroutine
+// and the channel, not the context-dependent state machine of the previous lexer
+func chanTokens(l *lexer) <-chan *token {
+	tokens := make(chan *token)
+	go func() {
+		defer close(tokens)
+		for {
+			t := l.nextToken()
+			tokens <- t
+			if t.kind == EOF {
+				return
+			}
+		}
+	}()
+	return tokens
+}
+
+// BenchmarkLexer compares pulling the tokens from the lexer with receiving them from chanTokens
+func BenchmarkLexer(b *testing.B) {
+	src := generateJava(2000)
+	b.Run("pull", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		for b.Loop() {
+			l := newStringLexer(src)
+			for t := l.nextToken(); t.kind != EOF; t = l.nextToken() {
+			}
+		}
+	})
+	b.Run("channel", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		for b.Loop() {
+			for t := range chanTokens(newStringLexer(src)) {
+				_ = t
+			}
+		}
+	})
+}
+
+func TestLexerAnnotations(t *testing.T) {
+	want := []tokenKind{AT, INTERFACE, IDENTIFIER, AT, IDENTIFIER, DOT, IDENTIFIER, DOT, IDENTIFIER, OPAREN, IDENTIFIER, ASSIGN, STRING_LITERAL, CPAREN}
+	tokens := lex(`@interface A @java.lang.SuppressWarnings(value = "all")`)
+	if len(tokens) != len(want) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(want), len(tokens), tokens)
+	}
+	for i, tok := range tokens {
+		if tok.kind != want[i] {
+			t.Errorf("token %d: expected %s, got %s (%s)", i, want[i], tok.kind, tok.value)
+		}
+	}
+}
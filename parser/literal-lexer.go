@@ -0,0 +1,368 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// isNumberPart reports whether r can continue a numeric literal.
+// Signs are handled separately since they are only valid after an exponent
+func isNumberPart(r rune) bool {
+	return isNumber(r) || unicode.IsLetter(r) || r == '_' || r == '.'
+}
+
+// lexNumber reads the rest of a numeric literal and emits it with its decoded value.
+// The first rune, digit or '.', must already be in the runes buffer
+func (l *lexer) lexNumber() {
+	dot := l.runes[0] == '.'
+	for {
+		r := l.next()
+		prev := l.runes[len(l.runes)-1]
+		switch {
+		case r == '.' && !dot:
+			dot = true
+		case (r == '+' || r == '-') && isExponent(prev, l.runes):
+		case r != '.' && isNumberPart(r):
+		default:
+			l.backup()
+			kind, value, err := parseNumber(l.currToken())
+			if err != "" {
+				// The literal is emitted anyway, so a malformed number doesn't derail the parser
+				l.send(&token{pos: l.pos(), value: l.currToken(), kind: kind, literal: zeroValue(kind), err: err})
+				return
+			}
+			l.emitLiteral(kind, value)
+			return
+		}
+		l.runes = append(l.runes, r)
+	}
+}
+
+// zeroValue returns the zero of the numeric literal kind
+func zeroValue(kind tokenKind) any {
+	switch kind {
+	case LONG_LITERAL:
+		return int64(0)
+	case FLOAT_LITERAL:
+		return float32(0)
+	case DOUBLE_LITERAL:
+		return 0.0
+	}
+	return int32(0)
+}
+
+// isMinimum reports whether t is the decimal literal 2147483648 or 9223372036854775808L, JLS 3.10.1.
+// They decode to the minimum values, and are only valid as the operand of unary minus
+func (t *token) isMinimum() bool {
+	switch {
+	case t.err != "" || !isDecimal(t.value):
+		return false
+	case t.kind == INT_LITERAL:
+		return t.literal == int32(math.MinInt32)
+	case t.kind == LONG_LITERAL:
+		return t.literal == int64(math.MinInt64)
+	}
+	return false
+}
+
+// isExponent reports whether prev marks the exponent of the literal in runes.
+// Hexadecimal literals use 'p' since 'e' is a hexadecimal digit
+func isExponent(prev rune, runes []rune) bool {
+	if isHex(string(runes)) {
+		return prev == 'p' || prev == 'P'
+	}
+	return prev == 'e' || prev == 'E'
+}
+
+func isHex(text string) bool {
+	return strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X")
+}
+
+func isBinary(text string) bool {
+	return strings.HasPrefix(text, "0b") || strings.HasPrefix(text, "0B")
+}
+
+func isDecimal(text string) bool {
+	return !isHex(text) && !isBinary(text) && (text == "0" || text[0] != '0')
+}
+
+// parseNumber decodes a Java numeric literal.
+// The value is an int32, int64, float32 or float64 matching the returned kind,
+// hexadecimal, octal and binary integers wrap around like they do in Java
+func parseNumber(text string) (kind tokenKind, value any, err string) {
+	if isHex(text) && !strings.ContainsAny(text, ".pP") || isBinary(text) {
+		return parseInteger(text[2:], text[1])
+	}
+	if isHex(text) || strings.ContainsAny(text, ".eEfFdD") {
+		return parseFloat(text)
+	}
+	return parseInteger(text, 0)
+}
+
+// parseInteger decodes digits in the radix given by prefix; 'x', 'b', or 0 for decimal and octal
+func parseInteger(digits string, prefix byte) (kind tokenKind, value any, err string) {
+	kind = INT_LITERAL
+	if strings.HasSuffix(digits, "l") || strings.HasSuffix(digits, "L") {
+		kind = LONG_LITERAL
+		digits = digits[:len(digits)-1]
+	}
+	radix := 10
+	switch prefix {
+	case 'x', 'X':
+		radix = 16
+		if digits == "" {
+			return kind, nil, "hexadecimal numbers must contain at least one hexadecimal digit"
+		}
+	case 'b', 'B':
+		radix = 2
+		if digits == "" {
+			return kind, nil, "binary numbers must contain at least one binary digit"
+		}
+	default:
+		if len(digits) > 1 && digits[0] == '0' {
+			radix = 8
+		}
+	}
+	if !validUnderscores(digits, radix) {
+		return kind, nil, "illegal underscore"
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+	for _, r := range digits {
+		if !isDigit(r, radix) {
+			return kind, nil, "illegal character in number: '" + string(r) + "'"
+		}
+	}
+	// Decimal literals may be one past the maximum value, to allow negating into the minimum value
+	n, e := strconv.ParseUint(digits, radix, 64)
+	if kind == INT_LITERAL {
+		limit := uint64(math.MaxUint32)
+		if radix == 10 {
+			limit = -math.MinInt32
+		}
+		if e != nil || n > limit {
+			return kind, nil, "integer number too large"
+		}
+		return kind, int32(uint32(n)), ""
+	}
+	if e != nil || radix == 10 && n > 1<<63 {
+		return kind, nil, "long number too large"
+	}
+	return kind, int64(n), ""
+}
+
+// parseFloat decodes decimal and hexadecimal floating-point literals
+func parseFloat(text string) (kind tokenKind, value any, err string) {
+	kind = DOUBLE_LITERAL
+	bits := 64
+	hex := isHex(text)
+	switch text[len(text)-1] {
+	case 'f', 'F':
+		kind, bits = FLOAT_LITERAL, 32
+		text = text[:len(text)-1]
+	case 'd', 'D':
+		text = text[:len(text)-1]
+	}
+	mantissa := text
+	if hex {
+		mantissa = text[2:]
+		i := strings.IndexAny(mantissa, "pP")
+		if i < 0 {
+			return kind, nil, "malformed floating-point literal"
+		}
+		mantissa = mantissa[:i]
+	} else if i := strings.IndexAny(text, "eE"); i >= 0 {
+		mantissa = text[:i]
+	}
+	radix := 10
+	if hex {
+		radix = 16
+	}
+	for part := range strings.SplitSeq(text, ".") {
+		if !validUnderscores(part, radix) {
+			return kind, nil, "illegal underscore"
+		}
+	}
+	text = strings.ReplaceAll(text, "_", "")
+	f, e := strconv.ParseFloat(text, bits)
+	if e != nil {
+		if ne, ok := e.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return kind, nil, "floating-point number too large"
+		}
+		return kind, nil, "malformed floating-point literal"
+	}
+	if f == 0 && strings.ContainsFunc(mantissa, func(r rune) bool { return r != '0' && isDigit(r, radix) }) {
+		return kind, nil, "floating-point number too small"
+	}
+	if kind == FLOAT_LITERAL {
+		return kind, float32(f), ""
+	}
+	return kind, f, ""
+}
+
+// validUnderscores reports whether every underscore in text sits between two digits of the radix
+func validUnderscores(text string, radix int) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '_' {
+			continue
+		}
+		if i == 0 || !isDigit(rune(text[i-1]), radix) {
+			return false
+		}
+		j := i
+		for j < len(text) && text[j] == '_' {
+			j++
+		}
+		if j == len(text) || !isDigit(rune(text[j]), radix) {
+			return false
+		}
+		i = j
+	}
+	return true
+}
+
+func isDigit(r rune, radix int) bool {
+	switch radix {
+	case 2:
+		return r == '0' || r == '1'
+	case 8:
+		return '0' <= r && r <= '7'
+	case 16:
+		return isNumber(r) || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+	default:
+		return isNumber(r)
+	}
+}
+
+// keywordLiteral returns the literal kind and value of the reserved literals true, false and null
+func keywordLiteral(word string) (kind tokenKind, value any, ok bool) {
+	switch word {
+	case "true":
+		return BOOLEAN_LITERAL, true, true
+	case "false":
+		return BOOLEAN_LITERAL, false, true
+	case "null":
+		return NULL_LITERAL, nil, true
+	}
+	return NOT_SUPPORTED, nil, false
+}
+
+// emitWord emits the word in the runes buffer as a keyword or literal if it is one, otherwise as kind
+func (l *lexer) emitWord(kind tokenKind) {
+	word := l.currToken()
+	if keyword, ok := keywords[word]; ok {
+		l.emit(keyword)
+	} else if lit, value, ok := keywordLiteral(word); ok {
+		l.emitLiteral(lit, value)
+	} else {
+		l.emit(kind)
+	}
+}
+
+// lexQuoted reads a string or character literal up to the closing quote,
+// and emits it with its escape sequences decoded. The opening quote must already be consumed.
+// Strings are decoded to Go strings and characters to a single UTF-16 code unit, like Java's char
+func (l *lexer) lexQuoted(quote rune) {
+	kind, name := STRING_LITERAL, "string"
+	if quote == TOKEN_APOSTROPHE {
+		kind, name = CHAR_LITERAL, "character"
+	}
+	l.runes = []rune{quote}
+	var units []uint16
+	invalid := ""
+	for {
+		r := l.next()
+		switch r {
+		case quote:
+			l.runes = append(l.runes, r)
+		case eof, '\n':
+			l.emit(ERROR, "unclosed "+name+" literal")
+			return
+		case '\\':
+			l.runes = append(l.runes, r)
+			unit, err := l.readEscape()
+			if err != "" && invalid == "" {
+				invalid = err
+			}
+			units = append(units, unit...)
+			continue
+		default:
+			l.runes = append(l.runes, r)
+			units = utf16.AppendRune(units, r)
+			continue
+		}
+		break
+	}
+	switch {
+	case invalid != "":
+		l.emit(ERROR, invalid)
+	case kind == STRING_LITERAL:
+		l.emitLiteral(kind, string(utf16.Decode(units)))
+	case len(units) == 0:
+		l.emit(ERROR, "empty character literal")
+	case len(units) > 1:
+		l.emit(ERROR, "unclosed character literal")
+	default:
+		l.emitLiteral(kind, units[0])
+	}
+}
+
+// readEscape reads the escape sequence following a backslash and returns its UTF-16 code units
+func (l *lexer) readEscape() ([]uint16, string) {
+	r := l.next()
+	l.runes = append(l.runes, r)
+	switch r {
+	case 'b':
+		return []uint16{'\b'}, ""
+	case 't':
+		return []uint16{'\t'}, ""
+	case 'n':
+		return []uint16{'\n'}, ""
+	case 'f':
+		return []uint16{'\f'}, ""
+	case 'r':
+		return []uint16{'\r'}, ""
+	case 's':
+		return []uint16{' '}, ""
+	case '"', '\'', '\\':
+		return []uint16{uint16(r)}, ""
+	case 'u':
+		// Any number of u's may follow the backslash
+		for l.peek() == 'u' {
+			l.runes = append(l.runes, l.next())
+		}
+		var unit uint16
+		for range 4 {
+			d := l.next()
+			if !isDigit(d, 16) {
+				l.backup()
+				return nil, "illegal unicode escape"
+			}
+			l.runes = append(l.runes, d)
+			v, _ := strconv.ParseUint(string(d), 16, 16)
+			unit = unit<<4 | uint16(v)
+		}
+		return []uint16{unit}, ""
+	}
+	if !isDigit(r, 8) {
+		if r == eof || r == '\n' {
+			l.backup()
+			l.runes = l.runes[:len(l.runes)-1]
+		}
+		return nil, "illegal escape character"
+	}
+	// Octal escapes are at most \377, the first digit decides if a third digit is allowed
+	unit := uint16(r - '0')
+	digits := 2
+	if r <= '3' {
+		digits = 3
+	}
+	for i := 1; i < digits && isDigit(l.peek(), 8); i++ {
+		d := l.next()
+		l.runes = append(l.runes, d)
+		unit = unit<<3 | uint16(d-'0')
+	}
+	return []uint16{unit}, ""
+}
@@ -0,0 +1,577 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// switchStrategy is how generated code dispatches on the selector of a switch
+type switchStrategy int
+
+const (
+	// compareChain tests the labels one after another, used for strings, enums and sparse labels
+	compareChain switchStrategy = iota
+	// jumpTable indexes a table of cases by the selector minus the lowest label
+	jumpTable
+)
+
+// switchLowering is the dispatch plan of a switch, see lower.
+// Cases are referred to by their index in the switch, and -1 means no case matches
+type switchLowering struct {
+	strategy switchStrategy
+	// keys are the integer labels in ascending order, only set when every label is an int or char constant
+	keys []switchKey
+	// table maps selector values from low to low+len(table)-1 to a case, only set for jumpTable
+	low   int64
+	table []int
+	// fallback is the default case, or -1 when the switch has no default
+	fallback int
+}
+
+// switchKey is an integer label and the case it selects
+type switchKey struct {
+	value int64
+	index int
+}
+
+// lower picks the dispatch strategy of the switch, the same way javac chooses between a tableswitch and a lookupswitch.
+// A jump table is used when its size and time cost don't exceed those of comparing the labels.
+// Labels naming constants are resolved in c, the class declaring the switch,
+// and the labels of a switch on an enum are replaced by the ordinals of their constants
+func (s *switchBlock) lower(c *class) *switchLowering {
+	l := &switchLowering{strategy: compareChain, fallback: -1}
+	for i, sc := range s.cases {
+		if sc.isDefault {
+			l.fallback = i
+		}
+		for _, label := range sc.labels {
+			value, ok := c.constant(label)
+			if s.enum != nil {
+				if e := s.enum.enumConstant(label); e != nil {
+					value, ok = int32(e.ordinal), true
+				}
+			}
+			if !ok || !isNumericConstant(value) || constantRank(value) > constantRank(int32(0)) {
+				l.keys = nil
+				return l
+			}
+			l.keys = append(l.keys, switchKey{toInt64(value), i})
+		}
+	}
+	if len(l.keys) == 0 {
+		return l
+	}
+	slices.SortFunc(l.keys, func(a, b switchKey) int {
+		return cmp.Compare(a.value, b.value)
+	})
+	low, high := l.keys[0].value, l.keys[len(l.keys)-1].value
+	n := int64(len(l.keys))
+	tableSpace, tableTime := 4+(high-low+1), int64(3)
+	chainSpace, chainTime := 3+2*n, n
+	if high-low >= math.MaxInt32 || tableSpace+3*tableTime > chainSpace+3*chainTime {
+		return l
+	}
+	l.strategy, l.low = jumpTable, low
+	l.table = make([]int, high-low+1)
+	for i := range l.table {
+		l.table[i] = l.fallback
+	}
+	for _, key := range l.keys {
+		l.table[key.value-low] = key.index
+	}
+	return l
+}
+
+// lowerConstructor returns the statements run by constructor m when an object of c is created, JLS 12.5.
+// Unless m delegates to another constructor with this(...), the superclass constructor runs first,
+// followed by the instance field initializers and instance initializers in declaration order.
+// The compact and implicit canonical constructors of a record assign the components to the fields after the body.
+// The synthetic fields of an inner class are assigned from the leading parameters before anything else runs,
+// so the superclass constructor can already use them
+func (c *class) lowerConstructor(m *method) []Statement {
+	var synthetic []Statement
+	for _, f := range c.syntheticFields() {
+		synthetic = append(synthetic, c.assignField(node{f.name, m.pos}, &identifier{expression: expression{node{f.name, m.pos}}}))
+	}
+	call := m.constructorCall()
+	if call != nil && call.name == "this" {
+		return append(synthetic, m.statements...)
+	}
+	body := m.statements
+	if call == nil {
+		call = &constructorCall{expression: expression{node{"super", m.pos}}}
+		if c.superclass != nil {
+			i := slices.IndexFunc(c.superclass.constructors(), func(ctor *method) bool { return len(ctor.parameters) == 0 })
+			if i >= 0 {
+				call.constructor = c.superclass.constructors()[i]
+			}
+		}
+	} else {
+		body = body[1:]
+	}
+	stmts := append(synthetic, &expressionStmt{statement: statement{call.node}, expr: call})
+	stmts = append(stmts, c.lowerInitializers(false)...)
+	stmts = append(stmts, body...)
+	if c.isRecord && (m.compact || m.implicit) {
+		for _, param := range m.parameters {
+			stmts = append(stmts, c.assignField(param.name, &identifier{expression: expression{param.name}}))
+		}
+	}
+	return stmts
+}
+
+// lowerInitializers returns the field initializers and initializer blocks of c that are static or not, interleaved
+// in declaration order, JLS 12.4.2 and 12.5. Each block becomes a nested block, keeping the scope of its locals
+func (c *class) lowerInitializers(static bool) []Statement {
+	var stmts []Statement
+	blocks := c.blocks
+	// addBlocks adds the blocks declared before the field at index i
+	addBlocks := func(i int) {
+		for ; len(blocks) > 0 && blocks[0].index <= i; blocks = blocks[1:] {
+			if b := blocks[0]; b.isStatic == static {
+				stmts = append(stmts, &block{statement: statement{b.node}, statements: b.statements})
+			}
+		}
+	}
+	fields := c.initializers(static)
+	for i, f := range c.fields {
+		addBlocks(i)
+		switch {
+		case !slices.Contains(fields, f):
+		case static:
+			target := &fieldAccess{expression: expression{f.node}, target: &identifier{expression: expression{node{c.binaryName(), f.pos}}}}
+			init := &assignment{expression: expression{node{"=", f.pos}}, op: ASSIGN, target: target, value: f.value}
+			stmts = append(stmts, &expressionStmt{statement: statement{init.node}, expr: init})
+		default:
+			stmts = append(stmts, c.assignField(f.node, f.value))
+		}
+	}
+	addBlocks(len(c.fields))
+	return stmts
+}
+
+// classInit initializes class unless it is initialized or being initialized, JLS 12.4.2.
+// Generated code keeps the state of each class: the first classInit of a class marks it as being initialized
+// and runs its clinit method, the classInits running after that, including the recursive ones from clinit, do nothing
+type classInit struct {
+	statement
+	class *class
+}
+
+// clinit returns the class initialization method of c, JLS 12.4.2. It initializes the superclass first,
+// then creates the enum constants and runs the static field initializers and static initializers in declaration order.
+// Constants aren't initialized, their value is inlined where they are used
+func (c *class) clinit() *method {
+	pos := c.pos
+	var stmts []Statement
+	if c.superclass != nil && !c.isInterface {
+		stmts = append(stmts, &classInit{statement: statement{node{"init", pos}}, class: c.superclass})
+	}
+	for _, e := range c.constants {
+		kind := classType(c.binaryName(), e.pos)
+		if e.body != nil {
+			kind = classType(e.body.binaryName(), e.pos)
+		}
+		value := &newObject{expression: expression{node{"new", e.pos}}, kind: kind, args: e.args, constructor: e.constructor}
+		target := &fieldAccess{expression: expression{e.node}, target: &identifier{expression: expression{node{c.binaryName(), e.pos}}}}
+		init := &assignment{expression: expression{node{"=", e.pos}}, op: ASSIGN, target: target, value: value}
+		stmts = append(stmts, &expressionStmt{statement: statement{init.node}, expr: init})
+	}
+	stmts = append(stmts, c.lowerInitializers(true)...)
+	return &method{
+		decl: &decl{
+			node:      node{"<clinit>", pos},
+			kind:      primitiveType(VOID, pos),
+			modifiers: modifiers{visibility: PRIVATE, isStatic: true},
+		},
+		body:     body{statements: stmts},
+		implicit: true,
+	}
+}
+
+// lowerInit returns the class initialization generated code runs before n in the body of c, or nil when n doesn't
+// initialize a class, JLS 12.4.1. Creating an instance, calling a static method and using a static field that isn't
+// a constant initialize the class declaring them. c and its superclasses are initialized already while c runs.
+// Only the classes of f are known
+func (c *class) lowerInit(f *file, n Node) *classInit {
+	var target *class
+	switch n := n.(type) {
+	case *newObject:
+		if target = n.body; target == nil {
+			target = f.class(n.kind.name)
+		}
+	case *call:
+		if n.method != nil && n.method.isStatic {
+			target = f.declaring(n.method.decl)
+		}
+	case *identifier:
+		if n.outer != nil {
+			target = f.staticField(n.outer.inheritedField(n.name))
+		}
+	case *fieldAccess:
+		if id, ok := n.target.(*identifier); ok && id.local == nil && id.outer == nil && f.class(id.name) != nil {
+			target = f.staticField(f.class(id.name).inheritedField(n.name))
+		}
+	}
+	if target == nil {
+		return nil
+	}
+	for s := c; s != nil; s = s.superclass {
+		if s == target {
+			return nil
+		}
+	}
+	return &classInit{statement: statement{node{"init", n.Position()}}, class: target}
+}
+
+// staticField returns the class declaring fld when it is a static field that isn't a constant, or nil
+func (f *file) staticField(fld *field) *class {
+	if fld == nil || !fld.isStatic || fld.constant != nil {
+		return nil
+	}
+	return f.declaring(fld.decl)
+}
+
+// declaring returns the class of f declaring the field or method d, or nil
+func (f *file) declaring(d *decl) *class {
+	for _, c := range f.classes {
+		for _, fld := range c.fields {
+			if fld.decl == d {
+				return c
+			}
+		}
+		for _, m := range c.methods {
+			if m.decl == d {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// assignField returns the statement this.name = value
+func (c *class) assignField(name node, value Expression) Statement {
+	target := &fieldAccess{expression: expression{name}, target: &thisExpr{expression: expression{node{"this", name.pos}}, class: c}}
+	init := &assignment{expression: expression{node{"=", name.pos}}, op: ASSIGN, target: target, value: value}
+	return &expressionStmt{statement: statement{init.node}, expr: init}
+}
+
+// syntheticFields returns the fields javac adds to a nested class, JLS 8.1.3. An inner class holds its enclosing
+// instance in this$0, and a local or anonymous class holds the locals it captures in val$ fields.
+// Its constructors take their values as leading parameters, in the same order
+func (c *class) syntheticFields() []*field {
+	var fields []*field
+	add := func(name string, kind *typeRef) {
+		fields = append(fields, &field{decl: &decl{node: node{name, c.pos}, kind: kind, isFinal: true, modifiers: modifiers{visibility: PACKAGE}}})
+	}
+	if c.inner {
+		add("this$0", classType(c.outer.binaryName(), c.pos))
+	}
+	for _, v := range c.captured {
+		add("val$"+v.name, v.kind.erasure())
+	}
+	return fields
+}
+
+// outerInstance returns the expression evaluating to the instance of the enclosing class o in the body of c,
+// following the this$0 fields of the inner classes in between, like this.this$0.this$0
+func (c *class) outerInstance(o *class, pos *pos) Expression {
+	var e Expression = &thisExpr{expression: expression{node{"this", pos}}, class: c}
+	for ; c != o; c = c.outer {
+		e = &fieldAccess{expression: expression{node{"this$0", pos}}, target: e}
+	}
+	return e
+}
+
+// vtable returns the instance methods of c that are dispatched dynamically, indexed by slot.
+// A class keeps the slots of its superclass, an overriding method takes the slot of the method it overrides
+// and new methods are appended, followed by the methods inherited from interfaces that no class declares.
+// The vtable of an interface lists its own and inherited abstract and default methods.
+// Only the classes of the file are known, so the methods of Object are left out
+func (c *class) vtable() []*method {
+	var table []*method
+	if c.superclass != nil {
+		table = slices.Clone(c.superclass.vtable())
+	}
+	add := func(m *method) {
+		if m.isStatic || m.isConstructor || m.visibility == PRIVATE {
+			return
+		}
+		sig := m.signature()
+		if i := slices.IndexFunc(table, func(o *method) bool { return o.signature() == sig }); i >= 0 {
+			table[i] = m
+		} else {
+			table = append(table, m)
+		}
+	}
+	for _, m := range c.methods {
+		add(m)
+	}
+	for _, i := range c.interfaces {
+		for _, m := range i.vtable() {
+			if impl, _ := c.lookupMethod(m.signature()); impl != nil && !slices.Contains(table, impl) {
+				add(impl)
+			}
+		}
+	}
+	return table
+}
+
+// itable returns the methods of c implementing the vtable slots of the interface i,
+// used to dispatch calls through a receiver of type i. Slots c doesn't implement are nil
+func (c *class) itable(i *class) []*method {
+	slots := i.vtable()
+	table := make([]*method, len(slots))
+	for n, m := range slots {
+		table[n], _ = c.lookupMethod(m.signature())
+	}
+	return table
+}
+
+// isVirtual reports whether c is dispatched through the vtable of the receiver.
+// Calls to static and private methods and through super are bound to the resolved method
+func (c *call) isVirtual() bool {
+	if c.method == nil || c.method.isStatic || c.method.visibility == PRIVATE {
+		return false
+	}
+	_, isSuper := c.target.(*superExpr)
+	return !isSuper
+}
+
+// boundsCheck is the check generated code runs before an array access, which throws an
+// ArrayIndexOutOfBoundsException with a message formatted from outOfBounds unless 0 <= index < array.length, JLS 15.10.4
+type boundsCheck struct {
+	array, index Expression
+}
+
+// outOfBounds is the message of the exception thrown by a failed bounds check, formatted with the index and the length
+const outOfBounds = "Index %d out of bounds for length %d"
+
+// lowerIndex returns the bounds check of a, or nil when the index is a constant known to be in bounds.
+// The length is known for the arrays created in place and the final locals initialized with them
+func (c *class) lowerIndex(a *arrayIndex) *boundsCheck {
+	index, ok := c.constant(a.index)
+	if length, known := c.arrayLength(a.array); ok && known && isNumericConstant(index) {
+		if i := toInt64(index); i >= 0 && i < length {
+			return nil
+		}
+	}
+	return &boundsCheck{array: a.array, index: a.index}
+}
+
+// arrayLength returns the length of the array e evaluates to, when it is known at compile time
+func (c *class) arrayLength(e Expression) (int64, bool) {
+	switch e := e.(type) {
+	case *arrayInit:
+		return int64(len(e.elements)), true
+	case *newArray:
+		if e.init != nil {
+			return int64(len(e.init.elements)), true
+		}
+		if length, ok := c.constant(e.dims[0]); ok && isNumericConstant(length) {
+			return toInt64(length), true
+		}
+	case *identifier:
+		if v := e.local; v != nil && v.isFinal && v.value != nil {
+			return c.arrayLength(v.value)
+		}
+	}
+	return 0, false
+}
+
+// lowerResources translates a try-with-resources statement into try statements without resources, JLS 14.20.3.
+// Each resource gets a finally block closing it, and an exception thrown by close() after the block threw
+// is added to the suppressed exceptions of the primary exception instead of replacing it.
+// The catch clauses and finally block of s apply to the whole translation
+func (s *tryStmt) lowerResources() Statement {
+	lowered := closeResources(s.resources, s.body)
+	if len(s.catches) == 0 && s.finally == nil {
+		return lowered
+	}
+	return &tryStmt{statement: s.statement, body: lowered, catches: s.catches, finally: s.finally}
+}
+
+// closeResources returns body wrapped in the statements closing resources, the first resource outermost.
+// For a resource r, that is
+//
+//	{ final R r = init; Throwable #primaryExc = null;
+//	  try body catch (Throwable #t) { #primaryExc = #t; throw #t; }
+//	  finally { if (r != null) { if (#primaryExc != null) { try { r.close(); } catch (Throwable #suppressedExc) {
+//	  #primaryExc.addSuppressed(#suppressedExc); } } else { r.close(); } } } }
+func closeResources(resources []Statement, body *block) *block {
+	if len(resources) == 0 {
+		return body
+	}
+	r := resources[0]
+	pos := r.Position()
+	n := func(name string) node { return node{name, pos} }
+	var stmts []Statement
+	var ref Expression
+	switch r := r.(type) {
+	case *localVars:
+		stmts = append(stmts, r)
+		ref = &identifier{expression: expression{r.vars[0].node}, local: r.vars[0]}
+	case *expressionStmt:
+		ref = r.expr
+	}
+	local := func(name string, value Expression) *localVar {
+		return &localVar{node: n(name), kind: classType("Throwable", pos), value: value}
+	}
+	use := func(v *localVar) Expression { return &identifier{expression: expression{v.node}, local: v} }
+	exprStmt := func(e Expression) Statement { return &expressionStmt{statement: statement{n(e.Name())}, expr: e} }
+	null := &literal{expression: expression{n("null")}, kind: NULL_LITERAL}
+	notNull := func(e Expression) Expression {
+		return &binary{expression: expression{n("!=")}, op: NOT_EQUALS, left: e, right: null}
+	}
+	block := func(stmts ...Statement) *block { return &block{statement: statement{n("{")}, statements: stmts} }
+	closeCall := func() Statement { return exprStmt(&call{expression: expression{n("close")}, target: ref}) }
+
+	primary := local("#primaryExc", null)
+	stmts = append(stmts, &localVars{statement: statement{n("Throwable")}, kind: primary.kind, vars: []*localVar{primary}})
+	t := local("#t", nil)
+	rethrow := &catchClause{node: n("catch"), kinds: []*typeRef{t.kind}, param: t, body: block(
+		exprStmt(&assignment{expression: expression{n("=")}, op: ASSIGN, target: use(primary), value: use(t)}),
+		&throwStmt{statement: statement{n("throw")}, value: use(t)},
+	)}
+	suppressed := local("#suppressedExc", nil)
+	addSuppressed := &call{expression: expression{n("addSuppressed")}, target: use(primary), args: []Expression{use(suppressed)}}
+	closeSuppressed := &tryStmt{statement: statement{n("try")}, body: block(closeCall()), catches: []*catchClause{
+		{node: n("catch"), kinds: []*typeRef{suppressed.kind}, param: suppressed, body: block(exprStmt(addSuppressed))},
+	}}
+	finally := block(&ifStmt{statement: statement{n("if")}, cond: notNull(ref), then: block(
+		&ifStmt{statement: statement{n("if")}, cond: notNull(use(primary)), then: block(closeSuppressed), otherwise: block(closeCall())},
+	)})
+	inner := closeResources(resources[1:], body)
+	stmts = append(stmts, &tryStmt{statement: statement{n("try")}, body: inner, catches: []*catchClause{rethrow}, finally: finally})
+	return block(stmts...)
+}
+
+// lowerLambda translates the index-th lambda l of c, declared in method m, the way javac does, JLS 15.27.4.
+// The body becomes the private method lambda$m$index of c, taking the captured locals followed by the parameters of l.
+// The lambda becomes the creation of a synthetic class implementing its functional interface, whose fields hold
+// the captured values and the enclosing instance, and whose function calls the body method with them
+func (c *class) lowerLambda(l *lambda, m *method, index int) (*method, *class, Expression) {
+	pos := l.pos
+	n := func(name string) node { return node{name, pos} }
+	use := func(name string) *identifier { return &identifier{expression: expression{n(name)}} }
+	result := l.function.kind.erasure()
+	void := result.kind == VOID
+	var params []*parameter
+	for _, v := range append(slices.Clone(l.captured), l.params...) {
+		params = append(params, &parameter{name: v.node, kind: v.kind.erasure()})
+	}
+	stmts := l.statements()
+	if expr, ok := l.body.(Expression); ok && !void {
+		stmts = []Statement{&returnStmt{statement: statement{n("return")}, value: expr}}
+	}
+	lifted := &method{
+		decl: &decl{
+			node:      n(fmt.Sprintf("lambda$%s$%d", m.name, index)),
+			kind:      result,
+			modifiers: modifiers{visibility: PRIVATE, isStatic: m.isStatic},
+		},
+		parameters: params,
+		body:       body{statements: stmts},
+		implicit:   true,
+	}
+
+	impl := &class{node: n(fmt.Sprintf("%s$$Lambda$%d", c.binaryName(), index)), isFinal: true, implements: []*typeRef{l.kind.erasure()}}
+	// The constructor takes the enclosing instance, unless m is static, and the captured values
+	fields := params[:len(l.captured)]
+	if !m.isStatic {
+		fields = append([]*parameter{{name: n("this$0"), kind: classType(c.binaryName(), pos)}}, fields...)
+	}
+	ctor := &method{decl: &decl{node: n(impl.name), kind: primitiveType(VOID, pos), isConstructor: true}, implicit: true}
+	var args []Expression
+	for _, f := range fields {
+		impl.fields = append(impl.fields, &field{decl: &decl{node: f.name, kind: f.kind, isFinal: true, modifiers: modifiers{visibility: PACKAGE}}})
+		ctor.parameters = append(ctor.parameters, f)
+		ctor.statements = append(ctor.statements, impl.assignField(f.name, use(f.name.name)))
+		args = append(args, use(f.name.name))
+	}
+	if !m.isStatic {
+		args[0] = &thisExpr{expression: expression{n("this")}, class: c}
+	}
+
+	var target Expression = use(c.binaryName())
+	if !m.isStatic {
+		target = &fieldAccess{expression: expression{n("this$0")}, target: &thisExpr{expression: expression{n("this")}, class: impl}}
+	}
+	forward := &call{expression: expression{lifted.node}, target: target, method: lifted}
+	for _, f := range params[:len(l.captured)] {
+		forward.args = append(forward.args, &fieldAccess{expression: expression{f.name}, target: &thisExpr{expression: expression{n("this")}, class: impl}})
+	}
+	function := &method{
+		decl: &decl{
+			node:      n(l.function.name),
+			kind:      l.function.kind.erasure(),
+			modifiers: modifiers{visibility: PUBLIC},
+		},
+		implicit: true,
+	}
+	for i, param := range l.function.parameters {
+		name := fmt.Sprintf("a%d", i)
+		function.parameters = append(function.parameters, &parameter{name: n(name), kind: param.kind.erasure()})
+		forward.args = append(forward.args, use(name))
+	}
+	if void {
+		function.statements = []Statement{&expressionStmt{statement: statement{forward.node}, expr: forward}}
+	} else {
+		function.statements = []Statement{&returnStmt{statement: statement{n("return")}, value: forward}}
+	}
+	impl.methods = []*method{ctor, function}
+	return lifted, impl, &newObject{expression: expression{n("new")}, kind: classType(impl.name, pos), args: args, constructor: ctor}
+}
+
+// lambda returns the lambda expression calling the method or constructor r refers to, with the parameters of its function.
+// A type name followed by an instance method calls it on the first parameter, like (a0, a1) -> a0.compareTo(a1).
+// A local target is captured, so it is read when the lambda is created
+func (r *methodRef) lambda() *lambda {
+	pos := r.pos
+	l := &lambda{expression: expression{node{"->", pos}}, kind: r.kind, function: r.function}
+	var args []Expression
+	for i, param := range r.function.parameters {
+		v := &localVar{node: node{fmt.Sprintf("a%d", i), pos}, kind: param.kind, inferred: true}
+		l.params = append(l.params, v)
+		args = append(args, &identifier{expression: expression{v.node}, local: v})
+	}
+	if r.name == "new" {
+		l.body = &newObject{expression: expression{node{"new", pos}}, kind: classType(fmt.Sprint(r.target), pos), args: args, constructor: r.method}
+		return l
+	}
+	target := r.target
+	unbound := r.method != nil && !r.method.isStatic && len(args) == len(r.method.parameters)+1
+	switch t := target.(type) {
+	case *identifier:
+		if t.local != nil {
+			l.captured = append(l.captured, t.local)
+		} else if unbound {
+			target, args = args[0], args[1:]
+		}
+	case *fieldAccess:
+		if unbound {
+			target, args = args[0], args[1:]
+		}
+	}
+	l.body = &call{expression: expression{node{r.name, pos}}, target: target, args: args, method: r.method}
+	return l
+}
+
+// Unlowered returns an error for each class of a using a construct that no backend lowers yet: enums,
+// and the class initialization of classes with static field initializers or static initializers
+func (a *AST) Unlowered() []error {
+	var errs []error
+	for _, f := range a.files {
+		for _, c := range f.classes {
+			switch {
+			case c.isEnum:
+				errs = append(errs, fmt.Errorf("%s:%s: enum %s is not supported by the backends yet", f.path, c.pos, c.name))
+			case len(c.lowerInitializers(true)) > 0:
+				errs = append(errs, fmt.Errorf("%s:%s: the initialization of class %s is not supported by the backends yet", f.path, c.pos, c.name))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,359 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// parseMemberClass parses a class declared in the body of p.class, following its modifiers, JLS 8.5.
+// Member interfaces, enums and records, and the member classes of interfaces, are implicitly static
+func (p *Parser) parseMemberClass(doc string, mods modifiers, isFinal bool) parseStateFn {
+	outer := p.class
+	if outer.isInterface {
+		mods.visibility, mods.isStatic = PUBLIC, true
+	}
+	c := p.parseClassHeader(outer, doc, mods, isFinal)
+	if c == nil {
+		return nil
+	}
+	c.isStatic = c.isStatic || c.isInterface || c.isEnum || c.isRecord
+	c.inner = !c.isStatic
+	if !p.parseClassBody(c) {
+		return nil
+	}
+	outer.classes = append(outer.classes, c)
+	return parseDeclaration
+}
+
+// isLocalClass reports whether a local class declaration starts at peekToken, possibly after modifiers
+func (p *Parser) isLocalClass() bool {
+	n := p.scanAnnotations(1)
+	for p.peekAt(n).kind.isModifier() {
+		n = p.scanAnnotations(n + 1)
+	}
+	switch p.peekAt(n).kind {
+	case CLASS, INTERFACE, ENUM:
+		return true
+	}
+	return p.peekAt(n).value == "record" && p.peekAt(n+1).kind == IDENTIFIER
+}
+
+// parseLocalClass parses a class declared in a block, JLS 14.3. It is in scope from its declaration to the end
+// of the block, and has an enclosing instance unless it is declared in a static context
+func (p *Parser) parseLocalClass() Statement {
+	doc := p.peekToken.doc
+	mods, isFinal := p.parseModifiers()
+	if mods.visibility != PACKAGE {
+		p.errorf("modifier %s not allowed here", mods.visibility)
+	} else if mods.isStatic {
+		p.errorf("modifier static not allowed here")
+	}
+	c := p.parseClassHeader(p.class, doc, mods, isFinal)
+	if c == nil {
+		return nil
+	}
+	c.local = 1
+	for _, other := range p.file.classes {
+		if other.outer == c.outer && other.local > 0 && other.name == c.name {
+			c.local++
+		}
+	}
+	c.inner = !p.decl.isStatic && !c.isInterface && !c.isEnum && !c.isRecord
+	if p.scope.class(c.name) != nil {
+		p.errorAt(c.pos, "duplicate class: %s", c.name)
+	}
+	p.declareClass(c)
+	if !p.parseClassBody(c) {
+		return nil
+	}
+	c.captureLocals(p.scope)
+	return &localClass{statement: statement{c.node}, class: c}
+}
+
+// parseAnonymousClass parses the body of the anonymous class created by n, following its opening brace, JLS 15.9.5.
+// javac numbers the anonymous classes of a class, like Outer$1
+func (p *Parser) parseAnonymousClass(n *newObject) bool {
+	index := 1
+	for _, other := range p.file.classes {
+		if other.outer == p.class && other.isAnonymous {
+			index++
+		}
+	}
+	n.body = &class{
+		node:        node{fmt.Sprintf("%s$%d", p.class.binaryName(), index), p.token.pos},
+		isAnonymous: true,
+		outer:       p.class,
+		inner:       p.decl != nil && !p.decl.isStatic,
+		extends:     n.kind,
+	}
+	if !p.parseClassBody(n.body) {
+		return false
+	}
+	n.body.captureLocals(p.scope)
+	return true
+}
+
+// parseQualifiedThis parses Outer.this, following this. left names the enclosing class, JLS 15.8.4
+func (p *Parser) parseQualifiedThis(left Expression) Expression {
+	t := p.token
+	id, ok := left.(*identifier)
+	if !ok {
+		p.errorAt(left.Position(), "not an enclosing class: %s", left)
+		return nil
+	}
+	for c, static := p.class, p.decl != nil && p.decl.isStatic; c != nil; c = c.outer {
+		if c.name != id.name {
+			static = static || !c.inner
+			continue
+		}
+		if static {
+			p.errorAt(id.pos, "non-static variable this cannot be referenced from a static context")
+		}
+		return &thisExpr{expression: expression{node{id.name + ".this", t.pos}}, class: c}
+	}
+	p.errorAt(id.pos, "not an enclosing class: %s", id.name)
+	return nil
+}
+
+// parseQualifiedSuper parses TypeName.super following left, the type name, JLS 15.11.2 and 15.12.1.
+// The name is an enclosing class, or else a direct superinterface, which is resolved once the classes are linked
+func (p *Parser) parseQualifiedSuper(left Expression) Expression {
+	t := p.token
+	id, ok := left.(*identifier)
+	if !ok {
+		p.errorAt(left.Position(), "not an enclosing class: %s", left)
+		return nil
+	}
+	if p.peekToken.kind != DOT && p.peekToken.kind != DOUBLE_COLON {
+		p.errorAt(p.peekToken.pos, "expected %s, got %s", DOT, p.peekToken.kind)
+	}
+	e := &superExpr{expression: expression{node{id.name + ".super", t.pos}}, class: p.class}
+	for c := p.class; c != nil; c = c.outer {
+		if c.name == id.name {
+			if this, ok := p.parseQualifiedThis(left).(*thisExpr); ok {
+				e.class = this.class
+			}
+			return e
+		}
+	}
+	if p.decl != nil && p.decl.isStatic {
+		p.errorAt(t.pos, "non-static variable super cannot be referenced from a static context")
+	}
+	e.iface = classType(id.name, id.pos)
+	return e
+}
+
+// owner returns the class whose members s refers to, or nil when it isn't in the source root.
+// The interface of I.super must be a direct superinterface of the class, JLS 15.12.3
+func (s *superExpr) owner(f *file) *class {
+	if s.iface == nil {
+		return s.class.superclass
+	}
+	i := f.class(s.iface.name)
+	if i != nil && !slices.Contains(s.class.interfaces, i) {
+		return nil
+	}
+	return i
+}
+
+// declareClass adds the local class c to the current scope
+func (p *Parser) declareClass(c *class) {
+	if p.scope == nil {
+		p.scope = &scope{}
+	}
+	if p.scope.classes == nil {
+		p.scope.classes = map[string]*class{}
+	}
+	p.scope.classes[c.name] = c
+}
+
+// class returns the local class named name in scope, or nil if there is none
+func (s *scope) class(name string) *class {
+	for ; s != nil; s = s.parent {
+		if c, ok := s.classes[name]; ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// binaryName returns the name of the class file of c, JLS 13.1. Member classes are named after the class
+// declaring them, like Outer$Inner, local classes are numbered, like Outer$1Local, and anonymous classes are named
+// by their number, like Outer$1
+func (c *class) binaryName() string {
+	switch {
+	case c.outer == nil || c.isAnonymous:
+		return c.name
+	case c.local > 0:
+		return fmt.Sprintf("%s$%d%s", c.outer.binaryName(), c.local, c.name)
+	}
+	return c.outer.binaryName() + "$" + c.name
+}
+
+// memberClass returns the member class named name of c or of the classes c is nested in, innermost first
+func (c *class) memberClass(name string) *class {
+	for ; c != nil; c = c.outer {
+		if i := slices.IndexFunc(c.classes, func(m *class) bool { return m.name == name }); i >= 0 {
+			return c.classes[i]
+		}
+	}
+	return nil
+}
+
+// nestedClass returns the member class of c or of its member classes whose binary name is name
+func (c *class) nestedClass(name string) *class {
+	for _, m := range c.classes {
+		if m.binaryName() == name {
+			return m
+		}
+		if n := m.nestedClass(name); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// nestedName returns the binary name of the member class that name refers to in the body of c, like Outer$Inner
+// for Inner or Outer.Inner, JLS 6.5.5. Other names are returned as they are
+func (f *file) nestedName(c *class, name string) string {
+	first, rest, _ := strings.Cut(name, ".")
+	m := c.memberClass(first)
+	if m == nil {
+		if m = f.class(first); m == nil {
+			return name
+		}
+	}
+	for rest != "" {
+		first, rest, _ = strings.Cut(rest, ".")
+		i := slices.IndexFunc(m.classes, func(n *class) bool { return n.name == first })
+		if i < 0 {
+			return name
+		}
+		m = m.classes[i]
+	}
+	return m.binaryName()
+}
+
+// linkNested replaces the names of member classes in the types written in the classes of f by their binary names,
+// so they are found by file.class
+func (p *Parser) linkNested(f *file) {
+	for _, c := range f.classes {
+		var link func(t *typeRef, static bool)
+		link = func(t *typeRef, static bool) {
+			if t.kind == IDENTIFIER && t.param == nil {
+				t.name = f.nestedName(c, t.name)
+			}
+			if t.bound != nil {
+				link(t.bound, static)
+			}
+			for _, arg := range t.args {
+				link(arg, static)
+			}
+		}
+		c.walkTypes(link)
+	}
+}
+
+// resolveInnerClass replaces the class name of the qualified creation n, like outer.new Inner(), by the binary name
+// of the member class of the type of outer it names, JLS 15.9.1
+func (p *Parser) resolveInnerClass(f *file, n *newObject) {
+	kind := p.typeOf(n.outer)
+	if kind == nil || f.class(kind.name) == nil {
+		return
+	}
+	outer := f.class(kind.name)
+	i := slices.IndexFunc(outer.classes, func(m *class) bool { return m.name == n.kind.name || m.binaryName() == n.kind.name })
+	if i < 0 {
+		p.errorAt(n.kind.pos, "cannot find symbol: class %s in class %s", n.kind.name, outer.name)
+		return
+	}
+	n.kind.name = outer.classes[i].binaryName()
+	if !outer.classes[i].inner {
+		p.errorAt(n.pos, "qualified new of static class")
+	}
+}
+
+// captureLocals records the locals declared in enclosing that the body of the local or anonymous class c uses,
+// including those that the classes declared in it capture
+func (c *class) captureLocals(enclosing *scope) {
+	c.inspectBody(func(n Node) bool {
+		if id, ok := n.(*identifier); ok && id.local != nil && enclosing.lookup(id.name) == id.local && !slices.Contains(c.captured, id.local) {
+			c.captured = append(c.captured, id.local)
+		}
+		return true
+	})
+}
+
+// inspectBody calls fn for the nodes in the field initializers, methods and initializer blocks of c,
+// and of the classes declared in them
+func (c *class) inspectBody(fn func(Node) bool) {
+	for _, f := range c.fields {
+		inspectClasses(f.value, fn)
+	}
+	for _, m := range c.bodies() {
+		for _, stmt := range m.statements {
+			inspectClasses(stmt, fn)
+		}
+	}
+	for _, m := range c.classes {
+		m.inspectBody(fn)
+	}
+}
+
+// inspectClasses is like inspect, but also walks the bodies of the local and anonymous classes declared under n
+func inspectClasses(n Node, fn func(Node) bool) {
+	inspect(n, func(n Node) bool {
+		if !fn(n) {
+			return false
+		}
+		switch n := n.(type) {
+		case *localClass:
+			n.class.inspectBody(fn)
+		case *newObject:
+			if n.body != nil {
+				n.body.inspectBody(fn)
+			}
+		}
+		return true
+	})
+}
+
+// resolveName links an identifier of c that isn't a local to the field of an enclosing class it names, JLS 6.5.6.1.
+// The fields of c and its superclasses hide those of the enclosing classes
+func (p *Parser) resolveName(c *class, n Node) {
+	id, ok := n.(*identifier)
+	if !ok || id.local != nil || c.inheritedField(id.name) != nil {
+		return
+	}
+	for o := c.outer; o != nil; o = o.outer {
+		if f := o.inheritedField(id.name); f != nil {
+			id.outer = o
+			if !f.isStatic && !c.enclosedBy(o) {
+				p.errorAt(id.pos, "non-static variable %s cannot be referenced from a static context", id.name)
+			}
+			return
+		}
+	}
+}
+
+// inheritedField returns the field named name that c declares or inherits from its superclasses, or nil
+func (c *class) inheritedField(name string) *field {
+	for seen := map[*class]bool{}; c != nil && !seen[c]; c = c.superclass {
+		seen[c] = true
+		if f := c.field(name); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// enclosedBy reports whether the instances of c have an enclosing instance of outer, through a chain of inner classes
+func (c *class) enclosedBy(outer *class) bool {
+	for ; c != outer; c = c.outer {
+		if c == nil || !c.inner {
+			return false
+		}
+	}
+	return true
+}
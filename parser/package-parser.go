@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// parseFile parses the package declaration and imports at the start of a file, JLS 7.3.
+// Files without a package declaration belong to the unnamed package
+func parseFile(p *Parser) parseStateFn {
+	if p.peekToken.kind == PACKAGE {
+		p.nextToken()
+		p.expectNext(IDENTIFIER)
+		p.file.pkg = p.ast.declarePackage(p.parseTypeName().name)
+		if !p.expectNext(SEMICOLON) {
+			return nil
+		}
+	}
+	for p.peekToken.kind == IMPORT {
+		if !p.parseImport() {
+			return nil
+		}
+	}
+	return parseClass
+}
+
+// parseImport parses a single-type, on demand or static import following the current token, JLS 7.5
+func (p *Parser) parseImport() bool {
+	p.nextToken()
+	imp := &importDecl{node: p.token.node()}
+	if p.peekToken.kind == STATIC {
+		p.nextToken()
+		imp.isStatic = true
+	}
+	if !p.expectNext(IDENTIFIER) {
+		return false
+	}
+	names := []string{p.token.value}
+	for p.peekToken.kind == DOT {
+		p.nextToken()
+		if p.peekToken.kind == MULTIPLY {
+			p.nextToken()
+			names = append(names, "*")
+			break
+		}
+		if !p.expectNext(IDENTIFIER) {
+			return false
+		}
+		names = append(names, p.token.value)
+	}
+	if len(names) == 1 {
+		p.errorAt(p.peekToken.pos, "'.' expected")
+		return false
+	}
+	imp.pkgName = strings.Join(names[:len(names)-1], ".")
+	imp.alias = names[len(names)-1]
+	p.file.imports = append(p.file.imports, imp)
+	return p.expectNext(SEMICOLON)
+}
+
+// declarePackage returns the package named name, which is added to the AST by the first file declaring it
+func (a *AST) declarePackage(name string) *pkg {
+	if pkg := a.pkg(name); pkg != nil {
+		return pkg
+	}
+	pkg := &pkg{name: name}
+	a.packages = append(a.packages, pkg)
+	return pkg
+}
+
+// pkg returns the package named name when a file of the source root declares it, or nil
+func (a *AST) pkg(name string) *pkg {
+	i := slices.IndexFunc(a.packages, func(pkg *pkg) bool { return pkg.name == name })
+	if i < 0 {
+		return nil
+	}
+	return a.packages[i]
+}
+
+// class returns the class of the package named name, or nil when the package is unknown
+func (pkg *pkg) class(name string) *class {
+	if pkg == nil {
+		return nil
+	}
+	for _, c := range pkg.classes {
+		if c.binaryName() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// linkImports reports the classes of the package of f that are declared twice,
+// and the imports of f that conflict or name classes missing from a package of the source root.
+// Packages outside the source root, like java.util, are not known and their imports are accepted
+func (p *Parser) linkImports(f *file) {
+	for _, c := range f.classes {
+		if first := f.pkg.class(c.binaryName()); first != c {
+			p.errorAt(c.pos, "duplicate class: %s", qualifiedName(f.pkg, c))
+		}
+	}
+	var singles []*importDecl
+	for _, imp := range f.imports {
+		switch {
+		case imp.alias == "*":
+			continue
+		case imp.isStatic:
+			if c := f.class(imp.pkgName); c != nil && c.methodsNamed(imp.alias) == nil && c.field(imp.alias) == nil {
+				p.errorAt(imp.pos, "cannot find symbol: %s in class %s", imp.alias, imp.pkgName)
+			}
+			continue
+		case p.ast.pkg(imp.pkgName) != nil && p.ast.pkg(imp.pkgName).class(imp.alias) == nil:
+			p.errorAt(imp.pos, "cannot find symbol: class %s in package %s", imp.alias, imp.pkgName)
+			continue
+		}
+		i := slices.IndexFunc(singles, func(o *importDecl) bool { return o.alias == imp.alias })
+		switch {
+		case i >= 0 && singles[i].pkgName != imp.pkgName:
+			p.errorAt(imp.pos, "a type with the same simple name %s is already defined by the single-type-import of %s.%s", imp.alias, singles[i].pkgName, imp.alias)
+		case slices.ContainsFunc(f.classes, func(c *class) bool { return c.name == imp.alias }) && imp.pkgName != f.pkg.name:
+			p.errorAt(imp.pos, "%s is already defined in this compilation unit", imp.alias)
+		}
+		singles = append(singles, imp)
+	}
+}
+
+// staticImports returns the static methods named name that f imports from the classes of the source root
+func (f *file) staticImports(name string) []*method {
+	var methods []*method
+	for _, imp := range f.imports {
+		if !imp.isStatic || imp.alias != name && imp.alias != "*" {
+			continue
+		}
+		for _, m := range f.class(imp.pkgName).methodsNamed(name) {
+			if m.isStatic {
+				methods = append(methods, m)
+			}
+		}
+	}
+	return methods
+}
+
+func qualifiedName(pkg *pkg, c *class) string {
+	if pkg.name == "" {
+		return c.binaryName()
+	}
+	return pkg.name + "." + c.binaryName()
+}
@@ -3,40 +3,132 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
 )
 
+// New returns a parser for the Java file at path
 // TOOD: Add fault tolerance, so that parser can continue parsing after an error
 func New(path string, language string) (*Parser, error) {
-	if lexer, err := newLexer(path); err != nil {
+	lexer, err := newLexer(path)
+	if err != nil {
 		return nil, err
-	} else {
-		exec := &file{path: path}
-		return &Parser{
-			lexer:  lexer,
-			Target: language,
-			ast:    &AST{files: []*file{exec}},
-			curr: curr{
-				file: exec,
-			},
-			peekToken:  lexer.nextToken(),
-			state:      parseClass,
-			errorLimit: 5,
-		}, nil
 	}
+	return newParser(path, lexer, language), nil
+}
+
+// NewFromReader returns a parser for the Java source read from r.
+// name is the virtual file name used in the AST
+func NewFromReader(name string, r io.Reader, language string) *Parser {
+	return newParser(name, newReaderLexer(r), language)
+}
+
+// NewFromString returns a parser for the Java source in src.
+// name is the virtual file name used in the AST
+func NewFromString(name, src, language string) *Parser {
+	return newParser(name, newStringLexer(src), language)
+}
+
+// NewFromDir returns a parser for every .java file under the source root dir.
+// The classes of the files are resolved across files by their package
+func NewFromDir(root, language string) (*Parser, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".java" {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .java files found in %s", root)
+	}
+	p, err := New(paths[0], language)
+	if err != nil {
+		return nil, err
+	}
+	p.sources = paths[1:]
+	return p, nil
+}
+
+func newParser(name string, lexer *lexer, language string) *Parser {
+	p := &Parser{
+		lexer:      lexer,
+		Target:     language,
+		ast:        &AST{},
+		errorLimit: 5,
+	}
+	p.startFile(name)
+	return p
+}
+
+// startFile adds the file at path to the AST and starts parsing it, with the lexer reading its source
+func (p *Parser) startFile(path string) {
+	f := &file{path: path, root: p.ast, pkg: p.ast.declarePackage("")}
+	p.ast.files = append(p.ast.files, f)
+	p.curr = curr{file: f}
+	p.prevToken, p.ahead = nil, nil
+	p.state = parseFile
+	p.peekToken = p.lexToken()
+}
+
+// nextSource starts parsing the next file of the source root, and reports whether there is one
+func (p *Parser) nextSource() bool {
+	for len(p.sources) > 0 {
+		path := p.sources[0]
+		p.sources = p.sources[1:]
+		src, err := os.Open(path)
+		if err != nil {
+			p.error(err.Error())
+			continue
+		}
+		p.lexer.Reset(src)
+		p.lexer.source = src
+		p.startFile(path)
+		return true
+	}
+	return false
 }
 
 // Parse parses the tokens and returns the AST or an error if parsing fails.
+// The files are checked once all of them are parsed, so their classes may refer to each other,
+// and erased once all of them are checked
 func (p *Parser) Parse() (*AST, []error) {
 	if !p.running {
 		p.running = true
-		for p.state != nil && p.peekToken.kind != EOF && len(p.errors) < p.errorLimit {
-			p.state = p.state(p)
+		for {
+			for p.state != nil && p.peekToken.kind != EOF && len(p.errors) < p.errorLimit {
+				p.state = p.state(p)
+			}
+			if len(p.errors) >= p.errorLimit || !p.nextSource() {
+				break
+			}
+		}
+		// The files after the one reaching the error limit aren't parsed, checking would report their classes as missing
+		if len(p.errors) < p.errorLimit {
+			for _, f := range p.ast.files {
+				p.file = f
+				p.linkImports(f)
+				p.linkNested(f)
+				p.linkSuperclasses(f)
+			}
+			for _, f := range p.ast.files {
+				p.file = f
+				p.checkFile(f)
+			}
+			for _, f := range p.ast.files {
+				p.eraseFile(f)
+			}
 		}
 		p.running = false
+		p.lexer.Close()
 	}
 	return p.ast, p.errors
 }
@@ -49,7 +141,7 @@ func funcCaller(skip int) (string, string, int, bool) {
 	pc, file, line, ok := runtime.Caller(skip)
 	fn := strings.Split(runtime.FuncForPC(pc).Name(), ".")
 	fnName := fn[len(fn)-1]
-	if fnName == "expect" || fnName == "expectNext" {
+	if fnName == "expect" || fnName == "expectNext" || fnName == "errorf" {
 		return funcCaller(skip + 2)
 	}
 	return fnName, filepath.Base(file), line, ok
@@ -70,21 +162,50 @@ func (p *Parser) nextToken() {
 		return
 	}
 
-	t := p.lexer.nextToken()
-	if t == nil {
-		panic("lexer returned nil token. This can happen if token channel is closed")
+	if len(p.ahead) > 0 {
+		p.peekToken = p.ahead[0]
+		p.ahead = p.ahead[1:]
+	} else {
+		p.peekToken = p.lexToken()
+	}
+}
+
+// peekAt returns the n-th token after the current token, peekAt(1) is peekToken.
+// Tokens are buffered until nextToken reaches them, and EOF is repeated past the end
+func (p *Parser) peekAt(n int) *token {
+	last := p.peekToken
+	for i := 0; i < n-1; i++ {
+		if i == len(p.ahead) {
+			if last.kind == EOF {
+				return last
+			}
+			p.ahead = append(p.ahead, p.lexToken())
+		}
+		last = p.ahead[i]
 	}
-	switch t.kind {
-	case NOT_SUPPORTED:
-		p.errorf("token: %s, is not supported", t.value)
-	case CRITICAL:
-		// TODO: Add fatal error handling
-	case ERROR, WARNING:
-		p.errorf(fmt.Sprintf("%s: %s", t.kind, t.value))
-	case INFO:
-		// TODO: Handle info logs
-	default:
-		p.peekToken = t
+	return last
+}
+
+// lexToken returns the next token from the lexer.
+// Diagnostics are recorded and skipped, so the parser only sees real tokens
+func (p *Parser) lexToken() *token {
+	for {
+		t := p.lexer.nextToken()
+		switch t.kind {
+		case NOT_SUPPORTED:
+			p.errorAt(t.pos, "token: %s, is not supported", t.value)
+		case CRITICAL:
+			// TODO: Add fatal error handling
+		case ERROR, WARNING:
+			p.errorAt(t.pos, "%s: %s", t.kind, t.value)
+		case INFO:
+			// TODO: Handle info logs
+		default:
+			if t.err != "" {
+				p.errorAt(t.pos, "%s: %s", t.err, t.value)
+			}
+			return t
+		}
 	}
 }
 
@@ -111,23 +232,84 @@ func (p *Parser) expectNext(kind ...tokenKind) bool {
 
 func (k tokenKind) isModifier() bool {
 	switch k {
-	case PUBLIC, PRIVATE, PROTECTED, STATIC, FINAL:
+	case PUBLIC, PRIVATE, PROTECTED, STATIC, FINAL, ABSTRACT, DEFAULT:
 		return true
 	default:
 		return false
 	}
 }
 
-// parseModifiers parses visibility and other modifiers for classes, methods, and fields.
+var literals = []tokenKind{
+	INT_LITERAL, LONG_LITERAL, FLOAT_LITERAL, DOUBLE_LITERAL,
+	STRING_LITERAL, CHAR_LITERAL, BOOLEAN_LITERAL, NULL_LITERAL,
+}
+
+func (k tokenKind) isLiteral() bool {
+	return slices.Contains(literals, k)
+}
+
+var primitives = []tokenKind{BOOLEAN, BYTE, SHORT, INT, LONG, CHAR, FLOAT, DOUBLE}
+
+// parseType parses the type following the current token, and stops at its last token
+func (p *Parser) parseType() *typeRef {
+	p.expectNext(append(primitives, VOID, IDENTIFIER)...)
+	t := p.parseTypeName()
+	if t.args != nil && len(t.args) == 0 {
+		p.errorf("illegal start of type")
+	}
+	t.dims = p.parseDims()
+	return t
+}
+
+// parseDims parses the pairs of brackets following the current token, and returns how many there are
+func (p *Parser) parseDims() int {
+	dims := 0
+	for p.peekToken.kind == OBRACKET && p.peekAt(2).kind == CBRACKET {
+		p.nextToken()
+		p.nextToken()
+		dims++
+	}
+	return dims
+}
+
+// parseTypeName parses the primitive or possibly qualified and parameterized class name at the current token.
+// A simple name may be a type variable of the class or method being parsed
+func (p *Parser) parseTypeName() *typeRef {
+	t := &typeRef{node: p.token.node(), kind: p.token.kind}
+	for t.kind == IDENTIFIER {
+		if p.peekToken.kind == LT {
+			t.args = p.parseTypeArgs()
+		}
+		if p.peekToken.kind != DOT || p.peekAt(2).kind != IDENTIFIER {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+		t.name += "." + p.token.value
+	}
+	if t.kind == IDENTIFIER && !strings.Contains(t.name, ".") && p.class != nil {
+		t.param = p.typeVar(t.name)
+		if local := p.scope.class(t.name); local != nil && t.param == nil {
+			t.name = local.binaryName()
+		}
+	}
+	return t
+}
+
+// parseModifiers parses visibility and other modifiers for classes, methods, and fields, and the annotations among them.
 func (p *Parser) parseModifiers() (mods modifiers, isFinal bool) {
 	mods = modifiers{
 		visibility: PACKAGE,
 	}
-	for p.peekToken.kind.isModifier() {
+	for p.peekToken.kind.isModifier() || p.isAnnotation() {
 		switch p.nextToken(); p.token.kind {
+		case AT:
+			if a := p.parseAnnotation(); a != nil {
+				mods.annotations = append(mods.annotations, a)
+			}
 		case PUBLIC, PRIVATE, PROTECTED:
-			if mods.isStatic || isFinal {
-				p.errorf("Visibility modifier must be declared before static and final")
+			if mods.isStatic || isFinal || mods.isAbstract || mods.isDefault {
+				p.errorf("Visibility modifier must be declared before static, final, abstract and default")
 			}
 			if mods.visibility != PACKAGE {
 				p.errorf("Multiple visibility modifiers declared")
@@ -140,164 +322,434 @@ func (p *Parser) parseModifiers() (mods modifiers, isFinal bool) {
 				p.errorf("Final can't be declared before static")
 			}
 			isFinal = true
+		case ABSTRACT:
+			mods.isAbstract = true
+		case DEFAULT:
+			mods.isDefault = true
 		}
 	}
+	switch {
+	case mods.isAbstract && isFinal:
+		p.errorf("illegal combination of modifiers: abstract and final")
+	case mods.isAbstract && mods.isStatic:
+		p.errorf("illegal combination of modifiers: abstract and static")
+	case mods.isAbstract && mods.visibility == PRIVATE:
+		p.errorf("illegal combination of modifiers: abstract and private")
+	case mods.isAbstract && mods.isDefault:
+		p.errorf("illegal combination of modifiers: abstract and default")
+	}
 	return mods, isFinal
 }
 
 func parseClass(p *Parser) parseStateFn {
-	mods, _ := p.parseModifiers()
-	p.expectNext(CLASS)
+	doc := p.peekToken.doc
+	mods, isFinal := p.parseModifiers()
+	p.scope, p.classScope = nil, nil
+	c := p.parseClassHeader(nil, doc, mods, isFinal)
+	if c == nil {
+		return nil
+	}
+	p.class = c
+	if c.isEnum {
+		return parseEnumConstants
+	}
+	return parseDeclaration
+}
+
+// isClassDecl reports whether a class, interface, enum or record declaration starts at peekToken, after its modifiers
+func (p *Parser) isClassDecl() bool {
+	switch p.peekToken.kind {
+	case CLASS, INTERFACE, ENUM:
+		return true
+	case AT:
+		return p.peekAt(2).kind == INTERFACE
+	}
+	// record is a contextual keyword, so it is an identifier followed by the name of the record
+	return p.peekToken.value == "record" && p.peekAt(2).kind == IDENTIFIER
+}
+
+// parseClassHeader parses a class declaration up to and including the opening brace of its body, following its modifiers.
+// outer is the class it is nested in, or nil for top level classes
+func (p *Parser) parseClassHeader(outer *class, doc string, mods modifiers, isFinal bool) *class {
+	enclosing := p.class
+	defer func() { p.class = enclosing }()
+	isRecord := p.isClassDecl() && p.peekToken.kind == IDENTIFIER
+	// An annotation interface is declared with @interface, JLS 9.6
+	isAnnotation := p.peekToken.kind == AT
+	if isRecord || isAnnotation {
+		p.nextToken()
+	}
+	if !isRecord && !p.expectNext(CLASS, INTERFACE, ENUM) {
+		return nil
+	}
+	isInterface, isEnum := p.token.kind == INTERFACE, p.token.kind == ENUM
 	p.expectNext(IDENTIFIER)
-	p.expectNext(OBRACE)
 	p.class = &class{
-		modifiers: mods,
-		node:      p.prevToken.node(),
+		modifiers:    mods,
+		node:         p.token.node(),
+		doc:          doc,
+		isFinal:      isFinal,
+		isInterface:  isInterface,
+		isEnum:       isEnum,
+		isRecord:     isRecord,
+		outer:        outer,
+		isAnnotation: isAnnotation,
+	}
+	p.typeParams = nil
+	if p.peekToken.kind == LT && !isEnum {
+		p.parseTypeParams(&p.class.typeParams, p.class)
+	}
+	switch {
+	case mods.isDefault:
+		p.errorf("modifier default not allowed here")
+	case isInterface && isFinal:
+		p.errorf("illegal combination of modifiers: interface and final")
+	case isEnum && isFinal:
+		p.errorf("modifier final not allowed here")
+	case (isEnum || isRecord) && mods.isAbstract:
+		p.errorf("modifier abstract not allowed here")
+	}
+	// A nested class may not have the name of a class enclosing it, JLS 8.1
+	for o := outer; o != nil; o = o.outer {
+		if o.name != p.class.name {
+			continue
+		}
+		location := "package unnamed package"
+		switch {
+		case o.outer != nil:
+			location = o.outer.kindName() + " " + o.outer.name
+		case p.file.pkg.name != "":
+			location = "package " + p.file.pkg.name
+		}
+		p.errorf("%s %s is already defined in %s", o.kindName(), o.name, location)
+		break
+	}
+	if isRecord && !p.parseRecordHeader() {
+		return nil
+	}
+	c := p.class
+	// Interfaces are implicitly abstract, and extend other interfaces instead of implementing them
+	p.class.isAbstract = p.class.isAbstract || isInterface
+	if p.peekToken.kind == EXTENDS && isInterface {
+		p.nextToken()
+		p.class.implements = p.parseTypeList()
+	} else if p.peekToken.kind == EXTENDS && !isEnum && !isRecord {
+		p.nextToken()
+		p.expectNext(IDENTIFIER)
+		p.class.extends = p.parseTypeName()
+	}
+	if p.peekToken.kind == IMPLEMENTS {
+		p.nextToken()
+		if isInterface {
+			p.errorf("'{' expected")
+		}
+		p.class.implements = p.parseTypeList()
+	}
+	if !p.expectNext(OBRACE) {
+		return nil
+	}
+	return c
+}
+
+// parseClassBody parses the members of c up to and including its closing brace, following its opening brace.
+// The class being parsed is restored afterwards, so c may be nested in it.
+// The members of local and anonymous classes are declared in a scope nested in the current one, see scope
+func (p *Parser) parseClassBody(c *class) bool {
+	outer, method, decl, declType, enclosing, targets, typeParams, lambda, classScope := p.class, p.method, p.decl, p.declType, p.scope, p.targets, p.typeParams, p.lambda, p.classScope
+	defer func() {
+		p.class, p.method, p.decl, p.declType, p.scope, p.targets, p.typeParams, p.lambda, p.classScope = outer, method, decl, declType, enclosing, targets, typeParams, lambda, classScope
+	}()
+	p.class, p.targets, p.lambda = c, nil, nil
+	if c.local > 0 || c.isAnonymous {
+		p.classScope = &scope{parent: p.scope, boundary: true}
+	}
+	state := parseDeclaration
+	if c.isEnum {
+		state = parseEnumConstants
+	}
+	for state != nil && !c.isClosed && p.peekToken.kind != EOF && len(p.errors) < p.errorLimit {
+		state = state(p)
+	}
+	return c.isClosed
+}
+
+// parseTypeList parses the comma separated class names following the current token
+func (p *Parser) parseTypeList() []*typeRef {
+	var kinds []*typeRef
+	for {
+		p.expectNext(IDENTIFIER)
+		kinds = append(kinds, p.parseTypeName())
+		if p.peekToken.kind != COMMA {
+			return kinds
+		}
+		p.nextToken()
 	}
-	return parseDeclaration
 }
 
 func parseDeclaration(p *Parser) parseStateFn {
-	if p.token.kind == CBRACE {
+	if p.peekToken.kind == CBRACE {
 		p.nextToken()
+		p.class.isClosed = true
+		p.checkClass(p.class)
 		p.addClass(p.class)
 		return parseClass
 	}
+	p.scope = p.classScope
+	doc := p.peekToken.doc
 	mods, isFinal := p.parseModifiers()
-	p.expectNext(INT, FLOAT, STRING, BOOLEAN, VOID)
+	if p.isClassDecl() {
+		return p.parseMemberClass(doc, mods, isFinal)
+	}
+	if p.peekToken.kind == OBRACE {
+		return p.parseInitBlock(mods, isFinal)
+	}
+	p.typeParams = nil
+	if p.peekToken.kind == LT {
+		p.parseTypeParams(&p.typeParams, nil)
+	}
+	// A constructor is a name followed by its parameters, without a return type.
+	// The compact constructor of a record leaves out the parameters too
+	isConstructor := p.peekToken.kind == IDENTIFIER && p.peekAt(2).kind == OPAREN || p.isCompactConstructor()
+	var kind *typeRef
+	if isConstructor {
+		kind = primitiveType(VOID, p.peekToken.pos)
+		switch {
+		case p.peekToken.value != p.class.name:
+			p.errorAt(p.peekToken.pos, "invalid method declaration; return type required")
+		case mods.isStatic:
+			p.errorAt(p.peekToken.pos, "modifier static not allowed here")
+		case isFinal:
+			p.errorAt(p.peekToken.pos, "modifier final not allowed here")
+		}
+	} else {
+		kind = p.parseType()
+	}
+	if p.class.isInterface {
+		p.interfaceMember(&mods, &isFinal, isConstructor)
+	} else if isConstructor && p.class.isEnum {
+		p.enumConstructor(&mods)
+	} else if mods.isDefault {
+		p.errorf("modifier default not allowed here")
+	}
 	p.expectNext(IDENTIFIER)
 	p.decl = &decl{
-		modifiers: mods,
-		isFinal:   isFinal,
-		node:      p.token.node(),
-		kind:      p.prevToken.kind,
+		modifiers:     mods,
+		isFinal:       isFinal,
+		isConstructor: isConstructor,
+		node:          p.token.node(),
+		kind:          arrayType(kind, p.parseDims()),
+		typeParams:    p.typeParams,
+		doc:           doc,
 	}
+	p.declType = kind
 	switch p.nextToken(); p.token.kind {
 	case OPAREN:
 		return parseParams
-	case ASSIGN:
+	case ASSIGN, COMMA, SEMICOLON:
 		return parseField
-	case SEMICOLON:
-		p.class.fields = append(p.class.fields, &field{decl: p.decl})
-	case CBRACE:
-		// if p.peekToken.kind == EOF {
-		// 	p.errorf("class: %s is missing a closing bracket", p.getClass().name)
-		// 	return nil
-		// }
-		return parseClass
+	case OBRACE:
+		if isConstructor {
+			return parseCompactConstructor
+		}
+		fallthrough
 	default:
 		p.errorf("unexpected token (declaration): %s", p.token.kind)
 	}
 	return parseDeclaration
 }
 
-func parseFunc(p *Parser) parseStateFn {
-	fn := &fn{reference: p.reference}
-	// p.reference = nil
-	for p.token.kind != CPAREN {
-		p.expectNext(ARGUMENT)
-		fn.args = append(fn.args, p.token.node())
-		p.expectNext(COMMA, CPAREN)
-	}
-	p.expectNext(SEMICOLON)
-	p.method.expressions = append(p.method.expressions, fn)
-	return parseMethodBody
-}
-
 func parseParams(p *Parser) parseStateFn {
-	var params []*parameter
-	for p.token.kind != CPAREN {
-		p.expectNext(INT, FLOAT, STRING, BOOLEAN)
-		p.expectNext(PARAMETER)
-		params = append(params, &parameter{
-			kind: p.prevToken.node(),
-			name: p.token.node(),
-		})
-		p.expectNext(COMMA, CPAREN)
-	}
-	p.expectNext(OBRACE)
+	params := p.parseParameters()
+	p.parseThrows()
+	var value Expression
+	if p.class.isAnnotation {
+		value = p.parseElementDefault(params)
+	}
+	if !p.expectNext(OBRACE, SEMICOLON) {
+		return nil
+	}
 	p.method = &method{
-		decl:       p.decl,
-		parameters: params,
+		decl:         p.decl,
+		parameters:   params,
+		defaultValue: value,
 	}
-	return parseMethodBody
+	if !p.checkMethodBody(p.token.kind == OBRACE) {
+		p.addMethod()
+		return parseDeclaration
+	}
+	return p.enterMethod()
 }
 
-// parseMethodBody parses lexer tokens until it reaches the end of the method
-func parseMethodBody(p *Parser) parseStateFn {
-	for p.token.kind != CBRACE {
-		switch p.nextToken(); p.token.kind {
-		case IDENTIFIER:
-			return parseExpression
-		case ASSIGN, LITERAL:
-			return parseStatement
-			// initialization
-			// should handle literal tokens
-		case SEMICOLON:
-			p.addReference()
-		case OPAREN:
-			return parseFunc
+// parseParameters parses the parameters following an opening parenthesis, up to and including the closing one
+func (p *Parser) parseParameters() []*parameter {
+	var params []*parameter
+	if p.peekToken.kind == CPAREN {
+		p.nextToken()
+		return nil
+	}
+	// The loop stops at the closing parenthesis, or when a parameter runs into the end of the file
+	for len(p.errors) < p.errorLimit {
+		param := &parameter{}
+		for p.peekToken.kind == FINAL || p.peekToken.kind == AT {
+			if p.nextToken(); p.token.kind == FINAL {
+				param.isFinal = true
+			} else if a := p.parseAnnotation(); a != nil {
+				param.annotations = append(param.annotations, a)
+			}
+		}
+		kind := p.parseType()
+		if p.peekToken.kind == ELLIPSIS {
+			p.nextToken()
+			kind, param.isVarargs = arrayType(kind, 1), true
+		}
+		if !p.expectNext(IDENTIFIER) {
+			break
+		}
+		param.name = p.token.node()
+		// The brackets may follow the name too, like String args[]
+		param.kind = arrayType(kind, p.parseDims())
+		params = append(params, param)
+		if !p.expectNext(COMMA, CPAREN) || p.token.kind == CPAREN {
+			break
+		}
+		if param.isVarargs {
+			p.errorAt(param.name.pos, "varargs parameter must be the last parameter")
 		}
 	}
-	p.addMethod()
-	return parseDeclaration
+	return params
 }
 
-func parseExpression(p *Parser) parseStateFn {
-	p.createReference()
-	if p.peekToken.kind == DOT {
-		p.nextToken() // consume DOT
-		p.expectNext(IDENTIFIER)
-		p.createReference()
+// enterMethod declares the parameters of p.method in a new scope, before its body is parsed
+func (p *Parser) enterMethod() parseStateFn {
+	p.scope, p.targets = p.classScope, nil
+	p.openScope()
+	for _, param := range p.method.parameters {
+		p.declare(&localVar{node: param.name, kind: param.kind})
 	}
 	return parseMethodBody
 }
 
-func parseStatement(p *Parser) parseStateFn {
-	return nil
+// interfaceMember applies the implicit modifiers of interface members, JLS 9.3 and 9.4.
+// Fields are public, static and final, and methods are public unless they are private
+func (p *Parser) interfaceMember(mods *modifiers, isFinal *bool, isConstructor bool) {
+	switch {
+	case isConstructor:
+		p.errorAt(p.peekToken.pos, "interfaces can't have constructors")
+	case mods.visibility == PROTECTED:
+		p.errorf("modifier protected not allowed here")
+	case mods.visibility == PACKAGE:
+		mods.visibility = PUBLIC
+	}
+	if p.peekAt(2).kind != OPAREN {
+		mods.isStatic, *isFinal = true, true
+	}
 }
 
-func parseField(p *Parser) parseStateFn {
-	return nil
+// checkMethodBody reports a method whose body doesn't match its modifiers, and reports whether it has one.
+// Methods without a body are abstract, which interface methods are by default
+func (p *Parser) checkMethodBody(hasBody bool) bool {
+	m := p.method
+	switch {
+	case p.class.isInterface && hasBody && !(m.isDefault || m.isStatic || m.visibility == PRIVATE):
+		p.errorAt(m.pos, "interface abstract methods cannot have body")
+	case p.class.isInterface && !hasBody && (m.isDefault || m.isStatic || m.visibility == PRIVATE):
+		p.errorAt(m.pos, "missing method body, or declare abstract")
+	case p.class.isInterface && !hasBody:
+		m.isAbstract = true
+	case hasBody && m.isAbstract:
+		p.errorAt(m.pos, "abstract methods cannot have a body")
+	case !hasBody && !m.isAbstract:
+		p.errorAt(m.pos, "missing method body, or declare abstract")
+	}
+	return hasBody
 }
 
-func (p *Parser) addReference() {
-	p.method.references = append(p.method.references, p.reference)
-	p.reference = nil
+// parseMethodBody parses the statements of a method until it reaches the end of the method
+func parseMethodBody(p *Parser) parseStateFn {
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+	p.method.statements = body.statements
+	p.checkConstructorCalls(p.method)
+	p.addMethod()
+	return parseDeclaration
 }
 
-func (p *Parser) createReference() {
-	if p.reference == nil {
-		p.reference = &reference{
-			node: p.token.node(),
+// parseField parses the initializers of a field declaration, following the name of its first field.
+// Every declarator becomes a field sharing the modifiers and type of the declaration, like int a = 1, b;
+func parseField(p *Parser) parseStateFn {
+	for {
+		f := &field{decl: p.decl}
+		if p.token.kind == ASSIGN {
+			f.value = p.parseInitializer(f.kind)
+			p.nextToken()
+		} else if p.class.isInterface {
+			p.errorf("= expected")
 		}
-	} else {
-		p.reference = &reference{
-			node:   p.token.node(),
-			parent: p.reference,
+		p.addField(f)
+		if !p.expect(COMMA, SEMICOLON) {
+			return nil
+		}
+		if p.token.kind == SEMICOLON {
+			return parseDeclaration
 		}
+		p.expectNext(IDENTIFIER)
+		decl := *p.decl
+		decl.node = p.token.node()
+		decl.kind = arrayType(p.declType, p.parseDims())
+		p.decl = &decl
+		p.nextToken()
 	}
 }
 
 func (p *Parser) addClass(c *class) {
 	p.file.classes = append(p.file.classes, c)
+	p.file.pkg.classes = append(p.file.pkg.classes, c)
 }
 
 func (p *Parser) addMethod() {
 	p.class.methods = append(p.class.methods, p.method)
 }
 
-func (p *Parser) addField(initValue string) {
-	p.class.fields = append(p.class.fields, &field{decl: p.decl, initValue: initValue})
+func (p *Parser) addField(f *field) {
+	if p.class.field(f.name) != nil {
+		p.errorAt(f.pos, "variable %s is already defined in %s %s", f.name, p.class.kindName(), p.class.name)
+	}
+	p.class.fields = append(p.class.fields, f)
 }
 
 func (p *Parser) errorf(format string, args ...any) {
-	caller, _, _, ok := funcCaller(2)
-	format = fmt.Sprintf("%s: %s", p.token.pos, format)
+	p.errorAt(p.token.pos, format, args...)
+}
+
+// errorAt records an error at the given position instead of the current token's
+func (p *Parser) errorAt(pos *pos, format string, args ...any) {
+	p.errors = append(p.errors, p.diagnostic(pos, format, args...))
+}
+
+// warnAt records a warning at the given position
+func (p *Parser) warnAt(pos *pos, format string, args ...any) {
+	p.warnings = append(p.warnings, p.diagnostic(pos, "warning: "+format, args...))
+}
+
+// Warnings returns the warnings of the last Parse, like the uses of deprecated declarations
+func (p *Parser) Warnings() []error {
+	return p.warnings
+}
+
+// diagnostic formats a message at pos for errorAt or warnAt, prefixed with the function reporting it
+func (p *Parser) diagnostic(pos *pos, format string, args ...any) error {
+	caller, _, _, ok := funcCaller(3)
+	format = fmt.Sprintf("%s: %s", pos, format)
+	// The errors of a source root name the file they are in
+	if len(p.ast.files) > 1 || len(p.sources) > 0 {
+		format = fmt.Sprintf("%s:%s", p.file.path, format)
+	}
 	if ok {
 		format = fmt.Sprintf("(%s) %s", caller, format)
 	}
-	p.errors = append(p.errors, fmt.Errorf(format, args...))
+	return fmt.Errorf(format, args...)
 }
 
 func (p *Parser) error(err string) {
@@ -0,0 +1,1583 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// parse parses src and fails the test on any error
+func parse(t *testing.T, src string) *AST {
+	t.Helper()
+	ast, errs := NewFromString("Main.java", src, "ELF").Parse()
+	for _, err := range errs {
+		t.Error(err)
+	}
+	return ast
+}
+
+func TestParseMain(t *testing.T) {
+	ast := parse(t, `/** Entry point */
+public class Main {
+  public static void main(String[] args) {
+    System.out.print("Hello World!");
+  }
+}`)
+	if len(ast.files) != 1 || ast.files[0].path != "Main.java" {
+		t.Fatalf("expected the virtual file Main.java, got %v", ast.files)
+	}
+	classes := ast.files[0].classes
+	if len(classes) != 1 || classes[0].name != "Main" || classes[0].doc != "Entry point" {
+		t.Fatalf("expected documented class Main, got %v", classes)
+	}
+	methods := classes[0].methods
+	if len(methods) != 2 || methods[0].name != "main" || !methods[0].isStatic {
+		t.Fatalf("expected static method main, got %v", methods)
+	}
+	if !methods[1].isConstructor || !methods[1].implicit || methods[1].visibility != PUBLIC {
+		t.Errorf("expected a public default constructor, got %v", methods[1])
+	}
+	if params := methods[0].parameters; len(params) != 1 || params[0].kind.name != "String" || params[0].kind.dims != 1 || params[0].name.name != "args" {
+		t.Errorf("expected parameter String[] args, got %v", params)
+	}
+}
+
+func TestParseParameterErrors(t *testing.T) {
+	// Truncated and malformed parameter lists must end parsing, not loop on the same token
+	tests := map[string]string{
+		"class Main { void m(int":           "expected identifier, got EOF",
+		"class Main { void m(i":             "expected identifier, got EOF",
+		"class Main { Main(int x { } }":     "expected one of [comma cparen], got obrace",
+		"class Main { void m(int a, ) {} }": "expected one of [boolean byte short int long char float double void identifier], got cparen",
+		"record R(int x":                    "expected one of [comma cparen], got EOF",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+}
+
+// expectError parses the file src and fails the test unless its first error contains want
+func expectError(t *testing.T, src, want string) {
+	t.Helper()
+	_, errs := NewFromString("Main.java", src, "ELF").Parse()
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), want) {
+		t.Errorf("%s: expected error %q, got %v", src, want, errs)
+	}
+}
+
+// inMethod returns a file declaring src as the body of a method m(int p)
+func inMethod(src string) string {
+	return "class Main { void m(int p) { " + src + " } }"
+}
+
+// parseBody parses src as the body of a method m(int p)
+func parseBody(src string) ([]Statement, []error) {
+	ast, errs := NewFromString("Main.java", inMethod(src), "ELF").Parse()
+	if len(ast.files[0].classes) != 1 || len(ast.files[0].classes[0].methods) == 0 {
+		return nil, errs
+	}
+	return ast.files[0].classes[0].methods[0].statements, errs
+}
+
+// parseExpr parses src as the only argument of a call statement
+func parseExpr(t *testing.T, src string) (Expression, []error) {
+	t.Helper()
+	stmts, errs := parseBody("f(" + src + ");")
+	if len(stmts) != 1 {
+		return nil, errs
+	}
+	return stmts[0].(*expressionStmt).expr.(*call).args[0], errs
+}
+
+func TestParseExpressionPrecedence(t *testing.T) {
+	tests := map[string]string{
+		"a + b * c":                "(a + (b * c))",
+		"a - b - c":                "((a - b) - c)",
+		"a = b += c":               "(a = (b += c))",
+		"a || b && c | d ^ e & f":  "(a || (b && (c | (d ^ (e & f)))))",
+		"a == b < c << d + e":      "(a == (b < (c << (d + e))))",
+		"a ? b : c ? d : e":        "(a ? b : (c ? d : e))",
+		"x = a > b ? a : b":        "(x = ((a > b) ? a : b))",
+		"-a * !b":                  "((-a) * (!b))",
+		"i++ + ++j":                "((i++) + (++j))",
+		"(a + b) * c":              "((a + b) * c)",
+		"(int) x + y":              "(((int) x) + y)",
+		"(String) o":               "((String) o)",
+		"(a) - b":                  "(a - b)",
+		"(int[]) o":                "((int[]) o)",
+		"o instanceof String == b": "((o instanceof String) == b)",
+		"a.b.c(1, x[i][j]).d":      "a.b.c(1, x[i][j]).d",
+		"f()":                      "f()",
+		"s.length() > 0 && !done":  "((s.length() > 0) && (!done))",
+		"-2147483648":              "(-2147483648)",
+	}
+	for src, want := range tests {
+		expr, errs := parseExpr(t, src)
+		for _, err := range errs {
+			t.Errorf("%s: %v", src, err)
+		}
+		if got := fmt.Sprint(expr); got != want {
+			t.Errorf("%s: expected %s, got %s", src, want, got)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := map[string]string{
+		"a + 1 = b":                "left-hand side of an assignment must be a variable",
+		"a ? b c":                  "expected colon",
+		"f(a b)":                   "expected one of",
+		"y - 2147483648":           "integer number too large: 2147483648",
+		"-(2147483648)":            "integer number too large: 2147483648",
+		"9223372036854775808L + 1": "long number too large: 9223372036854775808L",
+	}
+	for src, msg := range tests {
+		expectError(t, inMethod("f("+src+");"), msg)
+	}
+	// A literal out of range is reported once, without derailing the rest of the statement
+	if _, errs := parseBody("int x = 3000000000; int y = x;"); len(errs) != 1 || !strings.Contains(errs[0].Error(), "integer number too large: 3000000000") {
+		t.Errorf("expected a single range error, got %v", errs)
+	}
+}
+
+func TestParseLocalVars(t *testing.T) {
+	stmts, errs := parseBody(`int a = 1, b;
+    final String s = "x";
+    var n = 2L * a;
+    var t = s + n;
+    b = a += 2;
+    a++;
+    java.util.List[] lists;`)
+	for _, err := range errs {
+		t.Error(err)
+	}
+	want := []string{
+		"int a = 1, b",
+		`final String s = "x"`,
+		"var n = (2L * a)",
+		"var t = (s + n)",
+		"(b = (a += 2))",
+		"(a++)",
+		"java.util.List[] lists",
+	}
+	if len(stmts) != len(want) {
+		t.Fatalf("expected %d statements, got %v", len(want), stmts)
+	}
+	for i, stmt := range stmts {
+		if got := fmt.Sprint(stmt); got != want[i] {
+			t.Errorf("statement %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+	if n := stmts[2].(*localVars).vars[0]; n.kind.kind != LONG {
+		t.Errorf("expected var n to be inferred as long, got %s", n.kind)
+	}
+	if s := stmts[3].(*localVars).vars[0]; s.kind.name != "String" {
+		t.Errorf("expected var t to be inferred as String, got %s", s.kind)
+	}
+}
+
+func TestParseLocalVarErrors(t *testing.T) {
+	tests := map[string]string{
+		"int p;":                  "variable p is already defined in method m",
+		"int a; int a;":           "variable a is already defined in method m",
+		"var a;":                  "cannot use 'var' on variable without initializer",
+		"var a = null;":           "variable initializer is 'null'",
+		"var a = 1, b = 2;":       "'var' is not allowed in a compound declaration",
+		"final int a = 1; a++;":   "cannot assign a value to final variable a",
+		"final int a = 1; a = 2;": "cannot assign a value to final variable a",
+		"static int a;":           "modifier static not allowed here",
+		"p + 1;":                  "not a statement",
+	}
+	for src, msg := range tests {
+		expectError(t, inMethod(src), msg)
+	}
+}
+
+func TestParseControlFlow(t *testing.T) {
+	stmts, errs := parseBody(`if (p > 0) p--; else if (p < 0) { p++; } else ;
+    while (p < 10) p += 2;
+    do { p--; } while (p != 0);
+    for (int i = 0, j = 9; i < j; i++, j--) continue;
+    for (;;) break;
+    outer:
+    for (var a : new_args) {
+      for (int i = 0; i < 3; i++) {
+        if (i == p) continue outer;
+        if (i > p) break outer;
+      }
+    }
+    { int i = 1; }
+    return;`)
+	for _, err := range errs {
+		t.Error(err)
+	}
+	want := []string{
+		"if ((p > 0)) (p--) else if ((p < 0)) { (p++) } else ;",
+		"while ((p < 10)) (p += 2)",
+		"do { (p--) } while ((p != 0))",
+		"for (int i = 0, j = 9; (i < j); (i++), (j--)) continue",
+		"for (; ; ) break",
+		"outer: for (var a : new_args) { for (int i = 0; (i < 3); (i++)) { if ((i == p)) continue outer; if ((i > p)) break outer } }",
+		"{ int i = 1 }",
+		"return",
+	}
+	if len(stmts) != len(want) {
+		t.Fatalf("expected %d statements, got %v", len(want), stmts)
+	}
+	for i, stmt := range stmts {
+		if got := fmt.Sprint(stmt); got != want[i] {
+			t.Errorf("statement %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestParseControlFlowErrors(t *testing.T) {
+	tests := map[string]string{
+		"break;":                          "break outside switch or loop",
+		"continue;":                       "continue outside of loop",
+		"while (true) break done;":        "undefined label: done",
+		"a: { while (true) continue a; }": "not a loop label: a",
+		"a: a: ;":                         "label a already in use",
+		"return 1;":                       "incompatible types: unexpected return value",
+		"if (p) ;":                        "incompatible types: int cannot be converted to boolean",
+		"while (true) int i = 0;":         "variable declaration not allowed here",
+		"{ int i; } { int i; } int p2; { int p2; }": "variable p2 is already defined in method m",
+	}
+	for src, msg := range tests {
+		expectError(t, inMethod(src), msg)
+	}
+	// A variable may be redeclared once the scope of the first one ends
+	if _, errs := parseBody("for (int i = 0; ; ) ; int i = 1;"); len(errs) > 0 {
+		t.Error(errs)
+	}
+}
+
+func TestParseSwitch(t *testing.T) {
+	stmts, errs := parseBody(`switch (p) {
+      case 1:
+      case 2: p++; break;
+      default: int q = 1;
+    }
+    String s = switch (p) {
+      case 1, 2 -> "low";
+      case 3 -> { yield "mid"; }
+      default -> "high";
+    };
+    switch (s) { case "a" -> p--; }
+    loop:
+    while (true) {
+      switch (p) { case 1: continue; case 2: break loop; }
+    }`)
+	for _, err := range errs {
+		t.Error(err)
+	}
+	want := []string{
+		"switch (p) { case 1:  case 2: (p++); break default: int q = 1 }",
+		`String s = switch (p) { case 1, 2 -> yield "low" case 3 -> { yield "mid" } default -> yield "high" }`,
+		`switch (s) { case "a" -> (p--) }`,
+		"loop: while (true) { switch (p) { case 1: continue case 2: break loop } }",
+	}
+	if len(stmts) != len(want) {
+		t.Fatalf("expected %d statements, got %v", len(want), stmts)
+	}
+	for i, stmt := range stmts {
+		if got := fmt.Sprint(stmt); got != want[i] {
+			t.Errorf("statement %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestParseSwitchErrors(t *testing.T) {
+	tests := map[string]string{
+		"switch (p) { case 1 -> p++; case 2: p--; }":                     "different case kinds used in the switch",
+		"switch (p) { case 1: case 1: }":                                 "duplicate case label",
+		"switch (p) { default: default: }":                               "duplicate default label",
+		"switch (1L) { }":                                                "long can't be used as a switch selector",
+		"int a = switch (p) { case 1 -> 2; };":                           "does not cover all possible input values",
+		"int a = switch (p) { };":                                        "does not have any case clauses",
+		"yield 1;":                                                       "yield outside of switch expression",
+		"while (true) { int a = switch (p) { default -> { break; } }; }": "attempting to break out of a switch expression",
+		"int a = switch (p) { default -> { return; } };":                 "attempting to return out of a switch expression",
+		"switch (p) { case 1: continue; }":                               "continue outside of loop",
+	}
+	for src, msg := range tests {
+		expectError(t, inMethod(src), msg)
+	}
+}
+
+func TestLowerSwitch(t *testing.T) {
+	tests := []struct {
+		src      string
+		strategy switchStrategy
+		table    []int
+	}{
+		{"switch (p) { case 1: case 3: default: case 2: }", jumpTable, []int{0, 3, 1}},
+		{"switch (p) { case 'a' -> p++; case -1 -> p--; }", compareChain, nil},
+		{"switch (p) { case 1: case 1000: }", compareChain, nil},
+		{`switch (p) { case "a": }`, compareChain, nil},
+	}
+	for _, test := range tests {
+		stmts, errs := parseBody(test.src)
+		if len(errs) > 0 || len(stmts) != 1 {
+			t.Fatalf("%s: %v", test.src, errs)
+		}
+		l := stmts[0].(*switchStmt).lower(nil)
+		if l.strategy != test.strategy || !slices.Equal(l.table, test.table) {
+			t.Errorf("%s: expected strategy %d %v, got %d %v", test.src, test.strategy, test.table, l.strategy, l.table)
+		}
+	}
+	l := (&switchBlock{cases: []*switchCase{{isDefault: true}}}).lower(nil)
+	if l.fallback != 0 || l.keys != nil {
+		t.Errorf("expected a default-only switch to fall back to case 0, got %+v", l)
+	}
+}
+
+func TestParseFieldConstants(t *testing.T) {
+	ast := parse(t, `class Main {
+  static final int MAX = 10, MIN = -MAX;
+  static final long BIG = 1L << 40 | MAX;
+  static final byte B = 0x7f;
+  static final char C = 'a' + 1;
+  static final double HALF = 1 / 2.0;
+  static final String NAME = "max=" + MAX + ", half=" + HALF + ", " + C + (MAX > MIN) + 1e10 + 1f;
+  static final int WRAP = Main.Integer_MAX + 1;
+  static final int Integer_MAX = (int) 2147483647L;
+  static final int SATURATED = (int) 1e20, FLOOR = (int) -1e20f, NAN = (int) (0.0 / 0.0);
+  static final char CHAR_MAX = (char) 1e10;
+  static final byte BYTE = (byte) 300.5;
+  static final long LONG = (long) 1e20;
+  static int counter = MAX * 2;
+  final int id = counter++;
+  int next = id + 1;
+  final int[] unused = null;
+}`)
+	fields := ast.files[0].classes[0].fields
+	want := map[string]any{
+		"MAX":         int32(10),
+		"MIN":         int32(-10),
+		"BIG":         int64(1<<40 | 10),
+		"B":           int8(127),
+		"C":           uint16('b'),
+		"HALF":        0.5,
+		"NAME":        "max=10, half=0.5, btrue1.0E101.0",
+		"WRAP":        int32(-2147483648),
+		"Integer_MAX": int32(2147483647),
+		"SATURATED":   int32(2147483647),
+		"FLOOR":       int32(-2147483648),
+		"NAN":         int32(0),
+		"CHAR_MAX":    uint16(65535),
+		"BYTE":        int8(44),
+		"LONG":        int64(9223372036854775807),
+		"counter":     nil,
+		"id":          nil,
+		"unused":      nil,
+	}
+	for _, f := range fields {
+		if value, ok := want[f.name]; ok && f.constant != value {
+			t.Errorf("%s: expected constant %#v, got %#v", f.name, value, f.constant)
+		}
+	}
+
+	cls := ast.files[0].classes[0]
+	var static, instance []string
+	for _, f := range cls.initializers(true) {
+		static = append(static, f.name)
+	}
+	for _, f := range cls.initializers(false) {
+		instance = append(instance, f.name)
+	}
+	if !slices.Equal(static, []string{"counter"}) || !slices.Equal(instance, []string{"id", "next", "unused"}) {
+		t.Errorf("expected initializers [counter] and [id next unused], got %v and %v", static, instance)
+	}
+}
+
+func TestParseFieldErrors(t *testing.T) {
+	tests := map[string]string{
+		"int a = b; int b = 1;":                             "illegal forward reference",
+		"static int a = a + 1;":                             "self-reference in initializer",
+		"static final byte B = 128;":                        "possible lossy conversion from int to byte",
+		"static final int I = 1L;":                          "possible lossy conversion from long to int",
+		"static final char C = -1;":                         "possible lossy conversion from int to char",
+		"int a = 1, ;":                                      "expected identifier",
+		"final int a = 1; Main() { a = 2; }":                "cannot assign a value to final variable a",
+		"final int a; void m() { a = 2; }":                  "cannot assign a value to final variable a",
+		"static final int A; Main() { A = 1; }":             "cannot assign a value to final variable A",
+		"final int a; Main() { Runnable r = () -> a = 1; }": "cannot assign a value to final variable a",
+	}
+	for src, msg := range tests {
+		expectError(t, "class Main { "+src+" }", msg)
+	}
+	// Assigning a field before its declaration is allowed, and blank finals are assigned by the initializers
+	parse(t, "class Main { int a = (b = 2); int b; static final int C = 1; int c = C; }")
+	parse(t, "class Main { final int a; final int b; static final int C; static { C = 1; } { b = 2; } Main() { this.a = 1; } }")
+}
+
+func TestLowerSwitchConstants(t *testing.T) {
+	ast := parse(t, `class Main {
+  static final int A = 1, B = A + 1;
+  void m(int p) {
+    final int c = 3;
+    switch (p) { case A: case B: case c: }
+  }
+}`)
+	cls := ast.files[0].classes[0]
+	l := cls.methods[0].statements[1].(*switchStmt).lower(cls)
+	if l.strategy != jumpTable || !slices.Equal(l.table, []int{0, 1, 2}) {
+		t.Errorf("expected a jump table over the constants, got %+v", l)
+	}
+}
+
+func TestParseConstructors(t *testing.T) {
+	ast := parse(t, `class Point {
+  int x, y;
+  int id = next();
+  Point(int x, int y) {
+    this.x = x;
+    this.y = y;
+  }
+  Point(int v) { this(v, v); }
+  Point() { this(0); }
+  Point(String s) { this(s.length()); }
+  Point copy() { return new Point(x, y); }
+  static Point origin() { return new Point(); }
+  int next() { return 1; }
+}`)
+	cls := ast.files[0].classes[0]
+	ctors := cls.constructors()
+	if len(ctors) != 4 {
+		t.Fatalf("expected 4 constructors, got %v", ctors)
+	}
+	if got := fmt.Sprint(ctors[1].statements[0]); got != "this(v, v)" {
+		t.Errorf("expected this(v, v), got %s", got)
+	}
+	if ctors[1].delegate() != ctors[0] || ctors[2].delegate() != ctors[1] || ctors[3].delegate() != nil {
+		t.Errorf("expected this(...) to resolve by argument types")
+	}
+	newPoint := cls.methods[4].statements[0].(*returnStmt).value.(*newObject)
+	if newPoint.constructor != ctors[0] {
+		t.Errorf("expected new Point(x, y) to call Point(int,int), got %v", newPoint.constructor)
+	}
+
+	var lowered []string
+	for _, stmt := range cls.lowerConstructor(ctors[0]) {
+		lowered = append(lowered, fmt.Sprint(stmt))
+	}
+	want := []string{"super()", "(this.id = next())", "(this.x = x)", "(this.y = y)"}
+	if !slices.Equal(lowered, want) {
+		t.Errorf("expected super() and the field initializers before the constructor body %v, got %v", want, lowered)
+	}
+	if stmts := cls.lowerConstructor(ctors[1]); len(stmts) != 1 {
+		t.Errorf("expected a delegating constructor to leave the initializers to its delegate, got %v", stmts)
+	}
+}
+
+func TestParseConstructorErrors(t *testing.T) {
+	tests := map[string]string{
+		"A() {} A() {}":                          "constructor A() is already defined in class A",
+		"void m(int a) {} void m(int b) {}":      "method m(int) is already defined in class A",
+		"B() {}":                                 "invalid method declaration; return type required",
+		"static A() {}":                          "modifier static not allowed here",
+		"A() { int a = 1; this(); }":             "call to this must be first statement in constructor",
+		"void m() { this(); }":                   "call to this must be first statement in constructor",
+		"A() { this(1); } A(int a) { this(); }":  "recursive constructor invocation",
+		"A(int a) {} void m() { new A(); }":      "no suitable constructor found for A()",
+		`A(int a) {} void m() { new A("s"); }`:   "no suitable constructor found for A(String)",
+		"int x; static void m() { this.x = 1; }": "non-static variable this cannot be referenced from a static context",
+		"A() { return 1; }":                      "incompatible types: unexpected return value",
+		"A(int a, long b) {} A(long a, int b) {} void m() { new A(1, 2); }":       "reference to A is ambiguous",
+		"void f(int a, long b) {} void f(long a, int b) {} void m() { f(1, 2); }": "reference to f is ambiguous",
+		"void f(int... xs, int y) {}":                                             "varargs parameter must be the last parameter",
+		`A(int... xs) {} void m() { new A(1, "s"); }`:                             "no suitable constructor found for A(int,String)",
+	}
+	for src, msg := range tests {
+		expectError(t, "class A { "+src+" }", msg)
+	}
+	// Classes outside the file aren't resolved
+	parse(t, "class A { Object m() { return new StringBuilder(1, 2); } }")
+	// Variable arity constructors take any number of trailing arguments, fixed arity ones are preferred
+	ast := parse(t, `class A {
+  A(int... xs) {}
+  A(int x) {}
+  A(String s, Object... rest) {}
+  void m() { new A(); new A(1); new A(1, 2, 3); new A(new int[2]); new A("a"); new A("a", 1, "b"); new A("a") {}; }
+}`)
+	cls := ast.files[0].class("A")
+	ctors := cls.constructors()
+	var resolved []*method
+	var anon *class
+	inspectAll(cls.methods[3].statements, func(n Node) bool {
+		if n, ok := n.(*newObject); ok {
+			resolved, anon = append(resolved, n.constructor), n.body
+		}
+		return true
+	})
+	want := []*method{ctors[0], ctors[1], ctors[0], ctors[0], ctors[2], ctors[2], ctors[2]}
+	if !slices.Equal(resolved, want) {
+		t.Errorf("expected the constructors %v, got %v", want, resolved)
+	}
+	if anon == nil || !anon.constructors()[0].isVarargs() {
+		t.Errorf("expected the anonymous class to take the variable arity parameter, got %v", anon)
+	}
+}
+
+func TestParseInheritance(t *testing.T) {
+	ast := parse(t, `class Shape {
+  String name;
+  Shape(String name) { this.name = name; }
+  double area() { return 0; }
+  String describe() { return name + " " + area(); }
+  static Shape unit() { return new Square(1); }
+}
+final class Square extends Rect {
+  Square(double side) { super(side, side); }
+  double area() { return super.area(); }
+}
+class Rect extends Shape {
+  double w, h;
+  Rect(double w, double h) {
+    super("rect");
+    this.w = w;
+    this.h = h;
+  }
+  double area() { return w * h; }
+  protected double perimeter() { return 2 * (w + h); }
+}`)
+	classes := ast.files[0].classes
+	shape, square, rect := classes[0], classes[1], classes[2]
+	if square.superclass != rect || rect.superclass != shape || shape.superclass != nil {
+		t.Fatalf("expected Square extends Rect extends Shape")
+	}
+	var slots []string
+	for _, m := range square.vtable() {
+		slots = append(slots, fmt.Sprintf("%s.%s", ast.files[0].classOf(m).name, m.name))
+	}
+	if want := []string{"Square.area", "Shape.describe", "Rect.perimeter"}; !slices.Equal(slots, want) {
+		t.Errorf("expected vtable %v, got %v", want, slots)
+	}
+
+	superCall := square.constructors()[0].constructorCall()
+	if superCall == nil || superCall.constructor != rect.constructors()[0] {
+		t.Errorf("expected super(side, side) to call Rect(double,double)")
+	}
+	areaCall := square.methods[1].statements[0].(*returnStmt).value.(*call)
+	if areaCall.method != rect.methods[1] || areaCall.isVirtual() {
+		t.Errorf("expected super.area() to be bound to Rect.area")
+	}
+	describeCall := shape.methods[2].statements[0].(*returnStmt).value.(*binary).right.(*call)
+	if describeCall.method != shape.methods[1] || !describeCall.isVirtual() {
+		t.Errorf("expected area() to be dispatched through the vtable")
+	}
+}
+
+func TestParseInheritanceErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A extends B {} class B extends A {}":                           "cyclic inheritance involving",
+		"class A extends A {}":                                                "cyclic inheritance involving A",
+		"final class A {} class B extends A {}":                               "cannot inherit from final A",
+		"class A { final void m() {} } class B extends A { void m() {} }":     "m() in B cannot override m() in A; overridden method is final",
+		"class A { void m() {} } class B extends A { int m() { return 1; } }": "return type int is not compatible with void",
+		"class A { public void m() {} } class B extends A { void m() {} }":    "attempting to assign weaker access privileges; was public",
+		"class A { void m() {} } class B extends A { static void m() {} }":    "overriding method is static",
+		"class A { A(int a) {} } class B extends A { }":                       "no suitable constructor found for A()",
+		"class A { A() { int a; super(); } }":                                 "call to super must be first statement in constructor",
+		"class A { static void m() { super.m(); } }":                          "non-static variable super cannot be referenced from a static context",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	// Private methods aren't inherited, so they can't be overridden
+	parse(t, "class A { private void m() {} } class B extends A { int m() { return 1; } }")
+}
+
+func TestParseInterfaces(t *testing.T) {
+	ast := parse(t, `interface Named {
+  String PREFIX = "name: ";
+  String name();
+  default String label() { return PREFIX + name(); }
+  static Named of(String s) { return new Pet(s); }
+}
+interface Animal extends Named {
+  String sound();
+}
+abstract class Base implements Animal {
+  abstract int legs();
+  public String sound() { return "..."; }
+}
+class Pet extends Base implements Named {
+  String n;
+  Pet(String n) { this.n = n; }
+  public String name() { return n; }
+  int legs() { return 4; }
+  String speak(Animal a) { return a.label(); }
+}`)
+	classes := ast.files[0].classes
+	named, animal, base, pet := classes[0], classes[1], classes[2], classes[3]
+	if !named.isInterface || !named.isAbstract || len(named.constructors()) != 0 {
+		t.Fatalf("expected Named to be an interface without constructors")
+	}
+	if prefix := named.fields[0]; !prefix.isStatic || !prefix.isFinal || prefix.visibility != PUBLIC {
+		t.Errorf("expected interface fields to be public static final")
+	}
+	if m := named.methods[0]; !m.isAbstract || m.visibility != PUBLIC {
+		t.Errorf("expected name() to be public abstract")
+	}
+	if !slices.Equal(animal.interfaces, []*class{named}) || !slices.Equal(base.interfaces, []*class{animal}) || pet.superclass != base {
+		t.Fatalf("expected the supertypes to be linked")
+	}
+	var slots []string
+	for _, m := range pet.vtable() {
+		slots = append(slots, fmt.Sprintf("%s.%s", ast.files[0].classOf(m).name, m.name))
+	}
+	if want := []string{"Pet.legs", "Base.sound", "Pet.name", "Named.label", "Pet.speak"}; !slices.Equal(slots, want) {
+		t.Errorf("expected vtable %v, got %v", want, slots)
+	}
+	itable := pet.itable(animal)
+	if len(itable) != 3 || itable[0] != base.methods[1] || itable[1] != pet.methods[1] || itable[2] != named.methods[1] {
+		t.Errorf("expected the itable of Animal to map sound, name and label")
+	}
+	labelCall := pet.methods[3].statements[0].(*returnStmt).value.(*call)
+	if labelCall.method != named.methods[1] || !labelCall.isVirtual() {
+		t.Errorf("expected a.label() to call the default method Named.label")
+	}
+}
+
+func TestParseInterfaceErrors(t *testing.T) {
+	tests := map[string]string{
+		"interface I { void m(); } class A implements I {}":                                                     "A is not abstract and does not override abstract method m() in I",
+		"abstract class A { abstract void m(); } class B extends A {}":                                          "B is not abstract and does not override abstract method m() in A",
+		"interface I { void m() {} }":                                                                           "interface abstract methods cannot have body",
+		"interface I { default void m(); }":                                                                     "missing method body, or declare abstract",
+		"class A { void m(); }":                                                                                 "missing method body, or declare abstract",
+		"abstract class A { abstract void m() {} }":                                                             "abstract methods cannot have a body",
+		"abstract class A { abstract final void m(); }":                                                         "illegal combination of modifiers: abstract and final",
+		"class A { default void m() {} }":                                                                       "modifier default not allowed here",
+		"interface I { int X; }":                                                                                "= expected",
+		"interface I { I() {} }":                                                                                "interfaces can't have constructors",
+		"interface I {} class A extends I {}":                                                                   "no interface expected here",
+		"class B {} class A implements B {}":                                                                    "interface expected here",
+		"interface I extends J {} interface J extends I {}":                                                     "cyclic inheritance involving I",
+		"abstract class A {} class B { void m() { new A(); } }":                                                 "A is abstract; cannot be instantiated",
+		"interface I { void m(); } class A implements I { void m() {} }":                                        "attempting to assign weaker access privileges; was public",
+		"abstract class A { abstract void m(); } class B extends A { void m(); }":                               "missing method body, or declare abstract",
+		"interface I { default void m() {} } interface J { default void m() {} } class A implements I, J {}":    "types I and J are incompatible; class A inherits unrelated defaults for m() from types I and J",
+		"interface I { default void m() {} } interface J { void m(); } abstract class A implements I, J {}":     "types I and J are incompatible; class A inherits abstract and default for m() from types I and J",
+		"interface I { default void m() {} } interface J { default void m() {} } interface K extends I, J {}":   "types I and J are incompatible; interface K inherits unrelated defaults for m() from types I and J",
+		"interface I { default void m() {} } interface J {} class A implements J { void f() { I.super.m(); } }": "not an enclosing class: I",
+		"interface I { void m(); } class A implements I { public void m() { I.super.m(); } }":                   "abstract method m() in I cannot be accessed directly",
+		"interface I { default void m() {} } class A implements I { static void f() { I.super.m(); } }":         "non-static variable super cannot be referenced from a static context",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	// A default method implements the abstract method of another interface
+	parse(t, "interface I { void m(); } interface J extends I { default void m() {} } class A implements J {}")
+	// Conflicting defaults are resolved by overriding them, or by a subinterface or a superclass method winning
+	ast := parse(t, `interface I { default int m() { return 1; } }
+interface J { default int m() { return 2; } }
+interface K extends I { default int m() { return 3; } }
+class A implements I, J { public int m() { return I.super.m() + J.super.m(); } }
+class B implements I, K {}
+class C { public int m() { return 0; } }
+class D extends C implements I, J {}`)
+	classes := ast.files[0].classes
+	sum := classes[3].methods[0].statements[0].(*returnStmt).value.(*binary)
+	if left := sum.left.(*call); left.method != classes[0].methods[0] || left.isVirtual() || left.String() != "I.super.m()" {
+		t.Errorf("expected I.super.m() to call the default method of I directly, got %v", left)
+	}
+	if right := sum.right.(*call); right.method != classes[1].methods[0] {
+		t.Errorf("expected J.super.m() to call the default method of J, got %v", right.method)
+	}
+}
+
+func TestParseEnums(t *testing.T) {
+	ast := parse(t, `enum Planet implements Comparable {
+  MERCURY(3.303e+23, 2.4397e6),
+  EARTH(5.976e+24, 6.37814e6) {
+    double gravity() { return 9.8; }
+  };
+  final double mass, radius;
+  Planet(double mass, double radius) {
+    this.mass = mass;
+    this.radius = radius;
+  }
+  double gravity() { return mass / (radius * radius); }
+}
+enum Color { RED, GREEN, BLUE, }
+class Main {
+  int hue(Color c) {
+    switch (c) {
+      case RED: return 0;
+      case GREEN: return 120;
+      case BLUE: return 240;
+      default: return -1;
+    }
+  }
+  String first() { return Color.values()[0].name(); }
+}`)
+	classes := ast.files[0].classes
+	earth, planet, color, main := classes[0], classes[1], classes[2], classes[3]
+	if !planet.isEnum || planet.isFinal || !color.isFinal || len(planet.constants) != 2 || len(color.constants) != 3 {
+		t.Fatalf("expected Planet with 2 constants and final Color with 3")
+	}
+	if c := planet.constants[1]; c.ordinal != 1 || c.body != earth || earth.name != "Planet$1" || earth.superclass != planet {
+		t.Errorf("expected EARTH to have a body extending Planet")
+	}
+	ctor := planet.constructors()[0]
+	if ctor.visibility != PRIVATE || planet.constants[0].constructor != ctor {
+		t.Errorf("expected the constants to call the private constructor")
+	}
+	if f := planet.fields[0]; f.name != "MERCURY" || !f.isStatic || !f.isFinal || f.kind.name != "Planet" {
+		t.Errorf("expected the constants to be the first static fields")
+	}
+	var names []string
+	for _, m := range color.methods {
+		names = append(names, m.signature())
+	}
+	if want := []string{"values()", "valueOf(String)", "ordinal()", "name()", "Color()"}; !slices.Equal(names, want) {
+		t.Errorf("expected methods %v, got %v", want, names)
+	}
+	sw := main.methods[0].statements[0].(*switchStmt)
+	if sw.enum != color {
+		t.Fatalf("expected the switch to be on Color")
+	}
+	if l := sw.lower(main); l.strategy != jumpTable || l.low != 0 || !slices.Equal(l.table, []int{0, 1, 2}) {
+		t.Errorf("expected a jump table on the ordinals, got %+v", l)
+	}
+	values := main.methods[1].statements[0].(*returnStmt).value.(*call).target.(*arrayIndex).array.(*call)
+	if values.method != color.methods[0] {
+		t.Errorf("expected Color.values() to call the synthesised method")
+	}
+}
+
+func TestParseEnumErrors(t *testing.T) {
+	tests := map[string]string{
+		"enum E { A; public E() {} }":                    "modifier public not allowed here",
+		"enum E { A; E() { super(); } }":                 "call to super not allowed in enum constructor",
+		"enum E { A } class B { void m() { new E(); } }": "enum classes may not be instantiated",
+		"enum E { A(1) }":                                "no suitable constructor found for E(int)",
+		"enum E { A B }":                                 "',', '}', or ';' expected",
+		"final enum E { A }":                             "modifier final not allowed here",
+		"enum E { A; abstract void m(); }":               "E is not abstract and does not override abstract method m() in E",
+		"enum E { A { }; abstract void m(); }":           "E$1 is not abstract and does not override abstract method m() in E",
+		"enum E { A; void values() {} }":                 "values() is already defined in class E",
+		"enum E { A, B, A }":                             "variable A is already defined in enum E",
+		"enum E { A; static int A; }":                    "variable A is already defined in enum E",
+		"enum E { A, B } class C { int m(E e) { return switch (e) { case A -> 1; }; } }": "the switch expression does not cover all possible input values",
+		"enum E { A } class C { void m(E e) { switch (e) { case B: } } }":                "an enum switch case label must be the unqualified name of an enumeration constant",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	parse(t, "enum E { A { void m() {} }, B { void m() {} }; abstract void m(); } class C { int m(E e) { return switch (e) { case A, B -> 1; }; } }")
+}
+
+func TestUnlowered(t *testing.T) {
+	ast := parse(t, "enum Color { RED } class Main { Color c; }")
+	errs := ast.Unlowered()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "enum Color is not supported by the backends yet") {
+		t.Errorf("expected only Color to be reported, got %v", errs)
+	}
+	ast = parse(t, "class A { static int n = 1; } class B { static int[] a; static { a = new int[2]; } } class C { static final int N = 1; int m = 2; }")
+	errs = ast.Unlowered()
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "the initialization of class A is not supported") || !strings.Contains(errs[1].Error(), "class B") {
+		t.Errorf("expected the initialization of A and B to be reported, got %v", errs)
+	}
+}
+
+func TestParseRecords(t *testing.T) {
+	ast := parse(t, `record Point(int x, int y) {
+  static final Point ORIGIN = new Point(0, 0);
+  Point {
+    if (x < 0) x = -x;
+  }
+  Point(int both) { this(both, both); }
+  public int x() { return x; }
+  double length() { return Math.sqrt(x * x + y * y); }
+}
+public record Pair(String first, String second) {}
+class Main {
+  int sum(Point p) { return p.x() + p.y(); }
+}`)
+	classes := ast.files[0].classes
+	point, pair, main := classes[0], classes[1], classes[2]
+	if !point.isRecord || !point.isFinal || len(point.components) != 2 {
+		t.Fatalf("expected Point to be a final record with 2 components")
+	}
+	if f := point.fields[0]; f.name != "x" || f.visibility != PRIVATE || !f.isFinal || f.isStatic {
+		t.Errorf("expected the components to be private final fields")
+	}
+	var names []string
+	for _, m := range point.methods {
+		names = append(names, fmt.Sprintf("%s:%t", m.signature(), m.implicit))
+	}
+	want := []string{"Point(int,int):false", "Point(int):false", "x():false", "length():false",
+		"y():true", "equals(Object):true", "hashCode():true", "toString():true"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected methods %v, got %v", want, names)
+	}
+	compact := point.methods[0]
+	if !compact.compact || !point.canonical(compact) {
+		t.Fatalf("expected Point {...} to be the compact canonical constructor")
+	}
+	var stmts []string
+	for _, stmt := range point.lowerConstructor(compact) {
+		stmts = append(stmts, fmt.Sprint(stmt))
+	}
+	if got := strings.Join(stmts, "; "); !strings.HasSuffix(got, "(this.x = x); (this.y = y)") {
+		t.Errorf("expected the fields to be assigned after the body, got %s", got)
+	}
+	if ctor := pair.constructors()[0]; !ctor.implicit || ctor.visibility != PUBLIC || !pair.canonical(ctor) {
+		t.Errorf("expected an implicit public canonical constructor for Pair")
+	}
+	sum := main.methods[0].statements[0].(*returnStmt).value.(*binary)
+	if sum.left.(*call).method != point.methods[2] || sum.right.(*call).method != point.methods[4] {
+		t.Errorf("expected p.x() and p.y() to call the accessors")
+	}
+}
+
+func TestParseRecordErrors(t *testing.T) {
+	tests := map[string]string{
+		"record R(int a) { int b; }":                                                "field declaration must be static",
+		"record R(int a) { R(String s) {} }":                                        "constructor is not canonical, so its first statement must invoke another constructor of class R",
+		"public record R(int a) { R { } }":                                          "invalid compact constructor in record R (attempting to assign stronger access privileges; was public)",
+		"record R(int a) { R(int a) { this(a, 1); } R(int a, int b) { this(a); } }": "invalid canonical constructor in record R (canonical constructor must not contain explicit constructor invocation)",
+		"record R(int a) { R { return; } }":                                         "compact constructor must not have return statements",
+		"record R(int a) { int a() { return a; } }":                                 "invalid accessor method in record R (accessor method must be public)",
+		"record R(int a) { public long a() { return a; } }":                         "return type of accessor method a() must match the type of record component a",
+		"record R(int a) extends Object {}":                                         "expected",
+		"abstract record R(int a) {}":                                               "modifier abstract not allowed here",
+		"record R(int a) { R {} public R(int b) {} }":                               "R(int) is already defined in class R",
+		"record R(int a) {} class B extends R {}":                                   "cannot inherit from final R",
+		"record R(int a) { void m() { a = 1; } }":                                   "cannot assign a value to final variable a",
+		"record R(int a) { R { this.a = 1; } }":                                     "cannot assign a value to final variable a",
+		"record R(int a) { void m(R r) { r.a++; } }":                                "cannot assign a value to final variable a",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	// The canonical constructor assigns the fields, the compact one only its parameters
+	parse(t, "record R(int a) { R(int a) { this.a = a; } } record S(int a) { S { a = Math.abs(a); } }")
+}
+
+// writeSources writes the files of a source root to a temporary directory, and returns its path
+func writeSources(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestParseImports(t *testing.T) {
+	ast := parse(t, `package app.main;
+import java.util.List;
+import java.util.*;
+import static java.lang.Math.max;
+import static java.lang.Math.*;
+class Main {}`)
+	f := ast.files[0]
+	if f.pkg.name != "app.main" || f.pkg.class("Main") != f.classes[0] {
+		t.Fatalf("expected Main in package app.main, got %q", f.pkg.name)
+	}
+	var imports []string
+	for _, imp := range f.imports {
+		imports = append(imports, fmt.Sprintf("%s.%s:%t", imp.pkgName, imp.alias, imp.isStatic))
+	}
+	want := []string{"java.util.List:false", "java.util.*:false", "java.lang.Math.max:true", "java.lang.Math.*:true"}
+	if !slices.Equal(imports, want) {
+		t.Errorf("expected imports %v, got %v", want, imports)
+	}
+}
+
+func TestParseSourceRoot(t *testing.T) {
+	root := writeSources(t, map[string]string{
+		"app/Main.java": `package app;
+import shapes.Square;
+import static util.Numbers.twice;
+class Main {
+  int m(Square s) { return twice(s.side()); }
+  Helper helper() { return new Helper(); }
+}`,
+		"app/Helper.java":    "package app; class Helper extends shapes.Shape {}",
+		"shapes/Shape.java":  "package shapes; public abstract class Shape { abstract int side(); }",
+		"shapes/Square.java": "package shapes; public class Square extends Shape { int side() { return 2; } }",
+		"util/Numbers.java":  "package util; public class Numbers { public static int twice(int n) { return 2 * n; } }",
+	})
+	p, err := NewFromDir(root, "ELF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, errs := p.Parse()
+	// Helper inherits the abstract side() without implementing it
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "Helper.java") || !strings.Contains(errs[0].Error(), "Helper is not abstract") {
+		t.Fatalf("expected one error in Helper.java, got %v", errs)
+	}
+	if len(ast.files) != 5 {
+		t.Fatalf("expected 5 files, got %d", len(ast.files))
+	}
+	app := ast.pkg("app")
+	main, helper := app.class("Main"), app.class("Helper")
+	shape, square := ast.pkg("shapes").class("Shape"), ast.pkg("shapes").class("Square")
+	if main == nil || helper == nil || square.superclass != shape || helper.superclass != shape {
+		t.Fatalf("expected the classes to be linked across files")
+	}
+	twice := main.methods[0].statements[0].(*returnStmt).value.(*call)
+	if twice.method != ast.pkg("util").class("Numbers").methods[0] {
+		t.Errorf("expected twice to resolve through the static import")
+	}
+	if side := twice.args[0].(*call); side.method != square.methods[0] {
+		t.Errorf("expected s.side() to resolve to Square.side")
+	}
+	if ctor := main.methods[1].statements[0].(*returnStmt).value.(*newObject).constructor; ctor != helper.constructors()[0] {
+		t.Errorf("expected new Helper() to resolve in the package of Main")
+	}
+}
+
+func TestParseImportErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"cannot find symbol: class Circle in package shapes": {
+			"a/A.java":      "package a; import shapes.Circle; class A {}",
+			"shapes/S.java": "package shapes; class S {}",
+		},
+		"a type with the same simple name S is already defined by the single-type-import of b.S": {
+			"a/A.java": "package a; import b.S; import c.S; class A {}",
+			"b/S.java": "package b; public class S {}",
+			"c/S.java": "package c; public class S {}",
+		},
+		"S is already defined in this compilation unit": {
+			"a/A.java": "package a; import b.S; class S {}",
+			"b/S.java": "package b; public class S {}",
+		},
+		"duplicate class: a.A": {
+			"a/A.java": "package a; class A {}",
+			"a/B.java": "package a; class A {}",
+		},
+		"'.' expected": {
+			"A.java": "import A; class B {}",
+		},
+	}
+	for msg, files := range tests {
+		p, err := NewFromDir(writeSources(t, files), "ELF")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, errs := p.Parse()
+		if len(errs) == 0 || !strings.Contains(errs[0].Error(), msg) {
+			t.Errorf("expected error %q, got %v", msg, errs)
+		}
+	}
+}
+
+func TestParseSourceRootErrorLimit(t *testing.T) {
+	p, err := NewFromDir(writeSources(t, map[string]string{
+		"a/A.java": "package a; import a.C; class A {}",
+		"a/B.java": "package a; class B { int m() { int a = ; int b = ; int c = ; return 1; } }",
+		"a/C.java": "package a; public class C {}",
+	}), "ELF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parsing stops in B.java, so C.java isn't parsed and the import of C isn't checked
+	_, errs := p.Parse()
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "B.java") {
+			t.Errorf("expected only the errors of B.java, got %v", err)
+		}
+	}
+}
+
+func TestParseArrays(t *testing.T) {
+	stmts, errs := parseBody(`int[] a = new int[3];
+int b[] = {1, 2, 3,};
+int[][] grid = new int[2][];
+byte[] bytes = {1, -128, 127};
+final String[][] names = {{"a"}, {"b", "c"}};
+var copy = new int[]{1, 2};
+int n = a.length + grid[0].length;
+a[p] = names[1][0].length();
+int[] x, y[];
+for (int[] row : grid) row[0] = 1;
+int first = b[0] + names[1].length;`)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	var got []string
+	for _, stmt := range stmts {
+		got = append(got, fmt.Sprint(stmt))
+	}
+	want := []string{
+		"int[] a = new int[3]",
+		"int b[] = {1, 2, 3}",
+		"int[][] grid = new int[2][]",
+		"byte[] bytes = {1, (-128), 127}",
+		"final String[][] names = {{\"a\"}, {\"b\", \"c\"}}",
+		"var copy = new int[]{1, 2}",
+	}
+	if !slices.Equal(got[:len(want)], want) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got[:len(want)], "\n"))
+	}
+	p := NewFromString("Main.java", "", "ELF")
+	local := func(i, j int) *localVar { return stmts[i].(*localVars).vars[j] }
+	if kind := local(5, 0).kind; kind.name != "int" || kind.dims != 1 {
+		t.Errorf("expected var copy to be inferred as int[], got %s", kind)
+	}
+	if kind := local(8, 1).kind; kind.String() != "int[][]" || local(8, 0).kind.String() != "int[]" {
+		t.Errorf("expected int[] x, y[] to declare int[] and int[][], got %s", kind)
+	}
+	if kind := p.typeOf(local(6, 0).value); kind == nil || kind.String() != "int" {
+		t.Errorf("expected the lengths to be ints, got %v", kind)
+	}
+	if kind := p.typeOf(stmts[7].(*expressionStmt).expr.(*assignment).target); kind == nil || kind.String() != "int" {
+		t.Errorf("expected a[p] to be an int, got %v", kind)
+	}
+	if row := stmts[9].(*forEach).variable; row.kind.String() != "int[]" {
+		t.Errorf("expected the rows of grid to be int[], got %s", row.kind)
+	}
+
+	main := &class{}
+	sum := local(10, 0).value.(*binary)
+	if check := main.lowerIndex(sum.left.(*arrayIndex)); check == nil {
+		t.Errorf("expected b[0] to be checked, b isn't final")
+	}
+	if check := main.lowerIndex(sum.right.(*fieldAccess).target.(*arrayIndex)); check != nil {
+		t.Errorf("expected names[1] to be in bounds of the final names")
+	}
+	if check := main.lowerIndex(stmts[7].(*expressionStmt).expr.(*assignment).target.(*arrayIndex)); check == nil || fmt.Sprint(check.index) != "p" {
+		t.Errorf("expected a[p] to be checked")
+	}
+}
+
+func TestParseArrayErrors(t *testing.T) {
+	tests := map[string]string{
+		"int a = {1};":                 "illegal initializer for int",
+		"var a = {1};":                 "cannot infer type for local variable a (array initializer needs an explicit target-type)",
+		"var a[] = new int[1];":        "'var' is not allowed as an element type of an array",
+		"int[] a = new int[];":         "array dimension missing",
+		"int[] a = new int[1]{1};":     "array creation with both dimension expression and initialization is illegal",
+		"int[][] a = new int[][1];":    "']' expected",
+		"int[] a = new int[1L];":       "incompatible types: possible lossy conversion from long to int",
+		"int[] a = {1}; a[true] = 1;":  "incompatible types: boolean cannot be converted to int",
+		"int a = 1; a[0] = 1;":         "array required, but int found",
+		"int[] a = {1}; a.length = 2;": "cannot assign a value to final variable length",
+		"byte[] a = {1, 200};":         "incompatible types: possible lossy conversion from int to byte",
+		"int[] a = {1, \"2\"};":        "incompatible types: String cannot be converted to int",
+		"int[] a = {1.5};":             "incompatible types: possible lossy conversion from double to int",
+		"Object o = new int();":        "'[' expected",
+	}
+	for src, msg := range tests {
+		expectError(t, inMethod(src), msg)
+	}
+}
+
+func TestParseGenerics(t *testing.T) {
+	ast := parse(t, `interface Cmp<T> { int cmp(T o); }
+class Box<T extends Comparable<T>> implements Cmp<Box<T>> {
+  T value;
+  Box(T value) { this.value = value; }
+  T get() { return value; }
+  static <U> U first(U a, U b) { return a; }
+  public int cmp(Box<T> o) { return value.compareTo(o.get()); }
+}
+class Main {
+  java.util.Map<String, java.util.List<Integer>> m;
+  void run(int x) {
+    Box<String> b = new Box<>("x");
+    String s = b.get();
+    Integer n = Box.<Integer>first(1, 2);
+    x >>= 1;
+  }
+}`)
+	classes := ast.files[0].classes
+	box, main := classes[1], classes[2]
+	if param := box.typeParams[0]; param.name != "T" || len(param.bounds) != 1 || param.bounds[0].String() != "Comparable" {
+		t.Errorf("expected T extends Comparable<T> erased to Comparable, got %v", param.bounds)
+	}
+	var sigs []string
+	for _, m := range box.methods {
+		sigs = append(sigs, fmt.Sprintf("%s %s", m.kind, m.signature()))
+	}
+	want := []string{"void Box(Comparable)", "Comparable get()", "Object first(Object,Object)", "int cmp(Box)", "int cmp(Object)"}
+	if !slices.Equal(sigs, want) {
+		t.Errorf("expected erased methods %v, got %v", want, sigs)
+	}
+	if bridge := box.methods[4]; !bridge.implicit || fmt.Sprint(bridge.statements) != "[return this.cmp(((Box) o))]" {
+		t.Errorf("expected a bridge calling cmp(Box), got %v", bridge.statements)
+	}
+	if kind := main.fields[0].kind; kind.String() != "java.util.Map" {
+		t.Errorf("expected the field type to be erased, got %s", kind)
+	}
+	stmts := main.methods[0].statements
+	if got := fmt.Sprint(stmts[0]); got != `Box b = new Box("x")` {
+		t.Errorf("expected the diamond to be erased, got %s", got)
+	}
+	for i, want := range []string{"String", "Integer"} {
+		if c := stmts[i+1].(*localVars).vars[0].value.(*call); c.cast == nil || c.cast.String() != want {
+			t.Errorf("expected %s to be cast to %s, got %v", c, want, c.cast)
+		}
+	}
+	if a := stmts[3].(*expressionStmt).expr.(*assignment); a.op != SHIFT_RIGHT_ASSIGN {
+		t.Errorf("expected x >>= 1 to stay a shift, got %s", a)
+	}
+}
+
+func TestParseGenericErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A<T> { static T t; }":                                       "non-static type variable T cannot be referenced from a static context",
+		"class A { } class B { A<String> a; }":                             "type A does not take parameters",
+		"class A { } class B { A a = new A<>(); }":                         "cannot infer type arguments for A; reason: cannot use '<>' with non-generic class A",
+		"class A<K, V> { } class B { A<String> a; }":                       "wrong number of type arguments; required 2",
+		"class A<T extends Number> { } class B { A<String> a; }":           "type argument String is not within bounds of type-variable T",
+		"class A<T> { void m(A<String> a) {} void m(A<Integer> a) {} }":    "name clash: m(A<Integer>) and m(A<String>) have the same erasure",
+		"class A<T extends U, U extends T> { }":                            "cyclic inheritance involving T",
+		"class A<T> { void m() { A<String> a = new A<Integer>(); } }":      "incompatible types: A<Integer> cannot be converted to A<String>",
+		"class A<T> { A<int> a; }":                                         "unexpected type; required: reference, found: int",
+		"class A<T> { Object o = new T(); }":                               "unexpected type; required: class, found: type parameter T",
+		"interface I<T> { void m(T t); } class A implements I<String> { }": "A is not abstract and does not override abstract method m(String) in I",
+		"class A<T> { Object o = new A<String>[1]; }":                      "generic array creation",
+		"class A<T> { Object o = new A<>[1]; }":                            "cannot create array with '<>'",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	box := "class Box<T> { T t; void put(T t) { this.t = t; } T get() { return t; } } "
+	members := map[string]string{
+		"class A { void m(Box<String> b) { b.put(1); } }":            "incompatible types: int cannot be converted to String",
+		"class A { void m(Box<String> b) { Integer i = b.get(); } }": "incompatible types: String cannot be converted to Integer",
+		"class A { void m(Box<String> b) { int i; i = b.get(); } }":  "incompatible types: String cannot be converted to int",
+		"class A { String s = new Box<Integer>().get(); }":           "incompatible types: Integer cannot be converted to String",
+	}
+	for src, msg := range members {
+		expectError(t, box+src, msg)
+	}
+	parse(t, box+`class A {
+  void m(Box<String> s, Box<Integer> i) {
+    s.put("x");
+    i.put(1);
+    int n = i.get();
+    long l = i.get();
+    Object o = s.get();
+    CharSequence cs = s.get();
+  }
+}`)
+}
+
+func TestParseExceptions(t *testing.T) {
+	ast := parse(t, `class Res implements AutoCloseable {
+  public void close() throws java.io.IOException {}
+}
+class ParseError extends Exception {
+  ParseError(String msg) { super(msg); }
+}
+class Main {
+  int parse(String s) throws ParseError {
+    if (s == null) throw new ParseError("empty");
+    return 1;
+  }
+  void run() {
+    try (Res r = new Res()) {
+      parse("x");
+    } catch (ParseError | java.io.IOException e) {
+      throw new IllegalStateException();
+    } finally {
+      int done = 1;
+    }
+  }
+  void rethrow() throws ParseError {
+    try { parse("y"); } catch (Exception e) { throw e; }
+  }
+}`)
+	main := ast.files[0].classes[2]
+	if throws := main.methods[0].throws; len(throws) != 1 || throws[0].name != "ParseError" {
+		t.Errorf("expected parse to throw ParseError, got %v", throws)
+	}
+	try := main.methods[1].statements[0].(*tryStmt)
+	want := `try (Res r = new Res()) { parse("x") } catch (ParseError | java.io.IOException e) { throw new IllegalStateException() } finally { int done = 1 }`
+	if got := fmt.Sprint(try); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if kind := try.catches[0].param.kind; kind.String() != "Exception" {
+		t.Errorf("expected the multi-catch parameter to be an Exception, got %s", kind)
+	}
+	want = "try { Res r = new Res(); Throwable #primaryExc = null; try { parse(\"x\") } " +
+		"catch (Throwable #t) { (#primaryExc = #t); throw #t } " +
+		"finally { if ((r != null)) { if ((#primaryExc != null)) { try { r.close() } catch (Throwable #suppressedExc) { #primaryExc.addSuppressed(#suppressedExc) } } else { r.close() } } } } " +
+		"catch (ParseError | java.io.IOException e) { throw new IllegalStateException() } finally { int done = 1 }"
+	if got := fmt.Sprint(try.lowerResources()); got != want {
+		t.Errorf("expected the resource to be closed in a finally block\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestParseExceptionErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A { void m() { throw new Exception(); } }":                                                                         "unreported exception Exception; must be caught or declared to be thrown",
+		"class A { void m() throws java.io.IOException { } void n() { m(); } }":                                                   "unreported exception java.io.IOException; must be caught or declared to be thrown",
+		"class A { void m() { try { } catch (java.io.IOException e) { } } }":                                                      "exception java.io.IOException is never thrown in body of corresponding try statement",
+		"class A { void m() { try { } catch (Exception e) { } catch (RuntimeException e) { } } }":                                 "exception RuntimeException has already been caught",
+		"class A { void m() { try { } catch (IllegalStateException | RuntimeException e) { } } }":                                 "Alternatives in a multi-catch statement cannot be related by subclassing",
+		"class A { void m() { try { } } }":                                                                                        "'try' without 'catch', 'finally' or resource declarations",
+		"class A { void m() { catch (Exception e) { } } }":                                                                        "'catch' without 'try'",
+		"class A { void m() { throw 1; } }":                                                                                       "incompatible types: int cannot be converted to Throwable",
+		"class A { void m() { try { } catch (IllegalStateException | IllegalArgumentException e) { e = null; } } }":               "multi-catch parameter e may not be assigned",
+		"class R implements AutoCloseable { public void close() { } } class A { void m() { try (R r = new R()) { r = null; } } }": "auto-closeable resource r may not be assigned",
+		"class R { } class A { void m() { try (R r = new R()) { } } }":                                                            "incompatible types: try-with-resources not applicable to variable type",
+		"class A { void m() { try (AutoCloseable r = null) { } } }":                                                               "unreported exception Exception; must be caught or declared to be thrown",
+		"class A { void m() throws Exception { } } class B extends A { void m() throws Throwable { } }":                           "m() in B cannot override m() in A; overridden method does not throw Throwable",
+		"class A { void m() { try { throw new Exception(); } catch (Exception e) { throw e; } } }":                                "unreported exception Exception",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+}
+
+func TestParseLambdas(t *testing.T) {
+	ast := parse(t, `interface Op { int apply(int a, int b); }
+class Main {
+  int base = 1;
+  int add(int x) { return x + base; }
+  void run(Runnable r) { r.run(); }
+  void m(int k) {
+    Op plus = (a, b) -> a + b + k;
+    Op mul = (int a, int b) -> { return a * b; };
+    Comparator<String> byLength = (x, y) -> x.length() - y.length();
+    Function<Integer, Integer> inc = this::add;
+    run(() -> System.out.println(plus.apply(1, 2)));
+    switch (k) { case 1 -> run(null); default -> { } }
+  }
+}`)
+	main := ast.files[0].classes[1]
+	m := main.methods[2]
+	plus := m.statements[0].(*localVars).vars[0].value.(*lambda)
+	if got := fmt.Sprint(plus); got != "(a, b) -> ((a + b) + k)" {
+		t.Errorf("expected the lambda (a, b) -> ((a + b) + k), got %s", got)
+	}
+	if len(plus.captured) != 1 || plus.captured[0].name != "k" || plus.params[0].kind.String() != "int" {
+		t.Errorf("expected the lambda to capture k and take ints, got %v and %s", plus.captured, plus.params[0].kind)
+	}
+	if byLength := m.statements[2].(*localVars).vars[0].value.(*lambda); byLength.params[0].kind.String() != "String" {
+		t.Errorf("expected the comparator to take Strings, got %s", byLength.params[0].kind)
+	}
+	if ref := m.statements[3].(*localVars).vars[0].value.(*methodRef); ref.method != main.methods[0] || fmt.Sprint(ref.lambda()) != "(a0) -> this.add(a0)" {
+		t.Errorf("expected this::add to refer to add, got %v", ref.lambda())
+	}
+	arg := m.statements[4].(*expressionStmt).expr.(*call).args[0].(*lambda)
+	if arg.kind == nil || arg.kind.name != "Runnable" || arg.function.name != "run" {
+		t.Errorf("expected the argument to implement Runnable, got %v", arg.kind)
+	}
+
+	lifted, impl, create := main.lowerLambda(plus, m, 0)
+	if lifted.name != "lambda$m$0" || len(lifted.parameters) != 3 || fmt.Sprint(create) != "new Main$$Lambda$0(this, k)" {
+		t.Errorf("expected the body to take k, a and b, created with new Main$$Lambda$0(this, k), got %s and %s", lifted.name, create)
+	}
+	if got := fmt.Sprint(impl.methods[1].statements); got != "[return this.this$0.lambda$m$0(this.k, a0, a1)]" {
+		t.Errorf("expected apply to call the body with the captured k, got %s", got)
+	}
+}
+
+func TestParseLambdaErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A { void m() { int x = 1; x++; Runnable r = () -> System.out.println(x); } }":                   "local variables referenced from a lambda expression must be final or effectively final",
+		"class A { void m() { Runnable r = () -> 5; } }":                                                       "incompatible types: bad return type in lambda expression: int cannot be converted to void",
+		"class A { void m() { Supplier<Integer> s = () -> { return; }; } }":                                    "incompatible types: bad return type in lambda expression: missing return value",
+		"class A { void m() { Object o = () -> { }; } }":                                                       "incompatible types: Object is not a functional interface",
+		"class A { void m() { Runnable r = A::missing; } }":                                                    "invalid method reference: cannot find symbol missing in A",
+		"interface Op { int apply(int a, int b); } class A { Op o = (a, int b) -> a; }":                        "invalid lambda parameter declaration (cannot mix implicitly-typed and explicitly-typed parameters)",
+		"interface Op { int apply(int a, int b); } class A { Op o = a -> a; }":                                 "incompatible types: incompatible parameter types in lambda expression",
+		"class E extends Exception { } class A { void t() throws E { } void m() { Runnable r = () -> t(); } }": "unreported exception E; must be caught or declared to be thrown",
+		"class A { void m() { while (true) { Runnable r = () -> { break; }; } } }":                             "break outside switch or loop",
+		"interface S { String get(); } class A { S s = () -> 1; }":                                             "incompatible types: bad return type in lambda expression: int cannot be converted to String",
+		"interface S { int get(); } class A { S s = () -> { return \"s\"; }; }":                                "incompatible types: bad return type in lambda expression: String cannot be converted to int",
+		"interface S { int get(); } class A { S s = () -> { }; }":                                              "incompatible types: bad return type in lambda expression: missing return value",
+		"interface S { int get(); } class A { S s = () -> { while (true) { break; } }; }":                      "incompatible types: bad return type in lambda expression: missing return value",
+		"interface S { int get(); } class A { S s = () -> { if (true) return 1; }; }":                          "lambda body is neither value nor void compatible",
+		"class A { void m() { var v = () -> { }; } }":                                                          "cannot infer type for local variable v (lambda expression needs an explicit target-type)",
+		"class A { void m() { var v = A::m; } }":                                                               "cannot infer type for local variable v (method reference needs an explicit target-type)",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	parse(t, `interface S { int get(); }
+class A {
+  static final boolean ON = true;
+  S loop = () -> { while (true) { } };
+  S forever = () -> { for (;;) { if (ON) return 1; } };
+  S thrown = () -> { throw new IllegalStateException(); };
+  S branches = () -> { if (ON) { return 1; } else { return 2; } };
+  S widened = () -> 'c';
+  S labeled = () -> { outer: while (ON) { while (true) { break outer; } } return 0; };
+  S switched = () -> { switch (1) { case 1: return 1; default: throw new IllegalStateException(); } };
+  S tried = () -> { try { return 1; } finally { } };
+}`)
+}
+
+func TestParseNestedClasses(t *testing.T) {
+	ast := parse(t, `interface Greeter { String greet(String name); }
+class Outer {
+  int count = 3;
+  static class Nested { int get() { return 1; } }
+  class Inner {
+    int sum() { return count + Outer.this.count + bump(); }
+    class Deeper { int z() { return count; } }
+  }
+  int bump() { return count++; }
+  static Outer.Nested nested() { return new Outer.Nested(); }
+  void run(int k) {
+    int local = k * 2;
+    class Counter { int next() { return local + k; } }
+    Greeter g = new Greeter() { public String greet(String name) { return name + local; } };
+    Inner.Deeper d = new Inner().new Deeper();
+  }
+}`)
+	f := ast.files[0]
+	outer := f.class("Outer")
+	nested, inner := f.class("Outer$Nested"), f.class("Outer$Inner")
+	if nested == nil || nested.inner || inner == nil || !inner.inner || len(outer.classes) != 2 {
+		t.Fatalf("expected the static Outer$Nested and the inner Outer$Inner, got %v", outer.classes)
+	}
+	deeper := f.class("Outer$Inner$Deeper")
+	if deeper == nil || fmt.Sprint(deeper.outerInstance(outer, deeper.pos)) != "this.this$0.this$0" {
+		t.Fatalf("expected Outer$Inner$Deeper to reach Outer through this.this$0.this$0")
+	}
+	if id := deeper.methods[0].statements[0].(*returnStmt).value.(*identifier); id.outer != outer {
+		t.Errorf("expected count to be a field of Outer, got %v", id.outer)
+	}
+	if c := inner.methods[0].statements[0].(*returnStmt).value.(*binary).right.(*call); c.outer != outer || c.method != outer.methods[0] {
+		t.Errorf("expected bump() to call Outer.bump, got %v", c.method)
+	}
+	if got := fmt.Sprint(outer.methods[1].statements[0]); got != "return new Outer$Nested()" {
+		t.Errorf("expected Outer.Nested to name Outer$Nested, got %s", got)
+	}
+
+	run := outer.methods[2]
+	counter := run.statements[1].(*localClass).class
+	if counter.binaryName() != "Outer$1Counter" || len(counter.captured) != 2 || counter.captured[0].name != "local" || counter.captured[1].name != "k" {
+		t.Errorf("expected Outer$1Counter to capture local and k, got %s capturing %d locals", counter.binaryName(), len(counter.captured))
+	}
+	if got := fmt.Sprint(counter.lowerConstructor(counter.constructors()[0])); got != "[(this.this$0 = this$0) (this.val$local = val$local) (this.val$k = val$k) super()]" {
+		t.Errorf("expected the constructor to store the enclosing instance and the captured locals, got %s", got)
+	}
+	anon := run.statements[2].(*localVars).vars[0].value.(*newObject).body
+	if anon == nil || anon.name != "Outer$1" || len(anon.implements) != 1 || anon.implements[0].name != "Greeter" {
+		t.Errorf("expected the anonymous class Outer$1 implementing Greeter, got %v", anon)
+	}
+	if d := run.statements[3].(*localVars).vars[0].value.(*newObject); d.kind.name != "Outer$Inner$Deeper" || d.outer == nil {
+		t.Errorf("expected a qualified creation of Outer$Inner$Deeper, got %s", d)
+	}
+}
+
+func TestParseNestedClassErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A { int x; static class B { int f() { return x; } } }":                  "non-static variable x cannot be referenced from a static context",
+		"class A { void m() { } static class B { void f() { m(); } } }":                "non-static method m() cannot be referenced from a static context",
+		"class A { class B { } static class C { Object f() { return new B(); } } }":    "non-static variable this cannot be referenced from a static context",
+		"class A { static Object f() { return A.this; } }":                             "non-static variable this cannot be referenced from a static context",
+		"class A { Object f() { return String.this; } }":                               "not an enclosing class: String",
+		"class A { static class B { } void m(A a) { Object o = a.new B(); } }":         "qualified new of static class",
+		"class A { void m() { class L { } class L { } } }":                             "duplicate class: L",
+		"class A { class B { } static void m() { new B(); } }":                         "non-static variable this cannot be referenced from a static context",
+		"class A { class B { } static Object o = new B(); }":                           "non-static variable this cannot be referenced from a static context",
+		"class A { class B { } static { new B(); } }":                                  "non-static variable this cannot be referenced from a static context",
+		"class A { class A { } }":                                                      "class A is already defined in package unnamed package",
+		"package p; class A { static class B { interface A { } } }":                    "class A is already defined in package p",
+		"class O { class A { void m() { class A { } } } }":                             "class A is already defined in class O",
+		"class A { void m() { private class L { } } }":                                 "modifier private not allowed here",
+		"class A { void m() { int x = 1; x = 2; class L { int f() { return x; } } } }": "local variables referenced from an inner class must be final or effectively final",
+		"interface I { } class A { Object o = new I(1) { }; }":                         "anonymous class implements interface; cannot have arguments",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+}
+
+func TestParseInitializers(t *testing.T) {
+	ast := parse(t, `class Base { static int b = 1; }
+class Table extends Base {
+  static final int SIZE = 4;
+  static int[] squares = new int[SIZE];
+  static {
+    for (int i = 0; i < SIZE; i++) { squares[i] = i * i; }
+  }
+  static int count;
+  int id;
+  { id = ++count; }
+  String name = "t" + id;
+  static int square(int i) { return squares[i]; }
+}
+class Main {
+  void run() {
+    int x = Table.square(2);
+    Table t = new Table();
+    int c = Table.count;
+    int s = Table.SIZE;
+    Table u = new Table();
+  }
+}`)
+	f := ast.files[0]
+	table := f.class("Table")
+	if len(table.blocks) != 2 || !table.blocks[0].isStatic || table.blocks[0].index != 2 || table.blocks[1].isStatic || table.blocks[1].index != 4 {
+		t.Fatalf("expected a static and an instance initializer, got %v", table.blocks)
+	}
+	want := "[init Base (Table.squares = new int[SIZE]) { for (int i = 0; (i < SIZE); (i++)) { (squares[i] = (i * i)) } }]"
+	if got := fmt.Sprint(table.clinit().statements); got != want {
+		t.Errorf("expected the class initializer %s, got %s", want, got)
+	}
+	if got := fmt.Sprint(table.lowerConstructor(table.constructors()[0])); got != "[super() { (id = (++count)) } (this.name = (\"t\" + id))]" {
+		t.Errorf("expected the instance initializer to run before name is initialized, got %s", got)
+	}
+
+	main := f.class("Main")
+	var inits []string
+	for _, stmt := range main.methods[0].statements {
+		inspect(stmt, func(n Node) bool {
+			if init := main.lowerInit(f, n); init != nil {
+				inits = append(inits, fmt.Sprintf("%s: %s", n, init))
+			}
+			return true
+		})
+	}
+	want = "[Table.square(2): init Table new Table(): init Table Table.count: init Table new Table(): init Table]"
+	if got := fmt.Sprint(inits); got != want {
+		t.Errorf("expected the uses of Table to initialize it, except the constant SIZE, got %s", got)
+	}
+}
+
+func TestParseInitializerErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A { static { return; } }":                                      "return outside method",
+		"class A { static { x = 1; int y = x; } static int x; }":              "illegal forward reference",
+		"interface I { static { } }":                                          "initializers not allowed in interfaces",
+		"record R(int a) { { } }":                                             "instance initializers not allowed in records",
+		"class A { public { } }":                                              "illegal start of type",
+		"class A { static { Object o = this; } }":                             "non-static variable this cannot be referenced from a static context",
+		"class E extends Exception { } class A { static { throw new E(); } }": "unreported exception E; must be caught or declared to be thrown",
+		"class E extends Exception { } class A { { throw new E(); } A() throws E { } A(int x) { } }":          "unreported exception E; must be caught or declared to be thrown",
+		"class E extends Exception { } class A { static int f() throws E { return 1; } static int x = f(); }": "unreported exception E; must be caught or declared to be thrown",
+		"class E extends Exception { } class A { int f() throws E { return 1; } int x = f(); }":               "unreported exception E; must be caught or declared to be thrown",
+		"class E extends Exception { } class A { int f() throws E { return 1; } int x = f(); A(int y) { } }":  "unreported exception E; must be caught or declared to be thrown",
+		"class E extends Exception { } class A { A() throws E { } } class B extends A { }":                    "unreported exception E in default constructor",
+		"class E extends Exception { } class A { A() throws E { } } class B extends A { B(int x) { } }":       "unreported exception E; must be caught or declared to be thrown",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	for _, src := range []string{
+		"class E extends Exception { } class A { { throw new E(); } A() throws E { } }",
+		"class E extends Exception { } class A { int f() throws E { return 1; } int x = f(); A() throws E { } }",
+		"class E extends Exception { } class A { A() throws E { } } class B extends A { B() throws Exception { } }",
+		"class E extends Exception { } class A { Object o = new Object() { int x = f(); int f() throws E { return 1; } }; }",
+	} {
+		if _, errs := NewFromString("A.java", src, "ELF").Parse(); len(errs) > 0 {
+			t.Errorf("%s: expected the initializers and constructors to throw what the constructors declare, got %v", src, errs)
+		}
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	p := NewFromString("Main.java", `import java.lang.annotation.*;
+@Target({ElementType.METHOD, ElementType.TYPE})
+@interface Info { String author() default "me"; int version(); String[] tags() default {}; }
+@Deprecated class Old { static void run() { } }
+class Lib { @Deprecated static int LIMIT = 3; @Deprecated void legacy() { } }
+@FunctionalInterface interface Op { int apply(int a); }
+@Info(version = 2, tags = {"a", "b"})
+public class Main implements Comparable<Main> {
+  @Override public String toString() { return "Main"; }
+  @Override public int compareTo(Main o) { return 0; }
+  @Info(version = 1) void m(@Deprecated final Lib l) {
+    @SuppressWarnings("unused") int x = Lib.LIMIT;
+    Old.run();
+  }
+  @SuppressWarnings("deprecation") void quiet(Lib l) { l.legacy(); }
+}`, "ELF")
+	ast, errs := p.Parse()
+	for _, err := range errs {
+		t.Error(err)
+	}
+	f := ast.files[0]
+	info := f.class("Info")
+	if info == nil || !info.isAnnotation || !info.isInterface || len(info.methods) != 3 || info.methods[1].defaultValue != nil {
+		t.Fatalf("expected the annotation interface Info with 3 elements, got %v", info)
+	}
+	if got := fmt.Sprint(info.methods[0].defaultValue, info.targets()); got != `"me" [METHOD TYPE]` {
+		t.Errorf("expected author to default to \"me\" and Info to apply to methods and types, got %s", got)
+	}
+	main := f.class("Main")
+	if got := fmt.Sprint(main.annotations); got != `[@Info(version = 2, tags = {"a", "b"})]` {
+		t.Errorf("expected Main to be annotated with @Info, got %s", got)
+	}
+	m := main.methods[2]
+	if got := fmt.Sprint(m.annotations, m.parameters[0].annotations, m.parameters[0].isFinal); got != "[@Info(version = 1)] [@Deprecated] true" {
+		t.Errorf("expected the annotations of m and its final parameter, got %s", got)
+	}
+	if got := fmt.Sprint(m.statements[0].(*localVars).annotations); got != `[@SuppressWarnings(value = "unused")]` {
+		t.Errorf("expected the local to be annotated with @SuppressWarnings, got %s", got)
+	}
+	want := []string{"[deprecation] LIMIT in Lib has been deprecated", "[deprecation] Old in unnamed package has been deprecated"}
+	warnings := p.Warnings()
+	if len(warnings) != len(want) {
+		t.Fatalf("expected %d warnings, got %v", len(want), warnings)
+	}
+	for i, w := range warnings {
+		if !strings.Contains(w.Error(), want[i]) {
+			t.Errorf("expected warning %q, got %v", want[i], w)
+		}
+	}
+}
+
+func TestParseAnnotationErrors(t *testing.T) {
+	tests := map[string]string{
+		"class A { @Override void m() { } }":                                                        "method does not override or implement a method from a supertype",
+		"class A { @Override public boolean equals(A o) { return true; } }":                         "method does not override or implement a method from a supertype",
+		"interface I { } class A implements I { @Override static void s() { } }":                    "method does not override or implement a method from a supertype",
+		"@FunctionalInterface interface I { void a(); void b(); }":                                  "Unexpected @FunctionalInterface annotation: I is not a functional interface",
+		"@FunctionalInterface class C { }":                                                          "Unexpected @FunctionalInterface annotation: C is not a functional interface",
+		"@Override class C { }":                                                                     "annotation type not applicable to this kind of declaration",
+		"@interface T { int v(); } @T class C { }":                                                  "annotation @T is missing a default value for the element 'v'",
+		"@interface T { int v(); } @T(w = 1, v = 2) class C { }":                                    "cannot find symbol: method w() in T",
+		"@interface T { } @T @T class C { }":                                                        "T is not a repeatable annotation type",
+		"@interface T { Object v(); }":                                                              "invalid type for annotation member",
+		"@interface T { int v(int x); }":                                                            "@interface members may not have parameters",
+		"class X { } @X class C { }":                                                                "X is not an annotation type",
+		"import java.lang.annotation.*; @Target(ElementType.FIELD) @interface F { } @F class C { }": "annotation type not applicable to this kind of declaration",
+		"@interface A { int value(); } @A(\"s\") class C { }":                                       "incompatible types: String cannot be converted to int",
+		"@interface A { String value(); } @A(1) class C { }":                                        "incompatible types: int cannot be converted to String",
+		"@interface A { byte value(); } @A(300) class C { }":                                        "incompatible types: possible lossy conversion from int to byte",
+		"@interface A { int[] value(); } @A({1, \"s\"}) class C { }":                                "incompatible types: String cannot be converted to int",
+		"@interface A { int value(); } @A({1}) class C { }":                                         "illegal initializer for int",
+		"enum E { X } @interface A { E value(); } @A(1) class C { }":                                "an enum annotation value must be an enum constant",
+		"enum E { X } @interface A { E value(); } @A(E.Y) class C { }":                              "an enum annotation value must be an enum constant",
+		"@interface B { } @interface A { B value(); } @A(1) class C { }":                            "annotation value must be an annotation",
+		"@interface B { } @interface D { } @interface A { B value(); } @A(@D) class C { }":          "incompatible types: D cannot be converted to B",
+		"@interface B { int v(); } @interface A { B value(); } @A(@B(v = \"s\")) class C { }":       "incompatible types: String cannot be converted to int",
+		"@interface A { int value() default \"s\"; }":                                               "incompatible types: String cannot be converted to int",
+	}
+	for src, msg := range tests {
+		expectError(t, src, msg)
+	}
+	values := `enum E { X, Y }
+@interface B { int v() default 1; }
+@interface A { int value(); byte b() default 1; long l() default 'c'; String[] s() default "one"; E e() default E.X; E[] es() default {E.X, E.Y}; B nested() default @B(v = 2); }
+class C { static final int N = 3; @A(N + 1) void m() { } @A(value = 1, s = {"a", "b"}, b = 127) void n() { } }`
+	if _, errs := NewFromString("A.java", values, "ELF").Parse(); len(errs) > 0 {
+		t.Errorf("expected the element values to match their elements, got %v", errs)
+	}
+	for _, src := range []string{
+		"class B { void m() { } } class A extends B { @Override void m() { } }",
+		"record R(int x) { @Override public int x() { return x; } }",
+		"abstract class B<T> { abstract void put(T t); } class A extends B<String> { @Override void put(String s) { } }",
+		"class A { void m() { Runnable r = new Runnable() { @Override public void run() { } }; } }",
+	} {
+		if _, errs := NewFromString("A.java", src, "ELF").Parse(); len(errs) > 0 {
+			t.Errorf("%s: expected @Override to be accepted, got %v", src, errs)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package parser
+
+import "slices"
+
+// parseRecordHeader parses the components of a record following its name, JLS 8.10.1.
+// Every component becomes a private final field of the record
+func (p *Parser) parseRecordHeader() bool {
+	r := p.class
+	// Records are implicitly final
+	r.isFinal = true
+	if !p.expectNext(OPAREN) {
+		return false
+	}
+	r.components = p.parseParameters()
+	for _, c := range r.components {
+		p.addField(&field{decl: &decl{
+			node:      c.name,
+			kind:      c.kind,
+			isFinal:   true,
+			modifiers: modifiers{visibility: PRIVATE},
+		}})
+	}
+	return true
+}
+
+// isCompactConstructor reports whether the declaration at peekToken is the compact constructor of a record,
+// its name followed by the body
+func (p *Parser) isCompactConstructor() bool {
+	return p.class.isRecord && p.peekToken.value == p.class.name && p.peekAt(2).kind == OBRACE
+}
+
+// parseCompactConstructor parses the body of a compact constructor, following its opening brace.
+// The parameters are the components of the record, which are assigned to the fields after the body, see lowerConstructor
+func parseCompactConstructor(p *Parser) parseStateFn {
+	p.method = &method{
+		decl:       p.decl,
+		parameters: slices.Clone(p.class.components),
+		compact:    true,
+	}
+	return p.enterMethod()
+}
+
+// recordMembers returns the members synthesised for record c that it doesn't declare, JLS 8.10.3:
+// the canonical constructor, the accessors of the components, equals, hashCode and toString
+func recordMembers(c *class) []*method {
+	var methods []*method
+	synthesise := func(name string, kind *typeRef, isConstructor bool, params ...*parameter) {
+		// The canonical constructor has the access of the record, JLS 8.10.4
+		visibility := PUBLIC
+		if isConstructor {
+			visibility = c.visibility
+		}
+		m := &method{
+			decl: &decl{
+				node:          node{name, c.pos},
+				kind:          kind,
+				isConstructor: isConstructor,
+				modifiers:     modifiers{visibility: visibility},
+			},
+			parameters: params,
+			implicit:   true,
+		}
+		if !slices.ContainsFunc(c.methods, func(o *method) bool { return o.isConstructor == isConstructor && o.signature() == m.signature() }) {
+			methods = append(methods, m)
+		}
+	}
+	synthesise(c.name, primitiveType(VOID, c.pos), true, c.components...)
+	for _, comp := range c.components {
+		synthesise(comp.name.name, comp.kind, false)
+	}
+	synthesise("equals", primitiveType(BOOLEAN, c.pos), false, &parameter{name: node{"o", c.pos}, kind: classType("Object", c.pos)})
+	synthesise("hashCode", primitiveType(INT, c.pos), false)
+	synthesise("toString", classType("String", c.pos), false)
+	return methods
+}
+
+// canonical reports whether m is the canonical constructor of record c, whose parameters match the components
+func (c *class) canonical(m *method) bool {
+	return m.isConstructor && slices.EqualFunc(m.parameters, c.components, func(a, b *parameter) bool { return a.kind.String() == b.kind.String() })
+}
+
+// checkRecord reports the members of record c that break the rules of JLS 8.10
+func (p *Parser) checkRecord(c *class) {
+	for _, f := range c.fields[len(c.components):] {
+		if !f.isStatic {
+			p.errorAt(f.pos, "field declaration must be static")
+		}
+	}
+	for _, m := range c.methods {
+		switch {
+		case m.implicit:
+		case c.canonical(m):
+			p.checkCanonicalConstructor(c, m)
+		case m.isConstructor:
+			if call := m.constructorCall(); call == nil || call.name != "this" {
+				p.errorAt(m.pos, "constructor is not canonical, so its first statement must invoke another constructor of class %s", c.name)
+			}
+		case len(m.parameters) == 0 && !m.isStatic:
+			i := slices.IndexFunc(c.components, func(comp *parameter) bool { return comp.name.name == m.name })
+			switch {
+			case i < 0:
+			case m.visibility != PUBLIC:
+				p.errorAt(m.pos, "invalid accessor method in record %s (accessor method must be public)", c.name)
+			case m.kind.String() != c.components[i].kind.String():
+				p.errorAt(m.pos, "invalid accessor method in record %s (return type of accessor method %s() must match the type of record component %s)", c.name, m.name, m.name)
+			}
+		}
+	}
+}
+
+// checkCanonicalConstructor reports a canonical constructor m of record c that is less accessible than c,
+// calls another constructor, or returns from a compact constructor
+func (p *Parser) checkCanonicalConstructor(c *class, m *method) {
+	kind := "canonical"
+	if m.compact {
+		kind = "compact"
+	}
+	switch {
+	case slices.Index(accessRanks, m.visibility) < slices.Index(accessRanks, c.visibility):
+		p.errorAt(m.pos, "invalid %s constructor in record %s (attempting to assign stronger access privileges; was %s)", kind, c.name, c.visibility)
+	case m.constructorCall() != nil:
+		p.errorAt(m.pos, "invalid %s constructor in record %s (canonical constructor must not contain explicit constructor invocation)", kind, c.name)
+	case m.compact:
+		inspectAll(m.statements, func(n Node) bool {
+			if _, ok := n.(*returnStmt); ok {
+				p.errorAt(n.Position(), "invalid compact constructor in record %s (compact constructor must not have return statements)", c.name)
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,578 @@
+package parser
+
+import "slices"
+
+// parseStatement parses the statement following the current token, and stops at its last token.
+// nil is returned when the end of the file is reached before the statement ends
+func (p *Parser) parseStatement() Statement {
+	switch p.peekToken.kind {
+	case OBRACE:
+		p.nextToken()
+		return p.parseBlock()
+	case SEMICOLON:
+		p.nextToken()
+		return &empty{statement{p.token.node()}}
+	case IF:
+		return p.parseIf()
+	case WHILE:
+		return p.parseWhile()
+	case DO:
+		return p.parseDoWhile()
+	case FOR:
+		return p.parseFor()
+	case BREAK, CONTINUE:
+		return p.parseJump()
+	case RETURN:
+		return p.parseReturn()
+	case THROW:
+		return p.parseThrow()
+	case TRY:
+		return p.parseTry()
+	case CATCH, FINALLY:
+		p.nextToken()
+		p.errorf("'%s' without 'try'", p.token.kind)
+		return nil
+	case SWITCH:
+		p.nextToken()
+		s := &switchStmt{statement: statement{p.token.node()}}
+		if !p.parseSwitch(&s.switchBlock, s) {
+			return nil
+		}
+		return s
+	case IDENTIFIER:
+		if p.peekAt(2).kind == COLON {
+			return p.parseLabeled()
+		}
+		if p.isYield() {
+			return p.parseYield()
+		}
+	}
+	if p.isLocalClass() {
+		return p.parseLocalClass()
+	}
+	if p.isLocalVarDecl() {
+		return p.parseLocalVars()
+	}
+	stmt := p.parseExpressionStmt()
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return stmt
+}
+
+// parseExpressionStmt parses an expression that is used as a statement, without the trailing semicolon
+func (p *Parser) parseExpressionStmt() *expressionStmt {
+	expr := p.parseExpression(lowest)
+	if expr == nil {
+		return &expressionStmt{}
+	}
+	if !isStatementExpression(expr) {
+		p.errorAt(expr.Position(), "not a statement")
+	}
+	return &expressionStmt{statement: statement{node{expr.Name(), expr.Position()}}, expr: expr}
+}
+
+// parseBody parses the body of a control flow statement, which can't be a declaration
+func (p *Parser) parseBody() Statement {
+	stmt := p.parseStatement()
+	switch decl := stmt.(type) {
+	case *localVars:
+		p.errorAt(decl.pos, "variable declaration not allowed here")
+	case *localClass:
+		p.errorAt(decl.pos, "class, interface or enum declaration not allowed here")
+	}
+	return stmt
+}
+
+// parseBlock parses the statements up to the closing brace, the current token must be the opening brace
+func (p *Parser) parseBlock() *block {
+	b := &block{statement: statement{p.token.node()}}
+	p.openScope()
+	defer p.closeScope()
+	for p.peekToken.kind != CBRACE && p.peekToken.kind != EOF {
+		stmt := p.parseStatement()
+		if stmt == nil {
+			return nil
+		}
+		b.statements = append(b.statements, stmt)
+	}
+	if !p.expectNext(CBRACE) {
+		return nil
+	}
+	return b
+}
+
+// parseCondition parses a parenthesized boolean expression
+func (p *Parser) parseCondition() Expression {
+	p.expectNext(OPAREN)
+	cond := p.parseExpression(lowest)
+	p.expectNext(CPAREN)
+	p.checkBoolean(cond)
+	return cond
+}
+
+// checkBoolean reports an error when the type of cond is known and isn't boolean
+func (p *Parser) checkBoolean(cond Expression) {
+	if kind := p.typeOf(cond); kind != nil && kind.String() != "boolean" && kind.String() != "Boolean" {
+		p.errorAt(cond.Position(), "incompatible types: %s cannot be converted to boolean", kind)
+	}
+}
+
+func (p *Parser) parseIf() Statement {
+	p.nextToken()
+	s := &ifStmt{statement: statement{p.token.node()}, cond: p.parseCondition()}
+	if s.then = p.parseBody(); s.then == nil {
+		return nil
+	}
+	if p.peekToken.kind == ELSE {
+		p.nextToken()
+		if s.otherwise = p.parseBody(); s.otherwise == nil {
+			return nil
+		}
+	}
+	return s
+}
+
+func (p *Parser) parseWhile() Statement {
+	p.nextToken()
+	s := &whileStmt{statement: statement{p.token.node()}, cond: p.parseCondition()}
+	if s.body = p.parseLoopBody(s); s.body == nil {
+		return nil
+	}
+	return s
+}
+
+func (p *Parser) parseDoWhile() Statement {
+	p.nextToken()
+	s := &doWhile{statement: statement{p.token.node()}}
+	if s.body = p.parseLoopBody(s); s.body == nil {
+		return nil
+	}
+	p.expectNext(WHILE)
+	s.cond = p.parseCondition()
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return s
+}
+
+// parseFor parses both the basic and the enhanced for loop, they share the scope of their variables
+func (p *Parser) parseFor() Statement {
+	p.nextToken()
+	n := p.token.node()
+	p.expectNext(OPAREN)
+	p.openScope()
+	defer p.closeScope()
+	if p.isLocalVarDecl() {
+		decl := p.parseLocalVarType()
+		if p.peekAt(2).kind == COLON {
+			return p.parseForEach(n, decl)
+		}
+		init := p.parseDeclarators(decl)
+		if init == nil {
+			return nil
+		}
+		return p.parseForRest(&forStmt{statement: statement{n}, init: []Statement{init}})
+	}
+	s := &forStmt{statement: statement{n}}
+	for p.peekToken.kind != SEMICOLON && p.peekToken.kind != EOF {
+		s.init = append(s.init, p.parseExpressionStmt())
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return p.parseForRest(s)
+}
+
+// parseForRest parses the condition, update and body of a basic for loop, following the semicolon after init
+func (p *Parser) parseForRest(s *forStmt) Statement {
+	if p.peekToken.kind != SEMICOLON {
+		s.cond = p.parseExpression(lowest)
+		p.checkBoolean(s.cond)
+	}
+	p.expectNext(SEMICOLON)
+	for p.peekToken.kind != CPAREN && p.peekToken.kind != EOF {
+		s.update = append(s.update, p.parseExpressionStmt().expr)
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectNext(CPAREN) {
+		return nil
+	}
+	if s.body = p.parseLoopBody(s); s.body == nil {
+		return nil
+	}
+	return s
+}
+
+// parseForEach parses an enhanced for loop, following the type of its variable
+func (p *Parser) parseForEach(n node, decl *localVars) Statement {
+	p.expectNext(IDENTIFIER)
+	v := &localVar{node: p.token.node(), kind: decl.kind, isFinal: decl.isFinal, inferred: decl.inferred}
+	p.expectNext(COLON)
+	s := &forEach{statement: statement{n}, variable: v, iterable: p.parseExpression(lowest)}
+	if v.inferred {
+		if kind := p.typeOf(s.iterable); kind != nil && kind.isArray() {
+			v.kind = arrayType(&typeRef{node: node{kind.name, v.pos}, kind: kind.kind}, kind.dims-1)
+		}
+	}
+	p.declare(v)
+	if !p.expectNext(CPAREN) {
+		return nil
+	}
+	if s.body = p.parseLoopBody(s); s.body == nil {
+		return nil
+	}
+	return s
+}
+
+// parseLoopBody parses the body of loop, with loop as the target of unlabeled breaks and continues
+func (p *Parser) parseLoopBody(loop Statement) Statement {
+	p.pushTarget(loop)
+	defer p.popTarget()
+	return p.parseBody()
+}
+
+func (p *Parser) parseLabeled() Statement {
+	p.nextToken()
+	s := &labeled{statement: statement{p.token.node()}}
+	p.nextToken()
+	for _, target := range p.targets {
+		if l, ok := target.(*labeled); ok && l.name == s.name {
+			p.errorAt(s.pos, "label %s already in use", s.name)
+		}
+	}
+	switch p.peekToken.kind {
+	case WHILE, DO, FOR:
+		s.loop = true
+	}
+	p.pushTarget(s)
+	defer p.popTarget()
+	if s.body = p.parseStatement(); s.body == nil {
+		return nil
+	}
+	return s
+}
+
+// parseJump parses break and continue, and checks that they have a target
+func (p *Parser) parseJump() Statement {
+	p.nextToken()
+	s := &jump{statement: statement{p.token.node()}, kind: p.token.kind}
+	if p.peekToken.kind == IDENTIFIER {
+		p.nextToken()
+		s.label = p.token.value
+	}
+	p.checkJump(s)
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return s
+}
+
+func (p *Parser) checkJump(s *jump) {
+	for i := len(p.targets) - 1; i >= 0; i-- {
+		switch target := p.targets[i].(type) {
+		case *switchExpr:
+			p.errorAt(s.pos, "attempting to %s out of a switch expression", s.kind)
+			return
+		case *labeled:
+			if target.name != s.label {
+				continue
+			}
+			if s.kind == CONTINUE && !target.loop {
+				p.errorf("not a loop label: %s", s.label)
+			}
+			return
+		case *switchStmt:
+			if s.label == "" && s.kind == BREAK {
+				return
+			}
+		default:
+			if s.label == "" {
+				return
+			}
+		}
+	}
+	switch {
+	case s.label != "":
+		p.errorf("undefined label: %s", s.label)
+	case s.kind == BREAK:
+		p.errorAt(s.pos, "break outside switch or loop")
+	default:
+		p.errorAt(s.pos, "continue outside of loop")
+	}
+}
+
+func (p *Parser) pushTarget(target Node) {
+	p.targets = append(p.targets, target)
+}
+
+func (p *Parser) popTarget() {
+	p.targets = p.targets[:len(p.targets)-1]
+}
+
+// parseReturn parses a return statement, and checks the value against the return type of the method
+func (p *Parser) parseReturn() Statement {
+	p.nextToken()
+	s := &returnStmt{statement: statement{p.token.node()}}
+	if p.peekToken.kind != SEMICOLON {
+		s.value = p.parseExpression(lowest)
+	}
+	for _, target := range p.targets {
+		if _, ok := target.(*switchExpr); ok {
+			p.errorAt(s.pos, "attempting to return out of a switch expression")
+		}
+	}
+	// The return statements of a lambda body are checked against its function, see checkLambda
+	switch void := p.decl.kind.kind == VOID; {
+	case p.lambda != nil:
+	case p.decl.isInitializer():
+		p.errorAt(s.pos, "return outside method")
+	case void && s.value != nil:
+		p.errorAt(s.value.Position(), "incompatible types: unexpected return value")
+	case !void && s.value == nil:
+		p.errorAt(s.pos, "missing return value")
+	default:
+		targetFunction(p.decl.kind, s.value)
+	}
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return s
+}
+
+// isStatementExpression reports whether e may be used as a statement,
+// only assignments, increments, decrements and calls have side effects
+func isStatementExpression(e Expression) bool {
+	switch e := e.(type) {
+	case *assignment, *call, *newObject, *constructorCall:
+		return true
+	case *unary:
+		return e.op == INCREMENT || e.op == DECREMENT
+	default:
+		return false
+	}
+}
+
+// isLocalVarDecl reports whether the tokens after the current token start a local variable declaration,
+// a type followed by a name
+func (p *Parser) isLocalVarDecl() bool {
+	if p.peekToken.kind.isModifier() || p.peekToken.kind == AT {
+		return true
+	}
+	n := p.scanType(1)
+	return n > 0 && p.peekAt(n).kind == IDENTIFIER
+}
+
+// scanType returns the offset of the token after the type starting at peekAt(n), or 0 if there is no type there
+func (p *Parser) scanType(n int) int {
+	kind := p.peekAt(n).kind
+	primitive := slices.Contains(primitives, kind)
+	if !primitive && kind != IDENTIFIER {
+		return 0
+	}
+	n++
+	for !primitive {
+		if p.peekAt(n).kind == LT {
+			if n = p.scanTypeArgs(n); n == 0 {
+				return 0
+			}
+		}
+		if p.peekAt(n).kind != DOT || p.peekAt(n+1).kind != IDENTIFIER {
+			break
+		}
+		n += 2
+	}
+	for p.peekAt(n).kind == OBRACKET && p.peekAt(n+1).kind == CBRACKET {
+		n += 2
+	}
+	return n
+}
+
+// parseLocalVars parses a local variable declaration with one or more declarators, like int a = 1, b;
+func (p *Parser) parseLocalVars() Statement {
+	return p.parseDeclarators(p.parseLocalVarType())
+}
+
+// parseLocalVarType parses the modifiers and type of a local variable declaration
+func (p *Parser) parseLocalVarType() *localVars {
+	isFinal := false
+	var annotations []*annotation
+	for p.peekToken.kind.isModifier() || p.peekToken.kind == AT {
+		p.nextToken()
+		switch {
+		case p.token.kind == AT:
+			if a := p.parseAnnotation(); a != nil {
+				annotations = append(annotations, a)
+			}
+			continue
+		case p.token.kind != FINAL:
+			p.errorf("modifier %s not allowed here", p.token.kind)
+		case isFinal:
+			p.errorf("repeated modifier")
+		}
+		isFinal = isFinal || p.token.kind == FINAL
+	}
+	kind := p.parseType()
+	inferred := kind.kind == IDENTIFIER && kind.name == "var"
+	if inferred && kind.isArray() {
+		p.errorf("'var' is not allowed as an element type of an array")
+	}
+	return &localVars{statement: statement{kind.node}, kind: kind, isFinal: isFinal, inferred: inferred, annotations: annotations}
+}
+
+// parseDeclarators parses the declarators following the type of decl, up to the semicolon
+func (p *Parser) parseDeclarators(decl *localVars) Statement {
+	for {
+		p.expectNext(IDENTIFIER)
+		v := &localVar{node: p.token.node(), kind: decl.kind, isFinal: decl.isFinal, inferred: decl.inferred}
+		// The brackets may follow the name too, like int a[]
+		if dims := p.parseDims(); dims > 0 {
+			if v.inferred {
+				p.errorAt(v.pos, "'var' is not allowed as an element type of an array")
+			}
+			v.kind = arrayType(decl.kind, dims)
+		}
+		if p.peekToken.kind == ASSIGN {
+			p.nextToken()
+			v.value = p.parseInitializer(v.kind)
+		}
+		if v.inferred {
+			p.inferLocalVar(v, len(decl.vars) > 0 || p.peekToken.kind == COMMA)
+		}
+		p.declare(v)
+		decl.vars = append(decl.vars, v)
+		if !p.expectNext(COMMA, SEMICOLON) {
+			return nil
+		}
+		if p.token.kind == SEMICOLON {
+			return decl
+		}
+	}
+}
+
+// inferLocalVar replaces the kind of a var declaration with the type of its initializer.
+// The kind is left as var when the type is only known after resolving fields and methods
+func (p *Parser) inferLocalVar(v *localVar, compound bool) {
+	switch {
+	case compound:
+		p.errorAt(v.pos, "'var' is not allowed in a compound declaration")
+	case v.value == nil:
+		p.errorAt(v.pos, "cannot infer type for local variable %s (cannot use 'var' on variable without initializer)", v.name)
+	default:
+		if l, ok := v.value.(*literal); ok && l.kind == NULL_LITERAL {
+			p.errorAt(v.pos, "cannot infer type for local variable %s (variable initializer is 'null')", v.name)
+		} else if _, ok := v.value.(*arrayInit); ok {
+			p.errorAt(v.pos, "cannot infer type for local variable %s (array initializer needs an explicit target-type)", v.name)
+		} else if _, ok := v.value.(*lambda); ok {
+			p.errorAt(v.pos, "cannot infer type for local variable %s (lambda expression needs an explicit target-type)", v.name)
+		} else if _, ok := v.value.(*methodRef); ok {
+			p.errorAt(v.pos, "cannot infer type for local variable %s (method reference needs an explicit target-type)", v.name)
+		} else if kind := p.typeOf(v.value); kind != nil {
+			v.kind = kind
+		}
+	}
+}
+
+func (p *Parser) openScope() {
+	p.scope = &scope{parent: p.scope}
+}
+
+func (p *Parser) closeScope() {
+	p.scope = p.scope.parent
+}
+
+// declare adds v to the current scope.
+// Locals may not shadow a parameter or another local of the enclosing blocks
+func (p *Parser) declare(v *localVar) {
+	if p.scope == nil {
+		p.scope = &scope{}
+	}
+	for s := p.scope; s != nil && !s.boundary; s = s.parent {
+		if s.vars[v.name] != nil {
+			p.errorAt(v.pos, "variable %s is already defined in method %s", v.name, p.decl.name)
+			break
+		}
+	}
+	if p.scope.vars == nil {
+		p.scope.vars = map[string]*localVar{}
+	}
+	p.scope.vars[v.name] = v
+}
+
+// lookup returns the local variable or parameter named name, or nil if it isn't declared
+func (s *scope) lookup(name string) *localVar {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// completesNormally reports whether the statement s of class c can complete normally, JLS 14.22.
+// Unreachable statements aren't reported, a block completes normally when all its statements do
+func (c *class) completesNormally(s Statement) bool {
+	switch s := s.(type) {
+	case *block:
+		return !slices.ContainsFunc(s.statements, func(s Statement) bool { return !c.completesNormally(s) })
+	case *returnStmt, *throwStmt, *yield, *jump:
+		return false
+	case *ifStmt:
+		return s.otherwise == nil || c.completesNormally(s.then) || c.completesNormally(s.otherwise)
+	case *whileStmt:
+		return !c.isTrue(s.cond) || breaks(s.body, "")
+	case *doWhile:
+		return c.completesNormally(s.body) && !c.isTrue(s.cond) || breaks(s.body, "")
+	case *forStmt:
+		return s.cond != nil && !c.isTrue(s.cond) || breaks(s.body, "")
+	case *labeled:
+		return c.completesNormally(s.body) || breaks(s.body, s.name)
+	case *switchStmt:
+		if !slices.ContainsFunc(s.cases, func(k *switchCase) bool { return k.isDefault }) {
+			return true
+		}
+		for i, k := range s.cases {
+			if (s.arrow || i == len(s.cases)-1) && c.completesNormally(&block{statements: k.body}) || breaks(&block{statements: k.body}, "") {
+				return true
+			}
+		}
+		return false
+	case *tryStmt:
+		if s.finally != nil && !c.completesNormally(s.finally) {
+			return false
+		}
+		return c.completesNormally(s.body) || slices.ContainsFunc(s.catches, func(k *catchClause) bool { return c.completesNormally(k.body) })
+	}
+	return true
+}
+
+// isTrue reports whether e is a constant expression with the value true
+func (c *class) isTrue(e Expression) bool {
+	v, ok := c.constant(e)
+	return ok && v == true
+}
+
+// breaks reports whether s contains a break out of the statement it is the body of, with the label or, when label
+// is empty, from the innermost loop or switch
+func breaks(s Statement, label string) bool {
+	found := false
+	inspect(s, func(n Node) bool {
+		switch n := n.(type) {
+		case *jump:
+			found = found || n.kind == BREAK && n.label == label
+		case *lambda, *switchExpr:
+			return false
+		case *whileStmt, *doWhile, *forStmt, *forEach, *switchStmt:
+			return label != ""
+		}
+		return !found
+	})
+	return found
+}
@@ -1,6 +1,9 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func (a *AST) String() string {
 	prettyPrintAST(a)
@@ -11,10 +14,30 @@ func (a modifiers) String() string {
 	return fmt.Sprintf("Visibility: %s, isStatic: %t", a.visibility, a.isStatic)
 }
 
+func (t *typeRef) String() string {
+	switch {
+	case t.kind == QUESTION && t.bound == nil:
+		return "?"
+	case t.kind == QUESTION && t.isSuper:
+		return fmt.Sprintf("? super %s", t.bound)
+	case t.kind == QUESTION:
+		return fmt.Sprintf("? extends %s", t.bound)
+	}
+	name := t.name
+	if t.args != nil {
+		args := make([]string, len(t.args))
+		for i, arg := range t.args {
+			args[i] = arg.String()
+		}
+		name += "<" + strings.Join(args, ",") + ">"
+	}
+	return name + strings.Repeat("[]", t.dims)
+}
+
 func (m *method) String() string {
 	fmt.Printf("\tMethod: %s", m.name)
 	for _, p := range m.parameters {
-		fmt.Printf("\n\t  kind: %s\n\t  name: %s\n", p.kind.name, p.name.name)
+		fmt.Printf("\n\t  kind: %s\n\t  name: %s\n", p.kind, p.name.name)
 	}
 	return ""
 }
@@ -22,22 +45,360 @@ func (m *method) String() string {
 func prettyPrintAST(a *AST) {
 	for _, f := range a.files {
 		fmt.Printf("File: %s\n", f.path)
+		if f.pkg.name != "" {
+			fmt.Printf(" Package: %s\n", f.pkg.name)
+		}
+		for _, imp := range f.imports {
+			fmt.Printf(" Import: %s.%s (Static: %t)\n", imp.pkgName, imp.alias, imp.isStatic)
+		}
 		for _, c := range f.classes {
-			fmt.Printf(" Class: %s (Visibility: %s, Static: %t)\n", c.name, c.visibility, c.isStatic)
+			kind := "Class"
+			if c.isInterface {
+				kind = "Interface"
+			} else if c.isEnum {
+				kind = "Enum"
+			} else if c.isRecord {
+				kind = "Record"
+			}
+			fmt.Printf(" %s: %s (Visibility: %s, Static: %t, Abstract: %t)\n", kind, c.name, c.visibility, c.isStatic, c.isAbstract)
+			if c.extends != nil {
+				fmt.Printf("  Extends: %s\n", c.extends)
+			}
+			for _, i := range c.implements {
+				fmt.Printf("  Implements: %s\n", i)
+			}
+			for _, comp := range c.components {
+				fmt.Printf("  Component: %s %s\n", comp.kind, comp.name.name)
+			}
+			for _, e := range c.constants {
+				fmt.Printf("  Constant: %s (Ordinal: %d, Args: %s)\n", e.name, e.ordinal, joinExpressions(e.args))
+				if e.body != nil {
+					fmt.Printf("   Body: %s\n", e.body.name)
+				}
+			}
+			if c.doc != "" {
+				fmt.Printf("  Doc: %q\n", c.doc)
+			}
+			for _, a := range c.annotations {
+				fmt.Printf("  Annotation: %s\n", a)
+			}
 			for _, fld := range c.fields {
-				fmt.Printf("  Field: %s %s (%s, InitVal: %s)\n", fld.kind, fld.name, fld.modifiers, fld.initValue)
+				fmt.Printf("  Field: %s %s (%s, InitVal: %v)\n", fld.kind, fld.name, fld.modifiers, fld.value)
+				if fld.constant != nil {
+					fmt.Printf("   Constant: %#v\n", fld.constant)
+				}
+				for _, a := range fld.annotations {
+					fmt.Printf("   Annotation: %s\n", a)
+				}
 			}
 			for _, m := range c.methods {
-				fmt.Printf("  Method: return type: %s name: %s (%s)\n", m.kind, m.name, m.modifiers)
+				if m.isConstructor {
+					fmt.Printf("  Constructor: %s (%s, Implicit: %t)\n", m.name, m.modifiers, m.implicit)
+				} else {
+					fmt.Printf("  Method: return type: %s name: %s (%s)\n", m.kind, m.name, m.modifiers)
+				}
+				for _, a := range m.annotations {
+					fmt.Printf("    Annotation: %s\n", a)
+				}
+				if m.defaultValue != nil {
+					fmt.Printf("    Default: %s\n", m.defaultValue)
+				}
 				fmt.Printf("    Parameters:\n")
 				for _, p := range m.parameters {
-					fmt.Printf("\t- kind: %s name: %s\n", p.kind.name, p.name.name)
+					fmt.Printf("\t- kind: %s name: %s\n", p.kind, p.name.name)
+				}
+				for _, t := range m.throws {
+					fmt.Printf("    Throws: %s\n", t)
 				}
 				fmt.Printf("   Body:\n")
-				for _, ref := range m.body.references {
-					fmt.Printf("    Reference: %s\n", ref.name)
+				for _, stmt := range m.body.statements {
+					fmt.Printf("    Statement: %s\n", stmt)
 				}
 			}
+			for _, b := range c.blocks {
+				fmt.Printf("  Initializer: (Static: %t)\n", b.isStatic)
+				for _, stmt := range b.statements {
+					fmt.Printf("    Statement: %s\n", stmt)
+				}
+			}
+		}
+	}
+}
+
+func (l *literal) String() string {
+	return l.name
+}
+
+func (i *identifier) String() string {
+	return i.name
+}
+
+func (u *unary) String() string {
+	if u.postfix {
+		return fmt.Sprintf("(%s%s)", u.operand, u.name)
+	}
+	return fmt.Sprintf("(%s%s)", u.name, u.operand)
+}
+
+func (b *binary) String() string {
+	return fmt.Sprintf("(%s %s %s)", b.left, b.name, b.right)
+}
+
+func (c *conditional) String() string {
+	return fmt.Sprintf("(%s ? %s : %s)", c.cond, c.then, c.otherwise)
+}
+
+func (a *assignment) String() string {
+	return fmt.Sprintf("(%s %s %s)", a.target, a.name, a.value)
+}
+
+func (c *cast) String() string {
+	return fmt.Sprintf("((%s) %s)", c.kind, c.operand)
+}
+
+func (i *instanceOf) String() string {
+	return fmt.Sprintf("(%s instanceof %s)", i.operand, i.kind)
+}
+
+func (c *call) String() string {
+	if c.target == nil {
+		return fmt.Sprintf("%s(%s)", c.name, joinExpressions(c.args))
+	}
+	return fmt.Sprintf("%s.%s(%s)", c.target, c.name, joinExpressions(c.args))
+}
+
+func (f *fieldAccess) String() string {
+	return fmt.Sprintf("%s.%s", f.target, f.name)
+}
+
+func (l *lambda) String() string {
+	params := make([]string, len(l.params))
+	for i, param := range l.params {
+		params[i] = param.name
+		if !param.inferred {
+			params[i] = fmt.Sprintf("%s %s", param.kind, param.name)
+		}
+	}
+	return fmt.Sprintf("(%s) -> %s", strings.Join(params, ", "), l.body)
+}
+
+func (r *methodRef) String() string {
+	return fmt.Sprintf("%s::%s", r.target, r.name)
+}
+
+func (a *arrayIndex) String() string {
+	return fmt.Sprintf("%s[%s]", a.array, a.index)
+}
+
+func (a *newArray) String() string {
+	if a.init != nil {
+		return fmt.Sprintf("new %s%s", a.kind, a.init)
+	}
+	dims := make([]string, a.kind.dims)
+	for i := range dims {
+		dims[i] = "[]"
+		if i < len(a.dims) {
+			dims[i] = fmt.Sprintf("[%s]", a.dims[i])
 		}
 	}
+	return fmt.Sprintf("new %s%s", a.kind.name, strings.Join(dims, ""))
+}
+
+func (a *arrayInit) String() string {
+	return fmt.Sprintf("{%s}", joinExpressions(a.elements))
+}
+
+func (e *expressionStmt) String() string {
+	return fmt.Sprint(e.expr)
+}
+
+func (l *localVars) String() string {
+	vars := make([]string, len(l.vars))
+	for i, v := range l.vars {
+		vars[i] = v.name
+		if !l.inferred && v.kind.dims > l.kind.dims {
+			vars[i] += strings.Repeat("[]", v.kind.dims-l.kind.dims)
+		}
+		if v.value != nil {
+			vars[i] += fmt.Sprintf(" = %s", v.value)
+		}
+	}
+	s := fmt.Sprintf("%s %s", l.kind, strings.Join(vars, ", "))
+	if l.isFinal {
+		s = "final " + s
+	}
+	return s
+}
+
+func (b *block) String() string {
+	if len(b.statements) == 0 {
+		return "{ }"
+	}
+	stmts := make([]string, len(b.statements))
+	for i, stmt := range b.statements {
+		stmts[i] = fmt.Sprint(stmt)
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(stmts, "; "))
+}
+
+func (e *empty) String() string {
+	return ";"
+}
+
+func (i *ifStmt) String() string {
+	if i.otherwise == nil {
+		return fmt.Sprintf("if (%s) %s", i.cond, i.then)
+	}
+	return fmt.Sprintf("if (%s) %s else %s", i.cond, i.then, i.otherwise)
+}
+
+func (w *whileStmt) String() string {
+	return fmt.Sprintf("while (%s) %s", w.cond, w.body)
+}
+
+func (d *doWhile) String() string {
+	return fmt.Sprintf("do %s while (%s)", d.body, d.cond)
+}
+
+func (f *forStmt) String() string {
+	init := make([]string, len(f.init))
+	for i, stmt := range f.init {
+		init[i] = fmt.Sprint(stmt)
+	}
+	update := make([]string, len(f.update))
+	for i, expr := range f.update {
+		update[i] = fmt.Sprint(expr)
+	}
+	cond := ""
+	if f.cond != nil {
+		cond = fmt.Sprint(f.cond)
+	}
+	return fmt.Sprintf("for (%s; %s; %s) %s", strings.Join(init, ", "), cond, strings.Join(update, ", "), f.body)
+}
+
+func (f *forEach) String() string {
+	return fmt.Sprintf("for (%s %s : %s) %s", f.variable.kind, f.variable.name, f.iterable, f.body)
+}
+
+func (l *labeled) String() string {
+	return fmt.Sprintf("%s: %s", l.name, l.body)
+}
+
+func (j *jump) String() string {
+	if j.label == "" {
+		return j.kind.String()
+	}
+	return fmt.Sprintf("%s %s", j.kind, j.label)
+}
+
+func (r *returnStmt) String() string {
+	if r.value == nil {
+		return "return"
+	}
+	return fmt.Sprintf("return %s", r.value)
+}
+
+func (t *throwStmt) String() string {
+	return fmt.Sprintf("throw %s", t.value)
+}
+
+func (t *tryStmt) String() string {
+	s := "try "
+	if len(t.resources) > 0 {
+		resources := make([]string, len(t.resources))
+		for i, r := range t.resources {
+			resources[i] = fmt.Sprint(r)
+		}
+		s += fmt.Sprintf("(%s) ", strings.Join(resources, "; "))
+	}
+	s += t.body.String()
+	for _, c := range t.catches {
+		kinds := make([]string, len(c.kinds))
+		for i, kind := range c.kinds {
+			kinds[i] = kind.String()
+		}
+		s += fmt.Sprintf(" catch (%s %s) %s", strings.Join(kinds, " | "), c.param.name, c.body)
+	}
+	if t.finally != nil {
+		s += fmt.Sprintf(" finally %s", t.finally)
+	}
+	return s
+}
+
+func (s *switchBlock) String() string {
+	sep := ":"
+	if s.arrow {
+		sep = " ->"
+	}
+	cases := make([]string, len(s.cases))
+	for i, c := range s.cases {
+		label := "default"
+		if !c.isDefault {
+			labels := make([]string, len(c.labels))
+			for j, l := range c.labels {
+				labels[j] = fmt.Sprint(l)
+			}
+			label = "case " + strings.Join(labels, ", ")
+		}
+		body := make([]string, len(c.body))
+		for j, stmt := range c.body {
+			body[j] = fmt.Sprint(stmt)
+		}
+		cases[i] = fmt.Sprintf("%s%s %s", label, sep, strings.Join(body, "; "))
+	}
+	return fmt.Sprintf("switch (%s) { %s }", s.selector, strings.Join(cases, " "))
+}
+
+func (y *yield) String() string {
+	return fmt.Sprintf("yield %s", y.value)
+}
+
+func (t *thisExpr) String() string {
+	return t.name
+}
+
+func (s *superExpr) String() string {
+	return s.name
+}
+
+func (n *newObject) String() string {
+	s := fmt.Sprintf("new %s(%s)", n.kind, joinExpressions(n.args))
+	if n.outer != nil {
+		s = fmt.Sprintf("%s.%s", n.outer, s)
+	}
+	if n.body != nil {
+		s += " { " + n.body.name + " }"
+	}
+	return s
+}
+
+func (a *annotation) String() string {
+	if len(a.elements) == 0 {
+		return "@" + a.name
+	}
+	var elements []string
+	for _, e := range a.elements {
+		elements = append(elements, fmt.Sprintf("%s = %s", e.name.name, e.value))
+	}
+	return fmt.Sprintf("@%s(%s)", a.name, strings.Join(elements, ", "))
+}
+
+func (i *classInit) String() string {
+	return fmt.Sprintf("init %s", i.class.binaryName())
+}
+
+func (l *localClass) String() string {
+	return fmt.Sprintf("class %s", l.class.binaryName())
+}
+
+func (c *constructorCall) String() string {
+	return fmt.Sprintf("%s(%s)", c.name, joinExpressions(c.args))
+}
+
+func joinExpressions(exprs []Expression) string {
+	s := make([]string, len(exprs))
+	for i, e := range exprs {
+		s[i] = fmt.Sprint(e)
+	}
+	return strings.Join(s, ", ")
 }
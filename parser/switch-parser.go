@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+)
+
+// selectorTypes are the types a switch selector may have besides enums
+var selectorTypes = []string{"char", "byte", "short", "int", "Character", "Byte", "Short", "Integer", "String"}
+
+// parseSwitch parses the selector and cases of a switch statement or expression, following the switch keyword.
+// owner is pushed as the target of the breaks or yields in the cases
+func (p *Parser) parseSwitch(sw *switchBlock, owner Node) bool {
+	_, isExpr := owner.(*switchExpr)
+	p.expectNext(OPAREN)
+	sw.selector = p.parseExpression(lowest)
+	p.expectNext(CPAREN)
+	p.checkSelector(sw.selector)
+	if !p.expectNext(OBRACE) {
+		return false
+	}
+	p.pushTarget(owner)
+	defer p.popTarget()
+	// The cases of a switch share one block, so a local declared in one case is in scope in the next
+	p.openScope()
+	defer p.closeScope()
+
+	labels := map[string]bool{}
+	hasDefault := false
+	for p.peekToken.kind == CASE || p.peekToken.kind == DEFAULT {
+		c, arrow := p.parseCase(labels)
+		if len(sw.cases) == 0 {
+			sw.arrow = arrow
+		} else if sw.arrow != arrow {
+			p.errorf("different case kinds used in the switch")
+		}
+		if c.isDefault {
+			if hasDefault {
+				p.errorAt(c.pos, "duplicate default label")
+			}
+			hasDefault = true
+		}
+		if !p.parseCaseBody(c, arrow, isExpr) {
+			return false
+		}
+		sw.cases = append(sw.cases, c)
+	}
+	if !p.expectNext(CBRACE) {
+		return false
+	}
+	if isExpr {
+		switch kind := p.typeOf(sw.selector); {
+		case len(sw.cases) == 0:
+			p.errorf("switch expression does not have any case clauses")
+		case !hasDefault && kind != nil && slices.Contains(selectorTypes, kind.String()):
+			p.errorf("the switch expression does not cover all possible input values")
+		}
+	}
+	return true
+}
+
+// checkSelector reports an error when the type of the selector is known and can't be switched on.
+// Class types other than String and the boxed integers may be enums, which are checked once they are resolved
+func (p *Parser) checkSelector(selector Expression) {
+	kind := p.typeOf(selector)
+	if kind == nil || kind.kind == IDENTIFIER && !kind.isArray() {
+		return
+	}
+	if !slices.Contains(selectorTypes, kind.String()) {
+		p.errorAt(selector.Position(), "incompatible types: %s can't be used as a switch selector", kind)
+	}
+}
+
+// parseCase parses the labels of a case up to and including its colon or arrow.
+// labels holds the labels of the previous cases, to find duplicates
+func (p *Parser) parseCase(labels map[string]bool) (c *switchCase, arrow bool) {
+	p.nextToken()
+	c = &switchCase{node: p.token.node(), isDefault: p.token.kind == DEFAULT}
+	for !c.isDefault {
+		label := p.parseExpression(ternary)
+		if label != nil {
+			key := fmt.Sprint(label)
+			if labels[key] {
+				p.errorAt(label.Position(), "duplicate case label")
+			}
+			labels[key] = true
+		}
+		c.labels = append(c.labels, label)
+		if p.peekToken.kind != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	p.expectNext(ARROW, COLON)
+	return c, p.token.kind == ARROW
+}
+
+// parseCaseBody parses the statements of c.
+// The body of an arrow case is a block or a single expression, which is the value of a switch expression
+func (p *Parser) parseCaseBody(c *switchCase, arrow, isExpr bool) bool {
+	if !arrow {
+		for p.peekToken.kind != CASE && p.peekToken.kind != DEFAULT && p.peekToken.kind != CBRACE && p.peekToken.kind != EOF {
+			stmt := p.parseStatement()
+			if stmt == nil {
+				return false
+			}
+			c.body = append(c.body, stmt)
+		}
+		return true
+	}
+	var stmt Statement
+	switch {
+	case p.peekToken.kind == OBRACE:
+		p.nextToken()
+		if b := p.parseBlock(); b != nil {
+			stmt = b
+		}
+	case isExpr:
+		value := p.parseExpression(lowest)
+		y := &yield{value: value}
+		if value != nil {
+			y.node = node{value.Name(), value.Position()}
+		}
+		if p.expectNext(SEMICOLON) {
+			stmt = y
+		}
+	default:
+		s := p.parseExpressionStmt()
+		if p.expectNext(SEMICOLON) {
+			stmt = s
+		}
+	}
+	if stmt == nil {
+		return false
+	}
+	c.body = []Statement{stmt}
+	return true
+}
+
+// isYield reports whether the contextual keyword yield at peekToken starts a yield statement,
+// rather than being used as a variable name
+func (p *Parser) isYield() bool {
+	if p.peekToken.value != "yield" {
+		return false
+	}
+	switch next := p.peekAt(2).kind; {
+	case precedences[next] == assign, next == DOT, next == OBRACKET, next == INCREMENT, next == DECREMENT, next == SEMICOLON:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *Parser) parseYield() Statement {
+	p.nextToken()
+	s := &yield{statement: statement{p.token.node()}, value: p.parseExpression(lowest)}
+	p.checkYield(s)
+	if !p.expectNext(SEMICOLON) {
+		return nil
+	}
+	return s
+}
+
+// checkYield reports an error unless the innermost switch is a switch expression
+func (p *Parser) checkYield(s *yield) {
+	for i := len(p.targets) - 1; i >= 0; i-- {
+		switch p.targets[i].(type) {
+		case *switchExpr:
+			return
+		case *switchStmt:
+			p.errorAt(s.pos, "yield outside of switch expression")
+			return
+		}
+	}
+	p.errorAt(s.pos, "yield outside of switch expression")
+}
@@ -4,6 +4,12 @@ type token struct {
 	*pos
 	value string
 	kind  tokenKind
+	// literal is the decoded value of literal tokens
+	literal any
+	// err is the diagnostic of a malformed numeric literal, whose value is then zero
+	err string
+	// doc is the Javadoc comment preceding the token
+	doc string
 }
 
 type pos struct {
@@ -24,14 +30,55 @@ const (
 	PROTECTED
 	STATIC
 	FINAL
+	ABSTRACT
+	NATIVE
+	SYNCHRONIZED
+	TRANSIENT
+	VOLATILE
+	STRICTFP
 
 	// keywords
-	KEYWORD
 	IDENTIFIER
-	LITERAL
 	PACKAGE
 	IMPORT
 	CLASS
+	INTERFACE
+	ENUM
+	EXTENDS
+	IMPLEMENTS
+	NEW
+	THIS
+	SUPER
+	INSTANCEOF
+	IF
+	ELSE
+	WHILE
+	DO
+	FOR
+	BREAK
+	CONTINUE
+	RETURN
+	SWITCH
+	CASE
+	DEFAULT
+	TRY
+	CATCH
+	FINALLY
+	THROW
+	THROWS
+	ASSERT
+	CONST
+	GOTO
+
+	// literals
+	INT_LITERAL
+	LONG_LITERAL
+	FLOAT_LITERAL
+	DOUBLE_LITERAL
+	STRING_LITERAL
+	CHAR_LITERAL
+	BOOLEAN_LITERAL
+	NULL_LITERAL
 
 	// errors
 	CRITICAL
@@ -46,27 +93,29 @@ const (
 	CBRACE
 	OBRACKET
 	CBRACKET
+	AT
 
 	// types
 	VOID
+	BOOLEAN
+	BYTE
+	SHORT
 	INT
+	LONG
+	CHAR
 	FLOAT
-	STRING
-	BOOLEAN
 	DOUBLE
-	CHAR
-
-	// method elements
-	PARAMETER
-	ARGUMENT
 
 	// delimiter
 	SEMICOLON
 	COMMA
 	DOT
+	ELLIPSIS
+	DOUBLE_COLON
 
 	// operators
 	EQUALS
+	NOT_EQUALS
 	ASSIGN
 	PLUS
 	MINUS
@@ -76,16 +125,45 @@ const (
 	NOT
 	LT
 	GT
+	LT_EQUALS
+	GT_EQUALS
+	AND
+	OR
+	INCREMENT
+	DECREMENT
+	BIT_AND
+	BIT_OR
+	BIT_XOR
+	BIT_NOT
+	SHIFT_LEFT
+	SHIFT_RIGHT
+	UNSIGNED_SHIFT_RIGHT
+	PLUS_ASSIGN
+	MINUS_ASSIGN
+	MULTIPLY_ASSIGN
+	DIVIDE_ASSIGN
+	PERCENT_ASSIGN
+	BIT_AND_ASSIGN
+	BIT_OR_ASSIGN
+	BIT_XOR_ASSIGN
+	SHIFT_LEFT_ASSIGN
+	SHIFT_RIGHT_ASSIGN
+	UNSIGNED_SHIFT_RIGHT_ASSIGN
+	QUESTION
+	COLON
+	ARROW
 )
 
 const (
-	TOKEN_QUOTE     = '"'
-	TOKEN_COMMA     = ','
-	TOKEN_SEMICOLON = ';'
-	TOKEN_CPAREN    = ')'
-	TOKEN_OPAREN    = '('
-	TOKEN_OBRACE    = '{'
-	TOKEN_CBRACE    = '}'
+	TOKEN_QUOTE      = '"'
+	TOKEN_APOSTROPHE = '\''
+	TOKEN_COMMA      = ','
+	TOKEN_SEMICOLON  = ';'
+	TOKEN_DOT        = '.'
+	TOKEN_CPAREN     = ')'
+	TOKEN_OPAREN     = '('
+	TOKEN_OBRACE     = '{'
+	TOKEN_CBRACE     = '}'
 )
 
 func (t tokenKind) String() string {
@@ -102,10 +180,6 @@ func (t tokenKind) String() string {
 		return "INFO"
 	case IDENTIFIER:
 		return "identifier"
-	case CLASS:
-		return "class"
-	case ARGUMENT:
-		return "argument"
 	case NOT_SUPPORTED:
 		return "not supported"
 	case SEMICOLON:
@@ -114,12 +188,10 @@ func (t tokenKind) String() string {
 		return "comma"
 	case DOT:
 		return "period"
-	case PACKAGE:
-		return "package"
-	case VOID:
-		return "void"
-	case IMPORT:
-		return "import"
+	case ELLIPSIS:
+		return "ellipsis"
+	case DOUBLE_COLON:
+		return "double colon"
 	case PUBLIC:
 		return "public"
 	case PRIVATE:
@@ -130,10 +202,112 @@ func (t tokenKind) String() string {
 		return "static"
 	case FINAL:
 		return "final"
-	case KEYWORD:
-		return "keyword"
-	case LITERAL:
-		return "literal"
+	case ABSTRACT:
+		return "abstract"
+	case NATIVE:
+		return "native"
+	case SYNCHRONIZED:
+		return "synchronized"
+	case TRANSIENT:
+		return "transient"
+	case VOLATILE:
+		return "volatile"
+	case STRICTFP:
+		return "strictfp"
+	case PACKAGE:
+		return "package"
+	case IMPORT:
+		return "import"
+	case CLASS:
+		return "class"
+	case INTERFACE:
+		return "interface"
+	case ENUM:
+		return "enum"
+	case EXTENDS:
+		return "extends"
+	case IMPLEMENTS:
+		return "implements"
+	case NEW:
+		return "new"
+	case THIS:
+		return "this"
+	case SUPER:
+		return "super"
+	case INSTANCEOF:
+		return "instanceof"
+	case IF:
+		return "if"
+	case ELSE:
+		return "else"
+	case WHILE:
+		return "while"
+	case DO:
+		return "do"
+	case FOR:
+		return "for"
+	case BREAK:
+		return "break"
+	case CONTINUE:
+		return "continue"
+	case RETURN:
+		return "return"
+	case SWITCH:
+		return "switch"
+	case CASE:
+		return "case"
+	case DEFAULT:
+		return "default"
+	case TRY:
+		return "try"
+	case CATCH:
+		return "catch"
+	case FINALLY:
+		return "finally"
+	case THROW:
+		return "throw"
+	case THROWS:
+		return "throws"
+	case ASSERT:
+		return "assert"
+	case CONST:
+		return "const"
+	case GOTO:
+		return "goto"
+	case VOID:
+		return "void"
+	case BOOLEAN:
+		return "boolean"
+	case BYTE:
+		return "byte"
+	case SHORT:
+		return "short"
+	case INT:
+		return "int"
+	case LONG:
+		return "long"
+	case CHAR:
+		return "char"
+	case FLOAT:
+		return "float"
+	case DOUBLE:
+		return "double"
+	case INT_LITERAL:
+		return "int literal"
+	case LONG_LITERAL:
+		return "long literal"
+	case FLOAT_LITERAL:
+		return "float literal"
+	case DOUBLE_LITERAL:
+		return "double literal"
+	case STRING_LITERAL:
+		return "string literal"
+	case CHAR_LITERAL:
+		return "char literal"
+	case BOOLEAN_LITERAL:
+		return "boolean literal"
+	case NULL_LITERAL:
+		return "null literal"
 	case OPAREN:
 		return "oparen"
 	case CPAREN:
@@ -146,22 +320,12 @@ func (t tokenKind) String() string {
 		return "obracket"
 	case CBRACKET:
 		return "cbracket"
-	case PARAMETER:
-		return "parameter"
-	case STRING:
-		return "string"
-	case INT:
-		return "int"
-	case FLOAT:
-		return "float"
-	case DOUBLE:
-		return "double"
-	case CHAR:
-		return "char"
-	case BOOLEAN:
-		return "boolean"
+	case AT:
+		return "at"
 	case EQUALS:
 		return "equals"
+	case NOT_EQUALS:
+		return "not equals"
 	case ASSIGN:
 		return "assign"
 	case PLUS:
@@ -180,6 +344,60 @@ func (t tokenKind) String() string {
 		return "less than"
 	case GT:
 		return "greater than"
+	case LT_EQUALS:
+		return "less than or equals"
+	case GT_EQUALS:
+		return "greater than or equals"
+	case AND:
+		return "and"
+	case OR:
+		return "or"
+	case INCREMENT:
+		return "increment"
+	case DECREMENT:
+		return "decrement"
+	case BIT_AND:
+		return "bitwise and"
+	case BIT_OR:
+		return "bitwise or"
+	case BIT_XOR:
+		return "bitwise xor"
+	case BIT_NOT:
+		return "bitwise not"
+	case SHIFT_LEFT:
+		return "shift left"
+	case SHIFT_RIGHT:
+		return "shift right"
+	case UNSIGNED_SHIFT_RIGHT:
+		return "unsigned shift right"
+	case PLUS_ASSIGN:
+		return "plus assign"
+	case MINUS_ASSIGN:
+		return "minus assign"
+	case MULTIPLY_ASSIGN:
+		return "multiply assign"
+	case DIVIDE_ASSIGN:
+		return "divide assign"
+	case PERCENT_ASSIGN:
+		return "percent assign"
+	case BIT_AND_ASSIGN:
+		return "bitwise and assign"
+	case BIT_OR_ASSIGN:
+		return "bitwise or assign"
+	case BIT_XOR_ASSIGN:
+		return "bitwise xor assign"
+	case SHIFT_LEFT_ASSIGN:
+		return "shift left assign"
+	case SHIFT_RIGHT_ASSIGN:
+		return "shift right assign"
+	case UNSIGNED_SHIFT_RIGHT_ASSIGN:
+		return "unsigned shift right assign"
+	case QUESTION:
+		return "question mark"
+	case COLON:
+		return "colon"
+	case ARROW:
+		return "arrow"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,164 @@
+package parser
+
+import "slices"
+
+// numerics orders the numeric primitives by width, for binary numeric promotion
+var numerics = []tokenKind{BYTE, SHORT, CHAR, INT, LONG, FLOAT, DOUBLE}
+
+// literalTypes maps literal kinds to the type of their value
+var literalTypes = map[tokenKind]tokenKind{
+	INT_LITERAL:     INT,
+	LONG_LITERAL:    LONG,
+	FLOAT_LITERAL:   FLOAT,
+	DOUBLE_LITERAL:  DOUBLE,
+	CHAR_LITERAL:    CHAR,
+	BOOLEAN_LITERAL: BOOLEAN,
+}
+
+func primitiveType(kind tokenKind, pos *pos) *typeRef {
+	return &typeRef{node: node{kind.String(), pos}, kind: kind}
+}
+
+func classType(name string, pos *pos) *typeRef {
+	return &typeRef{node: node{name, pos}, kind: IDENTIFIER}
+}
+
+func (t *typeRef) isNumeric() bool {
+	return t != nil && slices.Contains(numerics, t.kind) && !t.isArray()
+}
+
+func (t *typeRef) isArray() bool {
+	return t != nil && t.dims > 0
+}
+
+// arrayType returns the type of the arrays with dims dimensions of the elements of type elem
+func arrayType(elem *typeRef, dims int) *typeRef {
+	t := *elem
+	t.dims += dims
+	return &t
+}
+
+// boxes maps the primitive types to their wrapper classes, JLS 5.1.7
+var boxes = map[string]string{
+	"boolean": "Boolean",
+	"byte":    "Byte",
+	"short":   "Short",
+	"char":    "Character",
+	"int":     "Integer",
+	"long":    "Long",
+	"float":   "Float",
+	"double":  "Double",
+}
+
+// boxed returns the wrapper class type of a primitive type t, and t itself for reference types
+func (t *typeRef) boxed() *typeRef {
+	if name, ok := boxes[t.name]; ok && t.kind != IDENTIFIER && !t.isArray() {
+		return classType(name, t.pos)
+	}
+	return t
+}
+
+func (t *typeRef) isString() bool {
+	return t != nil && t.kind == IDENTIFIER && (t.name == "String" || t.name == "java.lang.String") && t.dims == 0
+}
+
+// typeOf returns the static type of e, or nil when it depends on declarations the parser hasn't resolved,
+// like fields and method return types
+func (p *Parser) typeOf(e Expression) *typeRef {
+	switch e := e.(type) {
+	case *literal:
+		if e.kind == STRING_LITERAL {
+			return classType("String", e.pos)
+		}
+		if kind, ok := literalTypes[e.kind]; ok {
+			return primitiveType(kind, e.pos)
+		}
+	case *identifier:
+		// An inferred local whose initializer couldn't be typed is still var
+		if v := e.local; v != nil && !(v.inferred && v.kind.name == "var") {
+			return v.kind
+		}
+	case *thisExpr:
+		return classType(e.class.binaryName(), e.pos)
+	case *superExpr:
+		if e.iface != nil {
+			return e.iface
+		}
+		return e.class.extends
+	case *newObject:
+		return e.kind
+	case *newArray:
+		return e.kind
+	case *arrayInit:
+		return e.kind
+	case *arrayIndex:
+		if kind := p.typeOf(e.array); kind.isArray() {
+			return arrayType(kind, -1)
+		}
+	case *fieldAccess:
+		if e.name == "length" && p.typeOf(e.target).isArray() {
+			return primitiveType(INT, e.pos)
+		}
+	case *call:
+		// The type of a call is known once checkFile resolved it, see castCall
+		if e.cast != nil {
+			return e.cast
+		}
+		if e.method != nil && e.method.kind.param == nil {
+			return e.method.kind
+		}
+	case *cast:
+		return e.kind
+	case *lambda:
+		return e.kind
+	case *methodRef:
+		return e.kind
+	case *instanceOf:
+		return primitiveType(BOOLEAN, e.pos)
+	case *assignment:
+		return p.typeOf(e.target)
+	case *unary:
+		operand := p.typeOf(e.operand)
+		switch e.op {
+		case NOT:
+			return primitiveType(BOOLEAN, e.pos)
+		case INCREMENT, DECREMENT:
+			return operand
+		}
+		return promote(operand, operand)
+	case *binary:
+		left, right := p.typeOf(e.left), p.typeOf(e.right)
+		switch precedences[e.op] {
+		case logicalOr, logicalAnd, equality, relational:
+			return primitiveType(BOOLEAN, e.pos)
+		case shift:
+			return promote(left, left)
+		}
+		if e.op == PLUS && (left.isString() || right.isString()) {
+			return classType("String", e.pos)
+		}
+		if left != nil && right != nil && left.kind == BOOLEAN && right.kind == BOOLEAN {
+			return left
+		}
+		return promote(left, right)
+	case *conditional:
+		then, otherwise := p.typeOf(e.then), p.typeOf(e.otherwise)
+		if then != nil && otherwise != nil && then.String() == otherwise.String() {
+			return then
+		}
+		if then.isNumeric() && otherwise.isNumeric() {
+			return promote(then, otherwise)
+		}
+	}
+	return nil
+}
+
+// promote applies binary numeric promotion, the result is at least an int.
+// nil is returned when either operand isn't numeric
+func promote(left, right *typeRef) *typeRef {
+	if !left.isNumeric() || !right.isNumeric() {
+		return nil
+	}
+	kind := max(slices.Index(numerics, left.kind), slices.Index(numerics, right.kind), slices.Index(numerics, INT))
+	return primitiveType(numerics[kind], left.pos)
+}
@@ -25,80 +25,524 @@ func (n node) Position() *pos { return n.pos }
 
 type decl struct {
 	node
-	// kind is the returnType for methods
-	kind    tokenKind
-	isFinal bool
+	// kind is the returnType for methods, and void for constructors
+	kind *typeRef
+	// typeParams are the type parameters of generic methods and constructors
+	typeParams []*typeParam
+	// throws are the exception types in the throws clause of methods and constructors
+	throws        []*typeRef
+	isFinal       bool
+	isConstructor bool
 	modifiers
+	// doc is the Javadoc comment preceding the declaration
+	doc string
 }
 
 type parameter struct {
-	name node
-	kind node
+	name        node
+	kind        *typeRef
+	isFinal     bool
+	annotations []*annotation
+	// isVarargs marks the last parameter of a variable arity method, its type is an array of the written type
+	isVarargs bool
 }
 
-// reference represents an expression referencing a field, variable or method
-// inverse definition, end token is most relevant. Example; System.out.print(...)
-// becomes "print <- out <- System"
-type reference struct {
+// typeRef references a primitive or class type, kind is IDENTIFIER for class types.
+// The name is the primitive or class name of the type, or of the elements when dims is the number of array dimensions.
+// Qualified names keep their dots
+type typeRef struct {
 	node
-	parent *reference
+	kind tokenKind
+	dims int
+	// args are the type arguments of a parameterized type like List<String>, and empty for the diamond <>
+	args []*typeRef
+	// bound is the bound of a wildcard, whose kind is QUESTION, like ? extends Number or ? super Integer when isSuper is set
+	bound   *typeRef
+	isSuper bool
+	// param is the type parameter a type variable refers to
+	param *typeParam
 }
 
-type fn struct {
-	*reference
-	args []node
+// typeParam is a type parameter of a generic class or method, like T extends Comparable<T>.
+// class is the class declaring it, or nil for the type parameters of methods
+type typeParam struct {
+	node
+	bounds []*typeRef
+	class  *class
+}
+
+// expression is embedded in every expression node.
+// The node name is the operator, name or literal the expression is built around
+type expression struct {
+	node
+}
+
+func (expression) Evaluate() {}
+
+// literal holds the value decoded by the lexer, see token.literal
+type literal struct {
+	expression
+	kind  tokenKind
+	value any
+}
+
+// identifier references a variable, field, class or package by its simple name.
+// local is set when the name resolves to a parameter or local variable in scope,
+// and outer when it names a field of an enclosing class, see resolveName
+type identifier struct {
+	expression
+	local *localVar
+	outer *class
+}
+
+// unary is a prefix or postfix operation, op is one of + - ! ~ ++ --
+type unary struct {
+	expression
+	op      tokenKind
+	operand Expression
+	postfix bool
+}
+
+type binary struct {
+	expression
+	op          tokenKind
+	left, right Expression
+}
+
+// conditional is the ternary cond ? then : otherwise
+type conditional struct {
+	expression
+	cond, then, otherwise Expression
+}
+
+// assignment covers = and the compound assignment operators
+type assignment struct {
+	expression
+	op     tokenKind
+	target Expression
+	value  Expression
+}
+
+type cast struct {
+	expression
+	kind    *typeRef
+	operand Expression
+}
+
+type instanceOf struct {
+	expression
+	operand Expression
+	kind    *typeRef
+}
+
+// call is a method invocation, target is nil for unqualified calls.
+// The node name is the method name, and method is the method called when it is declared in the same file
+// cast is the type the result is cast to after erasure, when the method returns a type variable, see eraseFile.
+// outer is the enclosing class whose method an unqualified call invokes, when it isn't the class of the call
+type call struct {
+	expression
+	target   Expression
+	typeArgs []*typeRef
+	args     []Expression
+	method   *method
+	cast     *typeRef
+	outer    *class
+}
+
+// fieldAccess selects a member of target, the node name is the member name
+type fieldAccess struct {
+	expression
+	target Expression
+}
+
+// thisExpr is the current object, an instance of class.
+// A qualified this like Outer.this is the enclosing instance of class Outer, and its node name is Outer.this
+type thisExpr struct {
+	expression
+	class *class
+}
+
+// superExpr is the current object seen as an instance of the superclass of class, in super.m() and super.field.
+// class is an enclosing class in Outer.super.m(), and iface is the superinterface named in I.super.m(), JLS 15.12.1
+type superExpr struct {
+	expression
+	class *class
+	iface *typeRef
+}
+
+// newObject creates an instance of kind.
+// constructor is the constructor called, when kind is declared in the same file.
+// outer is the enclosing instance of an inner class given like outer.new Inner(), and body is the class body
+// of an anonymous class extending or implementing kind
+type newObject struct {
+	expression
+	kind        *typeRef
+	args        []Expression
+	constructor *method
+	outer       Expression
+	body        *class
+}
+
+// lambda is a lambda expression, body is an Expression or a *block.
+// kind is the functional interface type it is assigned to once it is known, and function its abstract method.
+// captured are the local variables declared outside the lambda that its body uses
+type lambda struct {
+	expression
+	params   []*localVar
+	body     Node
+	kind     *typeRef
+	function *method
+	captured []*localVar
+}
+
+// methodRef is a method reference like Foo::bar, this::m or Foo::new, the node name is the method name or new.
+// kind and function are the functional interface type and its abstract method, as for lambdas.
+// method is the method or constructor referenced, when it is declared in the source root
+type methodRef struct {
+	expression
+	target   Expression
+	kind     *typeRef
+	function *method
+	method   *method
+}
+
+// constructorCall is an explicit this(...) or super(...) call in the first statement of a constructor,
+// the node name is this or super
+type constructorCall struct {
+	expression
+	args        []Expression
+	constructor *method
+}
+
+type arrayIndex struct {
+	expression
+	array, index Expression
+}
+
+// newArray creates an array of kind, with the lengths of its first dimensions in dims, or the elements of init
+type newArray struct {
+	expression
+	kind *typeRef
+	dims []Expression
+	init *arrayInit
+}
+
+// annotation is the use of an annotation interface like @Name or @Name(key = value), JLS 9.7. The node name is
+// the name of the interface. elements are the element values in the order written, the single element
+// of @Name(value) is named value
+type annotation struct {
+	expression
+	kind     *typeRef
+	elements []*elementValue
+}
+
+// elementValue is the value of an element of an annotation, which is an expression, an annotation
+// or an array initializer of element values
+type elementValue struct {
+	name  node
+	value Expression
+}
+
+// arrayInit is an array initializer like {1, 2}, creating an array of kind
+type arrayInit struct {
+	expression
+	kind     *typeRef
+	elements []Expression
+}
+
+// statement is embedded in every statement node
+type statement struct {
+	node
+}
+
+func (statement) Execute() {}
+
+// expressionStmt is an expression evaluated for its side effects, such as an assignment or a call
+type expressionStmt struct {
+	statement
+	expr Expression
+}
+
+// localVars declares one or more local variables of the same type, the node name is the type name
+type localVars struct {
+	statement
+	kind        *typeRef
+	isFinal     bool
+	inferred    bool
+	vars        []*localVar
+	annotations []*annotation
+}
+
+// localVar is a single declarator of a local variable declaration, value is nil without an initializer.
+// inferred is set for var declarations, whose kind is replaced by the type of the initializer when it is known
+type localVar struct {
+	node
+	kind     *typeRef
+	isFinal  bool
+	inferred bool
+	value    Expression
+	// resource and multiCatch are set for the variables that are implicitly final, JLS 14.20
+	resource   bool
+	multiCatch bool
+}
+
+// localClass declares a local class in a block
+type localClass struct {
+	statement
+	class *class
+}
+
+// block is a braced list of statements with its own scope
+type block struct {
+	statement
+	statements []Statement
+}
+
+// empty is the empty statement ;
+type empty struct {
+	statement
+}
+
+// ifStmt has a nil otherwise without an else branch
+type ifStmt struct {
+	statement
+	cond      Expression
+	then      Statement
+	otherwise Statement
+}
+
+type whileStmt struct {
+	statement
+	cond Expression
+	body Statement
+}
+
+type doWhile struct {
+	statement
+	body Statement
+	cond Expression
+}
+
+// forStmt is the basic for loop, cond is nil when the loop runs until a break or return
+type forStmt struct {
+	statement
+	init   []Statement
+	cond   Expression
+	update []Expression
+	body   Statement
+}
+
+// forEach is the enhanced for loop over an array or Iterable
+type forEach struct {
+	statement
+	variable *localVar
+	iterable Expression
+	body     Statement
+}
+
+// labeled names a statement for break and continue, the node name is the label
+type labeled struct {
+	statement
+	body Statement
+	// loop is set when the body is a loop, so the label can be continued
+	loop bool
+}
+
+// jump is a break or continue, label is empty for the innermost loop or switch
+type jump struct {
+	statement
+	kind  tokenKind
+	label string
 }
 
-func (fn *fn) Evaluate() {}
-func (fn *fn) Position() *pos {
-	return fn.reference.pos
+// returnStmt has a nil value in void methods
+type returnStmt struct {
+	statement
+	value Expression
 }
 
+// switchCase is one case of a switch, labels is empty for default.
+// Arrow cases have a single statement in body, and arrow expressions of switch expressions are yields
+type switchCase struct {
+	node
+	labels    []Expression
+	isDefault bool
+	body      []Statement
+}
+
+// switchBlock is shared by switch statements and expressions.
+// arrow is set when the cases use -> instead of :, so they don't fall through
+// enum is the enum switched on, set once the file is checked, see checkEnumSwitch
+type switchBlock struct {
+	selector Expression
+	cases    []*switchCase
+	arrow    bool
+	enum     *class
+}
+
+type switchStmt struct {
+	statement
+	switchBlock
+}
+
+type switchExpr struct {
+	expression
+	switchBlock
+}
+
+// yield gives the value of the enclosing switch expression
+type yield struct {
+	statement
+	value Expression
+}
+
+// throwStmt throws value, which must be a Throwable
+type throwStmt struct {
+	statement
+	value Expression
+}
+
+// tryStmt is a try statement, finally is nil without a finally block.
+// resources are the *localVars declaring a resource and the *expressionStmt naming an existing one in try-with-resources,
+// they are closed in the reverse order after body, JLS 14.20.3
+type tryStmt struct {
+	statement
+	resources []Statement
+	body      *block
+	catches   []*catchClause
+	finally   *block
+}
+
+// catchClause handles the exceptions of its parameter type, kinds has more than one type for a multi-catch
+type catchClause struct {
+	node
+	kinds []*typeRef
+	param *localVar
+	body  *block
+}
+
+// scope maps the names of the parameters, local variables and local classes in a block to their declaration.
+// The scope of a local or anonymous class body is a boundary, the members of the class may redeclare the names of the
+// enclosing method and still use the locals declared outside it
+type scope struct {
+	parent   *scope
+	vars     map[string]*localVar
+	classes  map[string]*class
+	boundary bool
+}
+
+// field has a nil value without an initializer.
+// constant holds the value of a final primitive or String field with a constant initializer, see class.constant
 type field struct {
 	*decl
-	initValue string
+	value    Expression
+	constant any
 }
 
 type method struct {
 	*decl
 	parameters []*parameter
 	body
+	// implicit is set for the default constructor of a class without constructors,
+	// the methods synthesised for enums and records, and bridge methods
+	implicit bool
+	// compact is set for the compact canonical constructor of a record, which leaves out its parameters
+	compact bool
+	// defaultValue is the default value of an element of an annotation interface, nil without one
+	defaultValue Expression
 }
 
 type body struct {
-	references  []*reference
-	statements  []Statement
-	expressions []Expression
+	statements []Statement
 }
 
+// modifiers are shared by classes and members.
+// isDefault marks the default methods of interfaces, and annotations are the annotations written among the modifiers
 type modifiers struct {
-	visibility tokenKind
-	isStatic   bool
+	visibility  tokenKind
+	isStatic    bool
+	isAbstract  bool
+	isDefault   bool
+	annotations []*annotation
 }
 
+// class has a nil extends when it only extends Object, and interfaces are classes with isInterface set.
+// Annotation interfaces are interfaces with isAnnotation set, whose methods are their elements.
+// The superinterfaces of an interface are listed in implements.
+// superclass and interfaces are the supertypes declared in the same file.
+// Records list their components, and enums their constants, whose bodies are anonymous classes extending the enum.
+// Nested classes have the class they are declared in as outer, and inner is set when their instances have
+// an enclosing instance of it, JLS 8.1.3. Member classes are listed in classes, and local classes are numbered by local.
+// Local and anonymous classes capture the locals of the enclosing methods that they use.
+// blocks are the static and instance initializers, in declaration order
 type class struct {
 	node
 	modifiers
-	isClosed bool
-	fields   []*field
-	methods  []*method
+	doc          string
+	isClosed     bool
+	isFinal      bool
+	isInterface  bool
+	isEnum       bool
+	isRecord     bool
+	isAnonymous  bool
+	isAnnotation bool
+	extends      *typeRef
+	implements   []*typeRef
+	superclass   *class
+	interfaces   []*class
+	constants    []*enumConstant
+	components   []*parameter
+	typeParams   []*typeParam
+	fields       []*field
+	methods      []*method
+	blocks       []*initBlock
+	outer        *class
+	inner        bool
+	classes      []*class
+	local        int
+	captured     []*localVar
 }
 
+// initBlock is a static or instance initializer of a class, JLS 8.6 and 8.7. Its body is held by an implicit
+// void method without parameters, named <clinit> when it is static and <init> otherwise.
+// index is the number of fields declared before it, which orders it among the field initializers
+type initBlock struct {
+	*method
+	index int
+}
+
+// enumConstant is a public static final field of its enum, created with args by constructor.
+// body is nil unless the constant declares a class body
+type enumConstant struct {
+	*field
+	ordinal     int
+	args        []Expression
+	body        *class
+	constructor *method
+}
+
+// pkg holds the classes of the files declaring the package, the unnamed package has an empty name
 type pkg struct {
 	name    string
-	classes []class
+	classes []*class
 }
 
+// importDecl imports alias from pkgName, which is the class declaring the member for static imports.
+// alias is * for imports on demand, like import java.util.*;
 type importDecl struct {
-	pkgName string
-	alias   string
+	node
+	pkgName  string
+	alias    string
+	isStatic bool
 }
 
+// file is a compilation unit, root is the AST it belongs to, used to resolve the classes of other files
 type file struct {
-	path string
-	// pkg     pkg
-	imports []importDecl
+	path    string
+	pkg     *pkg
+	imports []*importDecl
 	classes []*class
+	root    *AST
 }
 
 type AST struct {
@@ -114,23 +558,39 @@ type parseStateFn func(*Parser) parseStateFn
 // keep in mind that its either these or more code per state function and thereby fewer state functions
 type curr struct {
 	*token
-	class     *class
-	method    *method
-	reference *reference
-	file      *file
-	decl      *decl
+	class  *class
+	method *method
+	file   *file
+	decl   *decl
+	// declType is the type of decl without the brackets following its name, shared by the fields declared with it
+	declType *typeRef
+	// typeParams are the type parameters of the method being declared
+	typeParams []*typeParam
+	scope      *scope
+	// targets are the enclosing loops, switches and labeled statements that break, continue and yield may jump to
+	targets []Node
+	// lambda is the innermost lambda whose body is being parsed
+	lambda *lambda
+	// classScope is the scope the members of a local or anonymous class are declared in, nil for other classes
+	classScope *scope
 }
 
-// TODO: Consider adding a token buffer, possibly replace prev, peek with a slice of tokens
+// TODO: Consider replacing prev and peek with the ahead buffer
 type Parser struct {
 	Target string
 	*lexer
 	prevToken *token
 	peekToken *token
+	// ahead buffers the tokens after peekToken, see peekAt
+	ahead []*token
 	curr
-	running    bool
+	running bool
+	// sources are the paths of the files left to parse in a source root, see NewFromDir
+	sources    []string
 	ast        *AST
 	state      parseStateFn
 	errors     []error
 	errorLimit int
+	// warnings are reported like errors but don't stop compilation, see Warnings
+	warnings []error
 }
@@ -1,6 +1,8 @@
 package transpiler
 
 import (
+	"errors"
+	"log"
 	"os"
 
 	"github.com/JoachimTislov/lite-jnc/parser"
@@ -35,7 +37,15 @@ func New(p *parser.Parser) *transpiler {
 	return &transpiler{p}
 }
 
+// Run stops with the errors of the program, including the constructs the transpilers don't handle yet
 func (t *transpiler) Run(out string) *os.File {
+	ast, errs := t.Parse()
+	if len(errs) == 0 {
+		errs = ast.Unlowered()
+	}
+	if len(errs) > 0 {
+		log.Fatal(errors.Join(errs...))
+	}
 	// Implement transpilation logic here
 	return nil
 }